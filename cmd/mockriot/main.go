@@ -0,0 +1,72 @@
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/mockriot"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultFixture 未指定数据文件时使用的示例数据
+//
+//go:embed fixture.example.yaml
+var defaultFixture []byte
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9090", "监听地址")
+	fixturePath := flag.String("fixture", "", "账号数据文件（.json/.yaml），为空时使用内置示例数据")
+	flag.Parse()
+
+	// 加载账号数据
+	fixture, err := loadFixture(*fixturePath)
+	if err != nil {
+		log.Fatalf("无法加载账号数据: %v", err)
+	}
+
+	mock, err := mockriot.New(fixture)
+	if err != nil {
+		log.Fatalf("无法创建模拟服务: %v", err)
+	}
+
+	// 打印后端需要使用的接口地址
+	baseURL := "http://" + *addr
+	if strings.HasPrefix(*addr, ":") {
+		baseURL = "http://127.0.0.1" + *addr
+	}
+	endpoints := mockriot.Endpoints(baseURL)
+	log.Printf("已加载%d个账号，在后端中使用以下环境变量连接模拟服务:", len(fixture.Accounts))
+	log.Printf("  RIOT_AUTH_URL=%s", endpoints.Auth)
+	log.Printf("  RIOT_ENTITLEMENTS_URL=%s", endpoints.Entitlements)
+	log.Printf("  RIOT_PD_URL=%s", endpoints.PD)
+	log.Printf("  RIOT_SHARED_URL=%s", endpoints.Shared)
+
+	server := &http.Server{
+		Addr:        *addr,
+		Handler:     mock,
+		ReadTimeout: 15 * time.Second,
+	}
+
+	// 启动服务器
+	log.Printf("模拟Riot服务正在 %s 上启动", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("模拟服务启动失败: %v", err)
+	}
+}
+
+// loadFixture 加载数据文件，path为空时解析内置的示例数据
+func loadFixture(path string) (*mockriot.Fixture, error) {
+	if path != "" {
+		return mockriot.LoadFixture(path)
+	}
+
+	var fixture mockriot.Fixture
+	if err := yaml.Unmarshal(defaultFixture, &fixture); err != nil {
+		return nil, err
+	}
+	return &fixture, nil
+}
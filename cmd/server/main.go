@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/emper0r/val-store/server/internal/api"
@@ -11,34 +12,31 @@ import (
 )
 
 func main() {
-	// 加载环境变量配置
-	if err := config.LoadConfig(); err != nil {
+	// 加载配置（命令行参数 > 环境变量 > 配置文件 > 默认值）
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
 		log.Fatalf("无法加载配置: %v", err)
 	}
 
-	// 设置Gin模式（默认为debug模式）
-	ginMode := config.GetEnv("GIN_MODE", "debug")
-	gin.SetMode(ginMode)
+	// 设置Gin模式
+	gin.SetMode(cfg.GinMode)
 
 	// 创建Gin引擎
 	app := gin.Default()
 
 	// 初始化路由
-	api.SetupRouter(app)
+	api.SetupRouter(app, cfg)
 
-	// 获取端口配置
-	port := config.GetEnv("PORT", "8080")
-
-	// 设置超时
+	// 设置超时，写超时根据Riot请求的超时配置计算
 	server := &http.Server{
-		Addr:         ":" + port,
+		Addr:         ":" + cfg.Port,
 		Handler:      app,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: cfg.WriteTimeout(),
 	}
 
 	// 启动服务器
-	log.Printf("服务器正在端口 %s 上启动，环境：%s", port, ginMode)
+	log.Printf("服务器正在端口 %s 上启动，环境：%s", cfg.Port, cfg.GinMode)
 	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("服务器启动失败: %v", err)
 	}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/emper0r/val-store/server/internal/api/middleware"
 	"github.com/emper0r/val-store/server/internal/models"
 	"github.com/emper0r/val-store/server/internal/services"
 	"github.com/gin-gonic/gin"
@@ -37,16 +38,51 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// 调用认证服务进行登录
-	response, err := h.authService.Login(credentials.Username, credentials.Password)
+	response, challenge, err := h.authService.Login(c.Request.Context(), credentials.Username, credentials.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.APIError{
-			Status:  http.StatusUnauthorized,
-			Message: "登录失败",
+		middleware.AbortWithError(c, "登录失败", err)
+		return
+	}
+
+	// 账号开启了二次验证，返回挑战信息等待客户端提交验证码
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, models.APISuccess{
+			Status:  http.StatusAccepted,
+			Message: "需要二次验证",
+			Data:    challenge,
+		})
+		return
+	}
+
+	// 返回登录成功响应
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "登录成功",
+		Data:    response,
+	})
+}
+
+// SubmitMFACode 处理二次验证码提交请求
+func (h *AuthHandler) SubmitMFACode(c *gin.Context) {
+	var request models.MFACodeRequest
+
+	// 绑定JSON数据到结构体
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的请求数据",
 			Error:   err.Error(),
 		})
 		return
 	}
 
+	// 调用认证服务提交验证码
+	response, err := h.authService.SubmitMFACode(c.Request.Context(), request.ChallengeID, request.Code, request.RememberDevice)
+	if err != nil {
+		middleware.AbortWithError(c, "二次验证失败", err)
+		return
+	}
+
 	// 返回登录成功响应
 	c.JSON(http.StatusOK, models.APISuccess{
 		Status:  http.StatusOK,
@@ -70,13 +106,9 @@ func (h *AuthHandler) LoginWithCookies(c *gin.Context) {
 	}
 
 	// 调用认证服务进行Cookie登录，传递区域参数
-	response, err := h.authService.LoginWithCookies(request.Cookies, request.Region)
+	response, err := h.authService.LoginWithCookies(c.Request.Context(), request.Cookies, request.Region)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.APIError{
-			Status:  http.StatusUnauthorized,
-			Message: "登录失败",
-			Error:   err.Error(),
-		})
+		middleware.AbortWithError(c, "登录失败", err)
 		return
 	}
 
@@ -88,6 +120,85 @@ func (h *AuthHandler) LoginWithCookies(c *gin.Context) {
 	})
 }
 
+// RefreshToken 使用刷新令牌换取新的令牌
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var request models.RefreshTokenRequest
+
+	// 绑定JSON数据到结构体
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的请求数据",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.RefreshTokens(request.RefreshToken)
+	if err != nil {
+		middleware.AbortWithError(c, "刷新令牌失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "刷新令牌成功",
+		Data:    response,
+	})
+}
+
+// Logout 注销当前登录
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.authService.Logout(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIError{
+			Status:  http.StatusInternalServerError,
+			Message: "注销失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "注销成功",
+	})
+}
+
+// LogoutAll 注销用户在所有设备上的登录
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.authService.LogoutAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIError{
+			Status:  http.StatusInternalServerError,
+			Message: "注销失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "已注销所有设备上的登录",
+	})
+}
+
 // Ping 简单的健康检查端点
 func (h *AuthHandler) Ping(c *gin.Context) {
 	c.JSON(http.StatusOK, models.APISuccess{
@@ -97,8 +208,16 @@ func (h *AuthHandler) Ping(c *gin.Context) {
 }
 
 // RegisterRoutes 注册认证相关路由
-func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
+func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	router.POST("/login", h.Login)
+	router.POST("/login/mfa", h.SubmitMFACode)
 	router.POST("/login/cookies", h.LoginWithCookies)
+	router.POST("/refresh", h.RefreshToken)
 	router.GET("/ping", h.Ping)
+
+	protected := router.Group("")
+	protected.Use(authMiddleware)
+
+	protected.POST("/logout", h.Logout)
+	protected.POST("/logout-all", h.LogoutAll)
 }
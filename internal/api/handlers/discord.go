@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/emper0r/val-store/server/internal/api/middleware"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+	"github.com/emper0r/val-store/server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDiscordBodySize Discord交互请求体的最大长度
+const maxDiscordBodySize = 1 << 20
+
+// DiscordHandler 处理Discord交互和账号绑定请求
+type DiscordHandler struct {
+	discordService *services.DiscordService
+}
+
+// NewDiscordHandler 创建新的Discord处理器
+func NewDiscordHandler(discordService *services.DiscordService) *DiscordHandler {
+	return &DiscordHandler{
+		discordService: discordService,
+	}
+}
+
+// HandleInteraction 处理Discord发送的交互，使用Ed25519签名认证而不是JWT
+func (h *DiscordHandler) HandleInteraction(c *gin.Context) {
+	if !h.discordService.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, models.APIError{
+			Status:  http.StatusServiceUnavailable,
+			Message: "未配置Discord集成",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxDiscordBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "读取请求失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Discord会定期发送签名错误的请求，验证失败时必须返回401
+	signature := c.GetHeader("X-Signature-Ed25519")
+	timestamp := c.GetHeader("X-Signature-Timestamp")
+	if !h.discordService.VerifySignature(signature, timestamp, body) {
+		c.JSON(http.StatusUnauthorized, models.APIError{
+			Status:  http.StatusUnauthorized,
+			Message: "无效的请求签名",
+		})
+		return
+	}
+
+	var interaction models.DiscordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的交互内容",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Discord要求直接返回交互响应，不使用统一的响应格式
+	c.JSON(http.StatusOK, h.discordService.HandleInteraction(c.Request.Context(), &interaction))
+}
+
+// GetLink 获取当前用户绑定的Discord账号
+func (h *DiscordHandler) GetLink(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取Discord绑定",
+		Data: map[string]interface{}{
+			"discord_user_ids": h.discordService.GetLinkedAccounts(userID),
+		},
+	})
+}
+
+// Link 将Discord账号绑定到当前用户
+func (h *DiscordHandler) Link(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	// 解析请求体
+	var req models.DiscordLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的请求参数",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := h.discordService.LinkAccount(userID, req.DiscordUserID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrDiscordUserIDInvalid):
+			c.JSON(http.StatusBadRequest, models.APIError{
+				Status:  http.StatusBadRequest,
+				Message: "无效的Discord用户ID",
+			})
+		case errors.Is(err, repositories.ErrLinkConflict):
+			c.JSON(http.StatusConflict, models.APIError{
+				Status:  http.StatusConflict,
+				Message: "该Discord账号已绑定到其他用户",
+				Code:    "DISCORD_ALREADY_LINKED",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: "绑定Discord账号失败",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功绑定Discord账号",
+		Data: map[string]string{
+			"discord_user_id": req.DiscordUserID,
+		},
+	})
+}
+
+// Unlink 解除当前用户绑定的所有Discord账号
+func (h *DiscordHandler) Unlink(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	removed, err := h.discordService.UnlinkAccount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIError{
+			Status:  http.StatusInternalServerError,
+			Message: "解除Discord绑定失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功解除Discord绑定",
+		Data: map[string]int{
+			"removed": removed,
+		},
+	})
+}
+
+// RegisterRoutes 注册Discord相关路由
+func (h *DiscordHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	// 交互地址由Discord调用，使用请求签名认证
+	router.POST("/integrations/discord/interactions", h.HandleInteraction)
+
+	protected := router.Group("")
+	protected.Use(authMiddleware)
+
+	protected.GET("/integrations/discord/link", h.GetLink)
+	protected.POST("/integrations/discord/link", h.Link)
+	protected.DELETE("/integrations/discord/link", h.Unlink)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/emper0r/val-store/server/internal/api/middleware"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// LoadoutHandler 处理玩家装备相关请求
+type LoadoutHandler struct {
+	loadoutService *services.LoadoutService
+	sessionService *services.SessionService
+}
+
+// NewLoadoutHandler 创建新的装备处理器
+func NewLoadoutHandler(loadoutService *services.LoadoutService, sessionService *services.SessionService) *LoadoutHandler {
+	return &LoadoutHandler{
+		loadoutService: loadoutService,
+		sessionService: sessionService,
+	}
+}
+
+// GetLoadout 获取玩家当前的装备
+func (h *LoadoutHandler) GetLoadout(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	var loadout *models.LoadoutResponse
+	err := h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		loadout, err = h.loadoutService.GetLoadout(c.Request.Context(), session)
+		return err
+	})
+	if err != nil {
+		middleware.AbortWithError(c, "获取装备失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取装备",
+		Data:    loadout,
+	})
+}
+
+// UpdateLoadout 修改玩家的装备
+func (h *LoadoutHandler) UpdateLoadout(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	// 解析请求体
+	var req models.LoadoutUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的请求参数",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var loadout *models.LoadoutResponse
+	err := h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		loadout, err = h.loadoutService.UpdateLoadout(c.Request.Context(), session, &req)
+		return err
+	})
+	if err != nil {
+		middleware.AbortWithError(c, "修改装备失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功修改装备",
+		Data:    loadout,
+	})
+}
+
+// RegisterRoutes 注册装备相关路由
+func (h *LoadoutHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	protected := router.Group("")
+	protected.Use(authMiddleware)
+
+	protected.GET("/user/loadout", h.GetLoadout)
+	protected.PUT("/user/loadout", h.UpdateLoadout)
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/emper0r/val-store/server/internal/api/middleware"
 	"github.com/emper0r/val-store/server/internal/models"
@@ -11,13 +14,17 @@ import (
 
 // ShopHandler 处理商店相关请求
 type ShopHandler struct {
-	shopService *services.ShopService
+	shopService      *services.ShopService
+	sessionService   *services.SessionService
+	shopImageService *services.ShopImageService
 }
 
 // NewShopHandler 创建新的商店处理器
-func NewShopHandler(shopService *services.ShopService) *ShopHandler {
+func NewShopHandler(shopService *services.ShopService, sessionService *services.SessionService, shopImageService *services.ShopImageService) *ShopHandler {
 	return &ShopHandler{
-		shopService: shopService,
+		shopService:      shopService,
+		sessionService:   sessionService,
+		shopImageService: shopImageService,
 	}
 }
 
@@ -33,40 +40,235 @@ func (h *ShopHandler) GetShop(c *gin.Context) {
 		return
 	}
 
-	// 从缓存中获取用户会话数据
-	session, exists := h.shopService.GetCachedSession(userID)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIError{
-			Status:  http.StatusUnauthorized,
-			Message: "会话已过期",
-			Error:   "请重新登录以刷新会话",
+	// 使用用户会话获取商店数据，Riot令牌过期时会自动重新认证
+	var shopData *models.ShopResponse
+	err := h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		shopData, err = h.shopService.GetShop(c.Request.Context(), session, wantsRefresh(c))
+		return err
+	})
+	if err != nil {
+		middleware.AbortWithError(c, "获取商店数据失败", err)
+		return
+	}
+
+	// 返回商店数据
+	setCacheHeaders(c, shopData.NextRotationAt)
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取商店数据",
+		Data:    shopData,
+	})
+}
+
+// GetNightMarket 获取用户的夜市
+func (h *ShopHandler) GetNightMarket(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
 		})
 		return
 	}
 
-	// 调用商店服务获取商店数据
-	shopData, err := h.shopService.GetShop(userID, session.AccessToken, session.Entitlement)
+	var nightMarket *models.NightMarketResponse
+	err := h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		nightMarket, err = h.shopService.GetNightMarket(c.Request.Context(), session, wantsRefresh(c))
+		return err
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIError{
-			Status:  http.StatusInternalServerError,
-			Message: "获取商店数据失败",
-			Error:   err.Error(),
+		middleware.AbortWithError(c, "获取夜市失败", err)
+		return
+	}
+
+	setCacheHeaders(c, nightMarket.ExpiresAt)
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取夜市",
+		Data:    nightMarket,
+	})
+}
+
+// GetAccessoryStore 获取用户的每周配件商店
+func (h *ShopHandler) GetAccessoryStore(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
 		})
 		return
 	}
 
-	// 返回商店数据
+	var accessoryStore *models.AccessoryStoreResponse
+	err := h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		accessoryStore, err = h.shopService.GetAccessoryStore(c.Request.Context(), session, wantsRefresh(c))
+		return err
+	})
+	if err != nil {
+		middleware.AbortWithError(c, "获取配件商店失败", err)
+		return
+	}
+
+	setCacheHeaders(c, accessoryStore.ExpiresAt)
 	c.JSON(http.StatusOK, models.APISuccess{
 		Status:  http.StatusOK,
-		Message: "成功获取商店数据",
-		Data:    shopData,
+		Message: "成功获取配件商店",
+		Data:    accessoryStore,
+	})
+}
+
+// GetShopHistory 分页获取用户的商店历史
+func (h *ShopHandler) GetShopHistory(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	history, err := h.shopService.GetShopHistory(userID, c.Query("from"), c.Query("to"), page, pageSize)
+	if err != nil {
+		middleware.AbortWithError(c, "获取商店历史失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取商店历史",
+		Data:    history,
 	})
 }
 
+// GetShopSnapshot 获取用户在指定日期的商店快照
+func (h *ShopHandler) GetShopSnapshot(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	snapshot, err := h.shopService.GetShopSnapshot(userID, c.Param("date"))
+	if err != nil {
+		middleware.AbortWithError(c, "获取商店快照失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取商店快照",
+		Data:    snapshot,
+	})
+}
+
+// GetShopImage 将用户的每日商店绘制为PNG图片，方便分享
+// 查询参数: layout（grid或list）、theme（dark或light）、bundles和nightmarket（是否包括精选套装和夜市）
+func (h *ShopHandler) GetShopImage(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	opts := services.ShopImageOptions{
+		Layout: c.Query("layout"),
+		Theme:  c.Query("theme"),
+	}
+	var err error
+	if opts.IncludeBundles, err = queryBool(c, "bundles"); err == nil {
+		opts.IncludeNightMarket, err = queryBool(c, "nightmarket")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的请求参数",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var shopData *models.ShopResponse
+	err = h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		shopData, err = h.shopService.GetShop(c.Request.Context(), session, wantsRefresh(c))
+		return err
+	})
+	if err != nil {
+		middleware.AbortWithError(c, "获取商店数据失败", err)
+		return
+	}
+
+	data, err := h.shopImageService.RenderShop(c.Request.Context(), shopData, opts)
+	if err != nil {
+		middleware.AbortWithError(c, "生成商店图片失败", err)
+		return
+	}
+
+	// 图片可能包含捆绑包和夜市，按最早的商店刷新时间缓存
+	setCacheHeaders(c, shopData.NextRotationAt)
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// queryBool 解析布尔类型的查询参数，未指定时为false
+func queryBool(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s必须是true或false", name)
+	}
+	return parsed, nil
+}
+
+// wantsRefresh 判断请求是否要求跳过缓存（?refresh=true）
+func wantsRefresh(c *gin.Context) bool {
+	refresh, _ := strconv.ParseBool(c.Query("refresh"))
+	return refresh
+}
+
+// setCacheHeaders 根据商店刷新时间设置缓存响应头
+// 商店数据因用户而异，只允许客户端缓存
+func setCacheHeaders(c *gin.Context, expiresAt int64) {
+	maxAge := expiresAt - time.Now().Unix()
+	if maxAge <= 0 {
+		c.Header("Cache-Control", "private, no-cache")
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	c.Header("Expires", time.Unix(expiresAt, 0).UTC().Format(http.TimeFormat))
+}
+
 // RegisterRoutes 注册商店相关路由
 func (h *ShopHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	protected := router.Group("")
 	protected.Use(authMiddleware)
 
 	protected.GET("/shop", h.GetShop)
+	protected.GET("/shop/nightmarket", h.GetNightMarket)
+	protected.GET("/shop/accessories", h.GetAccessoryStore)
+	protected.GET("/shop/image.png", h.GetShopImage)
+	protected.GET("/shop/history", h.GetShopHistory)
+	protected.GET("/shop/history/:date", h.GetShopSnapshot)
 }
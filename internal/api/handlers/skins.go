@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/emper0r/val-store/server/internal/api/middleware"
 	"github.com/emper0r/val-store/server/internal/models"
 	"github.com/emper0r/val-store/server/internal/services"
 	"github.com/gin-gonic/gin"
@@ -23,13 +24,9 @@ func NewSkinsHandler(skinsService *services.SkinsService) *SkinsHandler {
 // GetAllSkins 获取所有皮肤列表
 func (h *SkinsHandler) GetAllSkins(c *gin.Context) {
 	// 调用皮肤服务获取所有皮肤
-	skins, err := h.skinsService.GetAllSkins()
+	skins, err := h.skinsService.GetAllSkins(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIError{
-			Status:  http.StatusInternalServerError,
-			Message: "获取皮肤列表失败",
-			Error:   err.Error(),
-		})
+		middleware.AbortWithError(c, "获取皮肤列表失败", err)
 		return
 	}
 
@@ -56,11 +53,7 @@ func (h *SkinsHandler) GetSkinByID(c *gin.Context) {
 	// 调用皮肤服务获取皮肤信息
 	skin, err := h.skinsService.GetSkinByID(skinID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIError{
-			Status:  http.StatusNotFound,
-			Message: "皮肤未找到",
-			Error:   err.Error(),
-		})
+		middleware.AbortWithError(c, "获取皮肤信息失败", err)
 		return
 	}
 
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/emper0r/val-store/server/internal/api/middleware"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTelegramBodySize Telegram更新请求体的最大长度
+const maxTelegramBodySize = 1 << 20
+
+// TelegramHandler 处理Telegram机器人的更新和账号绑定请求
+type TelegramHandler struct {
+	telegramService *services.TelegramService
+}
+
+// NewTelegramHandler 创建新的Telegram处理器
+func NewTelegramHandler(telegramService *services.TelegramService) *TelegramHandler {
+	return &TelegramHandler{
+		telegramService: telegramService,
+	}
+}
+
+// HandleUpdate 处理Telegram推送的更新，使用地址中的密钥认证而不是JWT
+func (h *TelegramHandler) HandleUpdate(c *gin.Context) {
+	if !h.telegramService.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, models.APIError{
+			Status:  http.StatusServiceUnavailable,
+			Message: "未配置Telegram集成",
+		})
+		return
+	}
+
+	if !h.telegramService.VerifySecret(c.Param("secret")) {
+		c.JSON(http.StatusNotFound, models.APIError{
+			Status:  http.StatusNotFound,
+			Message: "资源不存在",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTelegramBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "读取请求失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var update models.TelegramUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的更新内容",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 命令可能需要请求Riot，在后台处理并通过Bot API回复，避免Telegram超时重发
+	// 后台处理不随本次请求结束而取消
+	go h.telegramService.HandleUpdate(context.WithoutCancel(c.Request.Context()), &update)
+
+	c.Status(http.StatusOK)
+}
+
+// CreateLinkCode 为当前用户生成在Telegram中使用的一次性绑定代码
+func (h *TelegramHandler) CreateLinkCode(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if !h.telegramService.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, models.APIError{
+			Status:  http.StatusServiceUnavailable,
+			Message: "未配置Telegram集成",
+		})
+		return
+	}
+
+	linkCode, err := h.telegramService.CreateLinkCode(userID)
+	if err != nil {
+		middleware.AbortWithError(c, "生成绑定代码失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "请在Telegram中向机器人发送 /login " + linkCode.Code,
+		Data:    linkCode,
+	})
+}
+
+// GetLink 获取当前用户绑定的Telegram账号
+func (h *TelegramHandler) GetLink(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取Telegram绑定",
+		Data: map[string]interface{}{
+			"telegram_user_ids": h.telegramService.GetLinkedAccounts(userID),
+		},
+	})
+}
+
+// Unlink 解除当前用户绑定的所有Telegram账号
+func (h *TelegramHandler) Unlink(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	removed, err := h.telegramService.UnlinkAccount(userID)
+	if err != nil {
+		middleware.AbortWithError(c, "解除Telegram绑定失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功解除Telegram绑定",
+		Data: map[string]int{
+			"removed": removed,
+		},
+	})
+}
+
+// RegisterRoutes 注册Telegram相关路由
+func (h *TelegramHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	protected := router.Group("")
+	protected.Use(authMiddleware)
+
+	protected.POST("/integrations/telegram/link-code", h.CreateLinkCode)
+	protected.GET("/integrations/telegram/link", h.GetLink)
+	protected.DELETE("/integrations/telegram/link", h.Unlink)
+
+	// 更新地址由Telegram调用，使用setWebhook时设置的地址中的密钥认证
+	router.POST("/integrations/telegram/:secret", h.HandleUpdate)
+}
@@ -11,15 +11,19 @@ import (
 
 // UserHandler 处理用户相关请求
 type UserHandler struct {
-	userService *services.UserService
-	shopService *services.ShopService
+	userService      *services.UserService
+	shopService      *services.ShopService
+	sessionService   *services.SessionService
+	inventoryService *services.InventoryService
 }
 
 // NewUserHandler 创建新的用户处理器
-func NewUserHandler(userService *services.UserService, shopService *services.ShopService) *UserHandler {
+func NewUserHandler(userService *services.UserService, shopService *services.ShopService, sessionService *services.SessionService, inventoryService *services.InventoryService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		shopService: shopService,
+		userService:      userService,
+		shopService:      shopService,
+		sessionService:   sessionService,
+		inventoryService: inventoryService,
 	}
 }
 
@@ -60,33 +64,53 @@ func (h *UserHandler) GetUserWallet(c *gin.Context) {
 		return
 	}
 
-	// 从缓存中获取用户会话数据
-	session, exists := h.shopService.GetCachedSession(userID)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIError{
-			Status:  http.StatusUnauthorized,
-			Message: "会话已过期",
-			Error:   "请重新登录以刷新会话",
+	// 使用用户会话获取钱包数据，Riot令牌过期时会自动重新认证
+	var walletData *models.WalletResponse
+	err := h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		walletData, err = h.userService.GetUserWallet(c.Request.Context(), session)
+		return err
+	})
+	if err != nil {
+		middleware.AbortWithError(c, "获取钱包数据失败", err)
+		return
+	}
+
+	// 返回钱包数据
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取钱包数据",
+		Data:    walletData,
+	})
+}
+
+// GetUserInventory 获取用户拥有的皮肤和饰品
+func (h *UserHandler) GetUserInventory(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
 		})
 		return
 	}
 
-	// 调用用户服务获取钱包数据
-	walletData, err := h.userService.GetUserWallet(userID, session.AccessToken, session.Entitlement)
+	var inventory *models.InventoryResponse
+	err := h.sessionService.WithSession(c.Request.Context(), userID, func(session *models.UserSession) error {
+		var err error
+		inventory, err = h.inventoryService.GetInventory(c.Request.Context(), session)
+		return err
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIError{
-			Status:  http.StatusInternalServerError,
-			Message: "获取钱包数据失败",
-			Error:   err.Error(),
-		})
+		middleware.AbortWithError(c, "获取库存失败", err)
 		return
 	}
 
-	// 返回钱包数据
 	c.JSON(http.StatusOK, models.APISuccess{
 		Status:  http.StatusOK,
-		Message: "成功获取钱包数据",
-		Data:    walletData,
+		Message: "成功获取库存",
+		Data:    inventory,
 	})
 }
 
@@ -125,11 +149,7 @@ func (h *UserHandler) SetUserRegion(c *gin.Context) {
 
 	// 更新用户会话中的区域设置
 	if err := h.shopService.UpdateUserRegion(userID, req.Region); err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIError{
-			Status:  http.StatusInternalServerError,
-			Message: "更新用户区域失败",
-			Error:   err.Error(),
-		})
+		middleware.AbortWithError(c, "更新用户区域失败", err)
 		return
 	}
 
@@ -165,6 +185,7 @@ func (h *UserHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin
 
 	protected.GET("/user/info", h.GetUserInfo)
 	protected.GET("/user/wallet", h.GetUserWallet)
+	protected.GET("/user/inventory", h.GetUserInventory)
 	protected.POST("/user/region", h.SetUserRegion)
 
 	// 区域列表可以不需要认证
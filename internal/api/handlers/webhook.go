@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/emper0r/val-store/server/internal/api/middleware"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+	"github.com/emper0r/val-store/server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler 处理Webhook相关请求
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler 创建新的Webhook处理器
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// ListWebhooks 列出用户注册的Webhook
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取Webhook列表",
+		Data: map[string]interface{}{
+			"webhooks": h.webhookService.ListWebhooks(userID),
+		},
+	})
+}
+
+// CreateWebhook 注册Webhook
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	// 解析请求体
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的请求参数",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookInvalid) {
+			c.JSON(http.StatusBadRequest, models.APIError{
+				Status:  http.StatusBadRequest,
+				Message: "无效的Webhook",
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIError{
+			Status:  http.StatusInternalServerError,
+			Message: "注册Webhook失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APISuccess{
+		Status:  http.StatusCreated,
+		Message: "成功注册Webhook，请保存签名密钥，之后不会再返回",
+		Data:    webhook,
+	})
+}
+
+// DeleteWebhook 删除Webhook
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(userID, c.Param("id")); err != nil {
+		respondWebhookError(c, err, "删除Webhook失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功删除Webhook",
+	})
+}
+
+// GetDeliveries 获取Webhook的推送记录
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(userID, c.Param("id"))
+	if err != nil {
+		respondWebhookError(c, err, "获取推送记录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取推送记录",
+		Data: map[string]interface{}{
+			"deliveries": deliveries,
+		},
+	})
+}
+
+// PingWebhook 向Webhook发送一次测试推送，返回推送记录
+func (h *WebhookHandler) PingWebhook(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	delivery, err := h.webhookService.Ping(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondWebhookError(c, err, "发送测试推送失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "已发送测试推送",
+		Data:    delivery,
+	})
+}
+
+// respondWebhookError 返回Webhook操作的错误响应
+func respondWebhookError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repositories.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, models.APIError{
+			Status:  http.StatusNotFound,
+			Message: "Webhook不存在",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.APIError{
+		Status:  http.StatusInternalServerError,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
+
+// RegisterRoutes 注册Webhook相关路由
+func (h *WebhookHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	protected := router.Group("")
+	protected.Use(authMiddleware)
+
+	protected.GET("/user/webhooks", h.ListWebhooks)
+	protected.POST("/user/webhooks", h.CreateWebhook)
+	protected.DELETE("/user/webhooks/:id", h.DeleteWebhook)
+	protected.GET("/user/webhooks/:id/deliveries", h.GetDeliveries)
+	protected.POST("/user/webhooks/:id/ping", h.PingWebhook)
+}
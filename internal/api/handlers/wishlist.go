@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/emper0r/val-store/server/internal/api/middleware"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// WishlistHandler 处理皮肤愿望单相关请求
+type WishlistHandler struct {
+	wishlistService *services.WishlistService
+}
+
+// NewWishlistHandler 创建新的愿望单处理器
+func NewWishlistHandler(wishlistService *services.WishlistService) *WishlistHandler {
+	return &WishlistHandler{
+		wishlistService: wishlistService,
+	}
+}
+
+// GetWishlist 获取用户的愿望单
+func (h *WishlistHandler) GetWishlist(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取愿望单",
+		Data:    h.wishlistService.GetWishlist(userID),
+	})
+}
+
+// AddWishlistSkin 添加皮肤到愿望单
+func (h *WishlistHandler) AddWishlistSkin(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	// 解析请求体
+	var req models.WishlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的请求参数",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	wishlist, err := h.wishlistService.AddSkin(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrWishlistSkinInvalid) {
+			c.JSON(http.StatusBadRequest, models.APIError{
+				Status:  http.StatusBadRequest,
+				Message: "皮肤不存在",
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIError{
+			Status:  http.StatusInternalServerError,
+			Message: "添加愿望单失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功添加到愿望单",
+		Data:    wishlist,
+	})
+}
+
+// RemoveWishlistSkin 从愿望单中删除皮肤
+func (h *WishlistHandler) RemoveWishlistSkin(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	wishlist, err := h.wishlistService.RemoveSkin(userID, c.Param("skinId"))
+	if err != nil {
+		if errors.Is(err, services.ErrWishlistSkinNotFound) {
+			c.JSON(http.StatusNotFound, models.APIError{
+				Status:  http.StatusNotFound,
+				Message: "愿望单中没有该皮肤",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIError{
+			Status:  http.StatusInternalServerError,
+			Message: "删除愿望单皮肤失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功从愿望单中删除",
+		Data:    wishlist,
+	})
+}
+
+// GetWishlistMatches 获取愿望单皮肤在商店中出现的记录
+func (h *WishlistHandler) GetWishlistMatches(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.APIError{
+			Status:  http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APISuccess{
+		Status:  http.StatusOK,
+		Message: "成功获取愿望单匹配记录",
+		Data: map[string]interface{}{
+			"matches": h.wishlistService.GetMatches(userID),
+		},
+	})
+}
+
+// RegisterRoutes 注册愿望单相关路由
+func (h *WishlistHandler) RegisterRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	protected := router.Group("")
+	protected.Use(authMiddleware)
+
+	protected.GET("/user/wishlist", h.GetWishlist)
+	protected.POST("/user/wishlist", h.AddWishlistSkin)
+	protected.DELETE("/user/wishlist/:skinId", h.RemoveWishlistSkin)
+	protected.GET("/user/wishlist/matches", h.GetWishlistMatches)
+}
@@ -1,12 +1,25 @@
 package middleware
 
 import (
-	"net/http"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/emper0r/val-store/server/internal/models"
 	"github.com/emper0r/val-store/server/internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrMissingToken 请求没有携带Authorization头
+	ErrMissingToken = errors.New("缺少Authorization头")
+	// ErrInvalidToken Authorization头格式错误，或令牌签名无效
+	ErrInvalidToken = errors.New("令牌无效")
+	// ErrTokenExpired 访问令牌已过期
+	ErrTokenExpired = errors.New("令牌已过期")
+	// ErrTokenRevoked 令牌已被注销
+	ErrTokenRevoked = errors.New("令牌已被注销")
 )
 
 // AuthMiddleware 创建JWT认证中间件
@@ -17,23 +30,13 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 
 		// 检查Authorization头是否存在并符合格式
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, models.APIError{
-				Status:  http.StatusUnauthorized,
-				Message: "未授权",
-				Error:   "缺少Authorization头",
-			})
-			c.Abort()
+			AbortWithError(c, "未授权", ErrMissingToken)
 			return
 		}
 
 		// 检查Bearer前缀
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, models.APIError{
-				Status:  http.StatusUnauthorized,
-				Message: "未授权",
-				Error:   "无效的Authorization格式，应为Bearer令牌",
-			})
-			c.Abort()
+			AbortWithError(c, "未授权", fmt.Errorf("%w: 应为Bearer令牌", ErrInvalidToken))
 			return
 		}
 
@@ -43,18 +46,24 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		// 验证JWT令牌
 		claims, err := authService.ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.APIError{
-				Status:  http.StatusUnauthorized,
-				Message: "未授权",
-				Error:   "令牌无效或已过期",
-			})
-			c.Abort()
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				AbortWithError(c, "未授权", fmt.Errorf("%w: %v", ErrTokenExpired, err))
+			} else {
+				AbortWithError(c, "未授权", fmt.Errorf("%w: %v", ErrInvalidToken, err))
+			}
+			return
+		}
+
+		// 检查令牌是否已被注销
+		if authService.IsTokenRevoked(claims) {
+			AbortWithError(c, "未授权", ErrTokenRevoked)
 			return
 		}
 
 		// 将用户信息存储在上下文中，以便后续处理程序使用
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("claims", claims)
 
 		c.Next()
 	}
@@ -69,6 +78,15 @@ func GetUserID(c *gin.Context) string {
 	return userID.(string)
 }
 
+// GetClaims 从上下文中获取JWT声明
+func GetClaims(c *gin.Context) *models.JWTClaims {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil
+	}
+	return claims.(*models.JWTClaims)
+}
+
 // GetUsername 从上下文中获取用户名
 func GetUsername(c *gin.Context) string {
 	username, exists := c.Get("username")
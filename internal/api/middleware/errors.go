@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+	"github.com/emper0r/val-store/server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// errorMapping 错误对应的状态码、错误码和提示
+type errorMapping struct {
+	err     error
+	status  int
+	code    string
+	message string
+	detail  string // 返回给客户端的错误说明，为空时使用err本身的内容（不包含包装的上下文）
+}
+
+// errorMappings 按顺序匹配，先匹配到的生效
+var errorMappings = []errorMapping{
+	{err: ErrMissingToken, status: http.StatusUnauthorized, code: "MISSING_TOKEN", message: "未授权"},
+	{err: ErrTokenExpired, status: http.StatusUnauthorized, code: "TOKEN_EXPIRED", message: "未授权", detail: "访问令牌已过期，请使用刷新令牌换取新令牌"},
+	{err: ErrInvalidToken, status: http.StatusUnauthorized, code: "INVALID_TOKEN", message: "未授权"},
+	{err: ErrTokenRevoked, status: http.StatusUnauthorized, code: "TOKEN_REVOKED", message: "未授权"},
+	{err: services.ErrSessionNotFound, status: http.StatusUnauthorized, code: "SESSION_EXPIRED", message: "会话已过期", detail: "请重新登录以刷新会话"},
+	{err: services.ErrSessionReauthFailed, status: http.StatusUnauthorized, code: "SESSION_EXPIRED", message: "会话已过期", detail: "请重新登录以刷新会话"},
+	{err: services.ErrInvalidRefreshToken, status: http.StatusUnauthorized, code: "INVALID_REFRESH_TOKEN", message: "刷新令牌失败"},
+	{err: services.ErrRefreshTokenReused, status: http.StatusUnauthorized, code: "REFRESH_TOKEN_REUSED", message: "刷新令牌失败"},
+	{err: services.ErrMFAChallengeNotFound, status: http.StatusUnauthorized, code: "MFA_CHALLENGE_NOT_FOUND", message: "二次验证失败"},
+	{err: services.ErrMFATooManyAttempts, status: http.StatusUnauthorized, code: "MFA_TOO_MANY_ATTEMPTS", message: "二次验证失败"},
+	{err: services.ErrInvalidCookies, status: http.StatusBadRequest, code: "INVALID_COOKIES", message: "登录失败"},
+	{err: repositories.ErrInvalidCredentials, status: http.StatusUnauthorized, code: "INVALID_CREDENTIALS", message: "登录失败"},
+	{err: repositories.ErrMFARequired, status: http.StatusUnauthorized, code: "MFA_REQUIRED", message: "二次验证失败"},
+	{err: repositories.ErrCaptchaRequired, status: http.StatusForbidden, code: "CAPTCHA_REQUIRED", message: "Riot要求完成人机验证，请使用Cookie登录"},
+	{err: repositories.ErrRiotTokenExpired, status: http.StatusUnauthorized, code: "RIOT_TOKEN_EXPIRED", message: "Riot令牌已失效，请重新登录"},
+	{err: repositories.ErrRegionMismatch, status: http.StatusConflict, code: "REGION_MISMATCH", message: "账号不属于当前区域，请修改区域后重试"},
+	{err: repositories.ErrUpstreamUnavailable, status: http.StatusBadGateway, code: "RIOT_UNAVAILABLE", message: "Riot服务暂时不可用，请稍后重试"},
+	{err: repositories.ErrShopSnapshotNotFound, status: http.StatusNotFound, code: "SHOP_SNAPSHOT_NOT_FOUND", message: "该日期没有商店记录"},
+	{err: repositories.ErrNotFound, status: http.StatusNotFound, code: "NOT_FOUND", message: "资源不存在"},
+	{err: services.ErrNightMarketNotActive, status: http.StatusNotFound, code: "NIGHT_MARKET_NOT_ACTIVE", message: "当前没有开放的夜市"},
+	{err: services.ErrLoadoutInvalid, status: http.StatusBadRequest, code: "LOADOUT_INVALID", message: "无效的装备"},
+	{err: services.ErrItemNotOwned, status: http.StatusForbidden, code: "ITEM_NOT_OWNED", message: "未拥有要装备的物品"},
+	{err: services.ErrInvalidHistoryQuery, status: http.StatusBadRequest, code: "INVALID_REQUEST", message: "无效的请求参数"},
+	{err: services.ErrInvalidImageOptions, status: http.StatusBadRequest, code: "INVALID_REQUEST", message: "无效的请求参数"},
+	{err: services.ErrSkinNotFound, status: http.StatusNotFound, code: "SKIN_NOT_FOUND", message: "皮肤未找到"},
+}
+
+// AbortWithError 记录错误并中止请求，由ErrorHandler生成错误响应
+// message是无法识别的错误（500）使用的提示
+func AbortWithError(c *gin.Context, message string, err error) {
+	c.Error(err).SetMeta(message)
+	c.Abort()
+}
+
+// ErrorHandler 创建错误处理中间件，把处理器通过AbortWithError记录的错误转换为统一的错误响应
+// 已知的错误返回对应的状态码和错误码，其他错误返回500
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		// 客户端已断开时不再写入响应，请求超时（DeadlineExceeded）仍然返回504
+		if errors.Is(c.Request.Context().Err(), context.Canceled) {
+			return
+		}
+
+		last := c.Errors.Last()
+		message, _ := last.Meta.(string)
+		if message == "" {
+			message = "请求失败"
+		}
+		respondError(c, last.Err, message)
+	}
+}
+
+// respondError 按错误类型写入错误响应
+// 响应中只包含每个错误码固定的说明，完整的错误链（可能包含Riot响应、地址和内部状态）只写入服务器日志
+func respondError(c *gin.Context, err error, message string) {
+	fmt.Printf("请求 %s %s 失败: %v\n", c.Request.Method, c.Request.URL.Path, err)
+
+	var rateLimitErr *repositories.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		retryAfter := max(int(math.Ceil(rateLimitErr.RetryAfter.Seconds())), 1)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, models.APIError{
+			Status:     http.StatusTooManyRequests,
+			Message:    "Riot请求过于频繁，请稍后重试",
+			Error:      repositories.ErrRateLimited.Error(),
+			Code:       "RIOT_RATE_LIMITED",
+			RetryAfter: retryAfter,
+		})
+		return
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, models.APIError{
+			Status:  http.StatusGatewayTimeout,
+			Message: "请求Riot服务超时",
+			Error:   "Riot服务没有在规定时间内响应",
+			Code:    "RIOT_TIMEOUT",
+		})
+		return
+	}
+
+	for _, mapping := range errorMappings {
+		if !errors.Is(err, mapping.err) {
+			continue
+		}
+		detail := mapping.detail
+		if detail == "" {
+			detail = mapping.err.Error()
+		}
+		c.JSON(mapping.status, models.APIError{
+			Status:  mapping.status,
+			Message: mapping.message,
+			Error:   detail,
+			Code:    mapping.code,
+		})
+		return
+	}
+
+	// 其他Riot返回的错误状态码
+	var statusErr *repositories.RiotStatusError
+	if errors.As(err, &statusErr) {
+		c.JSON(http.StatusBadGateway, models.APIError{
+			Status:  http.StatusBadGateway,
+			Message: message,
+			Error:   "Riot返回了错误",
+			Code:    "RIOT_REQUEST_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.APIError{
+		Status:  http.StatusInternalServerError,
+		Message: message,
+		Error:   "服务器内部错误",
+		Code:    "INTERNAL_ERROR",
+	})
+}
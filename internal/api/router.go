@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"net/http"
-	"strings"
+	"path/filepath"
+	"time"
 
 	"github.com/emper0r/val-store/server/internal/api/handlers"
 	"github.com/emper0r/val-store/server/internal/api/middleware"
@@ -13,35 +16,123 @@ import (
 )
 
 // SetupRouter 设置所有API路由
-func SetupRouter(router *gin.Engine) *gin.Engine {
+func SetupRouter(router *gin.Engine, cfg *config.Config) *gin.Engine {
 	// 配置CORS中间件
-	router.Use(corsMiddleware())
+	router.Use(corsMiddleware(cfg.AllowedOrigins))
+
+	// 处理器记录的错误统一转换为错误响应
+	router.Use(middleware.ErrorHandler())
+
+	// 每个请求的总超时，超时后处理器中的Riot请求立即取消并返回504
+	router.Use(middleware.Timeout(cfg.RequestDeadline()))
 
 	// 初始化存储库
-	valorantAPI, err := repositories.NewValorantAPI()
+	valorantAPI, err := repositories.NewValorantAPI(cfg.RiotEndpoints())
+	if err != nil {
+		panic(err)
+	}
+	valorantAPI.SetTimeouts(cfg.RiotTimeouts())
+	valorantAPI.SetRateLimit(cfg.RiotRateLimit, cfg.RiotRateBurst)
+
+	skinDatabase, err := repositories.NewSkinDatabase(filepath.Join(cfg.DataPath, repositories.SkinsDBFileName))
+	if err != nil {
+		panic(err)
+	}
+
+	// 初始化会话存储（memory、file或bolt）
+	sessionStore, err := repositories.NewSessionStore(cfg.SessionStore, cfg.DataPath)
+	if err != nil {
+		panic(err)
+	}
+	go expireSessions(sessionStore, 10*time.Minute)
+
+	tokenStore, err := repositories.NewTokenStore(filepath.Join(cfg.DataPath, repositories.TokensFileName))
+	if err != nil {
+		panic(err)
+	}
+
+	shopHistoryStore, err := repositories.NewShopHistoryStore(filepath.Join(cfg.DataPath, repositories.ShopHistoryDirName))
+	if err != nil {
+		panic(err)
+	}
+
+	wishlistStore, err := repositories.NewWishlistStore(filepath.Join(cfg.DataPath, repositories.WishlistsFileName))
 	if err != nil {
 		panic(err)
 	}
 
-	skinDatabase, err := repositories.NewSkinDatabase("")
+	webhookStore, err := repositories.NewWebhookStore(filepath.Join(cfg.DataPath, repositories.WebhooksFileName))
+	if err != nil {
+		panic(err)
+	}
+
+	linkStore, err := repositories.NewLinkStore(filepath.Join(cfg.DataPath, repositories.LinksFileName))
+	if err != nil {
+		panic(err)
+	}
+	iconCache, err := repositories.NewIconCache(filepath.Join(cfg.DataPath, repositories.IconCacheDirName))
 	if err != nil {
 		panic(err)
 	}
 
 	// 初始化服务
-	authService := services.NewAuthService(valorantAPI)
-	shopService := services.NewShopService(valorantAPI, skinDatabase)
+	authService := services.NewAuthService(cfg, valorantAPI, tokenStore)
+	priceService := services.NewPriceService(valorantAPI, skinDatabase, cfg.OffersRefreshInterval())
+	inventoryService := services.NewInventoryService(valorantAPI, skinDatabase)
+	shopService := services.NewShopService(valorantAPI, skinDatabase, sessionStore, priceService, inventoryService, shopHistoryStore)
 	userService := services.NewUserService(valorantAPI)
-	skinsService := services.NewSkinsService(valorantAPI, skinDatabase)
+	catalogAPI := repositories.NewCatalogAPI(cfg.SkinsAPIBaseURL, cfg.SkinsLanguage)
+	skinsService := services.NewSkinsService(valorantAPI, skinDatabase, catalogAPI, cfg.SkinsDumpFile)
+	sessionService := services.NewSessionService(valorantAPI, sessionStore)
+	loadoutService := services.NewLoadoutService(valorantAPI, skinDatabase, inventoryService)
+	wishlistService := services.NewWishlistService(wishlistStore, skinDatabase, shopService, sessionService)
+	webhookService := services.NewWebhookService(webhookStore)
+	discordService, err := services.NewDiscordService(cfg.DiscordPublicKey, linkStore, sessionService, shopService, userService, wishlistService)
+	if err != nil {
+		panic(err)
+	}
+	shopImageService, err := services.NewShopImageService(iconCache, cfg.ShopImageFont)
+	if err != nil {
+		panic(err)
+	}
+	telegramService := services.NewTelegramService(cfg.TelegramBotToken, cfg.TelegramWebhookSecret, cfg.TelegramAPIBaseURL, linkStore, sessionService, shopService, userService)
+
+	// 登录和刷新令牌通过会话服务写入会话，与重新认证使用同一把用户锁
+	authService.SetSessionService(sessionService)
+
+	// 商店、愿望单和会话事件通过Webhook和Telegram推送
+	notifiers := services.Notifiers{webhookService, telegramService}
+	shopService.SetNotifier(notifiers)
+	sessionService.SetNotifier(notifiers)
+	wishlistService.SetNotifier(notifiers)
+	wishlistService.SetShopWatchers(func() []string {
+		return append(webhookService.ShopSubscribers(), telegramService.ShopSubscribers()...)
+	})
+
+	// 按配置在启动时后台更新皮肤数据库
+	if cfg.UpdateSkinsOnStartup {
+		go func() {
+			if err := skinsService.UpdateSkinsDatabase(context.Background()); err != nil {
+				fmt.Printf("启动时更新皮肤数据库失败: %v\n", err)
+				return
+			}
+			fmt.Printf("启动时更新皮肤数据库完成，共 %d 个皮肤\n", skinDatabase.Count())
+		}()
+	}
 
-	// 设置AuthService的会话缓存为ShopService
-	authService.SetSessionCache(shopService)
+	// 每次每日商店刷新后在后台检查愿望单和订阅了商店事件的用户
+	go wishlistService.RunScheduler(context.Background())
 
 	// 初始化处理器
 	authHandler := handlers.NewAuthHandler(authService, shopService)
-	shopHandler := handlers.NewShopHandler(shopService)
-	userHandler := handlers.NewUserHandler(userService, shopService)
+	shopHandler := handlers.NewShopHandler(shopService, sessionService, shopImageService)
+	userHandler := handlers.NewUserHandler(userService, shopService, sessionService, inventoryService)
 	skinsHandler := handlers.NewSkinsHandler(skinsService)
+	loadoutHandler := handlers.NewLoadoutHandler(loadoutService, sessionService)
+	wishlistHandler := handlers.NewWishlistHandler(wishlistService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	discordHandler := handlers.NewDiscordHandler(discordService)
+	telegramHandler := handlers.NewTelegramHandler(telegramService)
 
 	// 创建身份验证中间件
 	authMiddleware := middleware.AuthMiddleware(authService)
@@ -50,22 +141,40 @@ func SetupRouter(router *gin.Engine) *gin.Engine {
 	api := router.Group("/api")
 	{
 		// 注册各个处理器的路由
-		authHandler.RegisterRoutes(api)
+		authHandler.RegisterRoutes(api, authMiddleware)
 		shopHandler.RegisterRoutes(api, authMiddleware)
 		userHandler.RegisterRoutes(api, authMiddleware)
+		loadoutHandler.RegisterRoutes(api, authMiddleware)
+		wishlistHandler.RegisterRoutes(api, authMiddleware)
+		webhookHandler.RegisterRoutes(api, authMiddleware)
+		discordHandler.RegisterRoutes(api, authMiddleware)
+		telegramHandler.RegisterRoutes(api, authMiddleware)
 		skinsHandler.RegisterRoutes(api)
 	}
 
 	return router
 }
 
-// corsMiddleware 创建CORS中间件
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 获取允许的域名列表
-		allowedOrigins := config.GetEnv("ALLOWED_ORIGINS", "http://localhost:3000")
-		origins := strings.Split(allowedOrigins, ",")
+// expireSessions 定期清理会话存储中已过期的会话
+func expireSessions(store repositories.SessionStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
+	for range ticker.C {
+		removed, err := store.Expire(time.Now())
+		if err != nil {
+			fmt.Printf("清理过期会话失败: %v\n", err)
+			continue
+		}
+		if removed > 0 {
+			fmt.Printf("已清理 %d 个过期会话\n", removed)
+		}
+	}
+}
+
+// corsMiddleware 创建CORS中间件，origins为允许的域名列表
+func corsMiddleware(origins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		// 获取请求的Origin
 		origin := c.Request.Header.Get("Origin")
 
@@ -1,36 +1,549 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/emper0r/val-store/server/internal/repositories"
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
-// LoadConfig 加载.env文件中的配置
-func LoadConfig() error {
-	// 获取当前工作目录
-	wd, err := os.Getwd()
+const (
+	// DevelopmentJWTSecret 开发环境使用的默认JWT密钥，release模式下禁止使用
+	DevelopmentJWTSecret = "val-store-secret-key-development-only"
+
+	// exampleJWTSecret .env.example中的占位密钥，release模式下同样禁止使用
+	exampleJWTSecret = "change_this_to_a_secure_secret_key"
+
+	// writeTimeoutMargin HTTP写超时比请求总超时多出的时间，用于写入超时错误响应
+	writeTimeoutMargin = 15 * time.Second
+)
+
+// Config 服务器配置
+// 优先级（从高到低）：命令行参数 > 环境变量（含.env文件） > 配置文件 > 默认值
+type Config struct {
+	Port                  string   `yaml:"port" toml:"port"`
+	GinMode               string   `yaml:"gin_mode" toml:"gin_mode"`
+	JWTSecret             string   `yaml:"jwt_secret" toml:"jwt_secret"`
+	JWTExpirationHours    int      `yaml:"jwt_expiration_hours" toml:"jwt_expiration_hours"`
+	JWTAccessExpiration   string   `yaml:"jwt_access_expiration" toml:"jwt_access_expiration"` // 访问令牌的有效期（如15m）
+	DataPath              string   `yaml:"data_path" toml:"data_path"`
+	UpdateSkinsOnStartup  bool     `yaml:"update_skins_on_startup" toml:"update_skins_on_startup"`
+	AllowedOrigins        []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	SessionStore          string   `yaml:"session_store" toml:"session_store"`
+	SkinsAPIBaseURL       string   `yaml:"skins_api_base_url" toml:"skins_api_base_url"`
+	SkinsLanguage         string   `yaml:"skins_language" toml:"skins_language"`
+	SkinsDumpFile         string   `yaml:"skins_dump_file" toml:"skins_dump_file"`
+	OffersRefreshHours    int      `yaml:"offers_refresh_hours" toml:"offers_refresh_hours"`
+	DiscordPublicKey      string   `yaml:"discord_public_key" toml:"discord_public_key"`
+	TelegramBotToken      string   `yaml:"telegram_bot_token" toml:"telegram_bot_token"`
+	TelegramWebhookSecret string   `yaml:"telegram_webhook_secret" toml:"telegram_webhook_secret"`
+	TelegramAPIBaseURL    string   `yaml:"telegram_api_base_url" toml:"telegram_api_base_url"`
+	ShopImageFont         string   `yaml:"shop_image_font" toml:"shop_image_font"`
+
+	// Riot接口地址，可以指向本地的替代服务。PD和Shared地址中的{shard}会替换为分片代码
+	RiotAuthURL         string            `yaml:"riot_auth_url" toml:"riot_auth_url"`
+	RiotEntitlementsURL string            `yaml:"riot_entitlements_url" toml:"riot_entitlements_url"`
+	RiotPDURL           string            `yaml:"riot_pd_url" toml:"riot_pd_url"`
+	RiotSharedURL       string            `yaml:"riot_shared_url" toml:"riot_shared_url"`
+	RiotShardURLs       map[string]string `yaml:"riot_shard_urls" toml:"riot_shard_urls"` // 按分片覆盖PD和Shared地址
+
+	// 每个API请求的总超时时间（如60s），包括处理器依次发出的所有Riot请求
+	RequestTimeout string `yaml:"request_timeout" toml:"request_timeout"`
+
+	// Riot请求的超时时间（如30s），包括该次请求的所有重试
+	RiotTimeout      string            `yaml:"riot_timeout" toml:"riot_timeout"`
+	RiotCallTimeouts map[string]string `yaml:"riot_timeouts" toml:"riot_timeouts"` // 按请求名称覆盖超时时间，如storefront=10s
+
+	// 每个Riot主机的限流，所有用户共享。RiotRateLimit为每秒请求数，为0时不限流
+	RiotRateLimit float64 `yaml:"riot_rate_limit" toml:"riot_rate_limit"`
+	RiotRateBurst int     `yaml:"riot_rate_burst" toml:"riot_rate_burst"`
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Port:                 "8080",
+		GinMode:              "debug",
+		JWTSecret:            DevelopmentJWTSecret,
+		JWTExpirationHours:   24,
+		JWTAccessExpiration:  "15m",
+		DataPath:             "./data",
+		UpdateSkinsOnStartup: false,
+		AllowedOrigins:       []string{"http://localhost:3000"},
+		SessionStore:         "file",
+		SkinsAPIBaseURL:      "https://valorant-api.com",
+		SkinsLanguage:        "zh-CN",
+		OffersRefreshHours:   12,
+		TelegramAPIBaseURL:   "https://api.telegram.org",
+		RiotAuthURL:          "https://auth.riotgames.com",
+		RiotEntitlementsURL:  "https://entitlements.auth.riotgames.com",
+		RiotPDURL:            "https://pd.{shard}.a.pvp.net",
+		RiotSharedURL:        "https://shared.{shard}.a.pvp.net",
+		RequestTimeout:       "60s",
+		RiotTimeout:          repositories.DefaultRiotTimeout.String(),
+		RiotRateLimit:        repositories.DefaultRiotRateLimit,
+		RiotRateBurst:        repositories.DefaultRiotRateBurst,
+	}
+}
+
+// JWTExpiration 刷新令牌和会话的有效期
+func (c *Config) JWTExpiration() time.Duration {
+	return time.Duration(c.JWTExpirationHours) * time.Hour
+}
+
+// AccessTokenExpiration JWT访问令牌的有效期，无效的时间在Validate中报告
+func (c *Config) AccessTokenExpiration() time.Duration {
+	expiration, _ := time.ParseDuration(c.JWTAccessExpiration)
+	return expiration
+}
+
+// OffersRefreshInterval 商店价格表的刷新间隔
+func (c *Config) OffersRefreshInterval() time.Duration {
+	return time.Duration(c.OffersRefreshHours) * time.Hour
+}
+
+// RiotEndpoints Riot接口地址，皮肤数据接口用于获取客户端版本
+func (c *Config) RiotEndpoints() *repositories.RiotEndpoints {
+	return &repositories.RiotEndpoints{
+		Auth:         c.RiotAuthURL,
+		Entitlements: c.RiotEntitlementsURL,
+		PD:           c.RiotPDURL,
+		Shared:       c.RiotSharedURL,
+		Catalog:      c.SkinsAPIBaseURL,
+		Shards:       c.RiotShardURLs,
+	}
+}
+
+// RiotTimeouts Riot请求的超时配置，无效的时间在Validate中报告
+func (c *Config) RiotTimeouts() repositories.RiotTimeouts {
+	timeouts := repositories.RiotTimeouts{Calls: make(map[string]time.Duration)}
+	timeouts.Default, _ = time.ParseDuration(c.RiotTimeout)
+	for call, value := range c.RiotCallTimeouts {
+		timeouts.Calls[call], _ = time.ParseDuration(value)
+	}
+	return timeouts
+}
+
+// RequestDeadline 每个API请求的总超时时间，无效的时间在Validate中报告
+func (c *Config) RequestDeadline() time.Duration {
+	timeout, _ := time.ParseDuration(c.RequestTimeout)
+	return timeout
+}
+
+// WriteTimeout HTTP服务器的写超时，比请求的总超时多出writeTimeoutMargin，请求超时后仍有时间写入504响应
+func (c *Config) WriteTimeout() time.Duration {
+	return c.RequestDeadline() + writeTimeoutMargin
+}
+
+// Load 依次读取默认值、配置文件、环境变量和命令行参数，返回校验后的配置
+// 配置文件通过 -config 参数或 CONFIG_FILE 环境变量指定，支持 .yaml/.yml/.toml
+func Load(args []string) (*Config, error) {
+	loadDotEnv()
+
+	cfg := Default()
+
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := flags.String("config", GetEnv("CONFIG_FILE", ""), "配置文件路径（.yaml/.yml/.toml）")
+	port := flags.String("port", "", "监听端口")
+	ginMode := flags.String("gin-mode", "", "Gin模式: debug, release, test")
+	jwtSecret := flags.String("jwt-secret", "", "JWT签名密钥")
+	jwtExpirationHours := flags.Int("jwt-expiration-hours", 0, "刷新令牌和会话有效期（小时）")
+	jwtAccessExpiration := flags.String("jwt-access-expiration", "", "访问令牌有效期，如15m")
+	dataPath := flags.String("data-path", "", "本地数据目录")
+	updateSkins := flags.Bool("update-skins-on-startup", false, "启动时更新皮肤数据库")
+	allowedOrigins := flags.String("allowed-origins", "", "允许跨域的来源，逗号分隔")
+	sessionStore := flags.String("session-store", "", "会话存储方式: memory, file, bolt")
+	skinsAPIBaseURL := flags.String("skins-api-base-url", "", "皮肤数据接口地址（valorant-api.com或兼容服务）")
+	skinsLanguage := flags.String("skins-language", "", "皮肤数据语言，如zh-CN、en-US")
+	skinsDumpFile := flags.String("skins-dump-file", "", "离线皮肤数据文件，设置后不再从网络获取")
+	offersRefreshHours := flags.Int("offers-refresh-hours", 0, "商店价格表刷新间隔（小时）")
+	discordPublicKey := flags.String("discord-public-key", "", "Discord应用公钥（十六进制），为空时不启用Discord交互")
+	telegramBotToken := flags.String("telegram-bot-token", "", "Telegram机器人令牌，为空时不启用Telegram机器人")
+	telegramSecret := flags.String("telegram-webhook-secret", "", "Telegram Webhook地址中的密钥")
+	telegramAPIBaseURL := flags.String("telegram-api-base-url", "", "Telegram Bot API地址")
+	riotAuthURL := flags.String("riot-auth-url", "", "Riot认证服务地址")
+	riotEntitlementsURL := flags.String("riot-entitlements-url", "", "Riot授权令牌服务地址")
+	riotPDURL := flags.String("riot-pd-url", "", "Riot PD服务地址，{shard}替换为分片代码")
+	riotSharedURL := flags.String("riot-shared-url", "", "Riot Shared服务地址，{shard}替换为分片代码")
+	riotShardURLs := flags.String("riot-shard-urls", "", "按分片覆盖PD和Shared地址，格式: na=http://localhost:9000,eu=...")
+	requestTimeout := flags.String("request-timeout", "", "每个API请求的总超时时间，如60s")
+	riotTimeout := flags.String("riot-timeout", "", "Riot请求的超时时间，如30s")
+	riotTimeouts := flags.String("riot-timeouts", "", "按请求名称覆盖超时时间，格式: storefront=10s,wallet=5s")
+	riotRateLimit := flags.Float64("riot-rate-limit", 0, "每个Riot主机每秒允许的请求数，为0时不限流")
+	riotRateBurst := flags.Int("riot-rate-burst", 0, "每个Riot主机允许的突发请求数")
+	shopImageFont := flags.String("shop-image-font", "", "商店图片使用的TTF/OTF字体文件，显示中文皮肤名称时需要")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 配置文件
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// 环境变量
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// 命令行参数，只覆盖显式指定的参数
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = *port
+		case "gin-mode":
+			cfg.GinMode = *ginMode
+		case "jwt-secret":
+			cfg.JWTSecret = *jwtSecret
+		case "jwt-expiration-hours":
+			cfg.JWTExpirationHours = *jwtExpirationHours
+		case "jwt-access-expiration":
+			cfg.JWTAccessExpiration = *jwtAccessExpiration
+		case "data-path":
+			cfg.DataPath = *dataPath
+		case "update-skins-on-startup":
+			cfg.UpdateSkinsOnStartup = *updateSkins
+		case "allowed-origins":
+			cfg.AllowedOrigins = splitList(*allowedOrigins)
+		case "session-store":
+			cfg.SessionStore = *sessionStore
+		case "skins-api-base-url":
+			cfg.SkinsAPIBaseURL = *skinsAPIBaseURL
+		case "skins-language":
+			cfg.SkinsLanguage = *skinsLanguage
+		case "skins-dump-file":
+			cfg.SkinsDumpFile = *skinsDumpFile
+		case "offers-refresh-hours":
+			cfg.OffersRefreshHours = *offersRefreshHours
+		case "discord-public-key":
+			cfg.DiscordPublicKey = *discordPublicKey
+		case "telegram-bot-token":
+			cfg.TelegramBotToken = *telegramBotToken
+		case "telegram-webhook-secret":
+			cfg.TelegramWebhookSecret = *telegramSecret
+		case "telegram-api-base-url":
+			cfg.TelegramAPIBaseURL = *telegramAPIBaseURL
+		case "shop-image-font":
+			cfg.ShopImageFont = *shopImageFont
+		case "riot-auth-url":
+			cfg.RiotAuthURL = *riotAuthURL
+		case "riot-entitlements-url":
+			cfg.RiotEntitlementsURL = *riotEntitlementsURL
+		case "riot-pd-url":
+			cfg.RiotPDURL = *riotPDURL
+		case "riot-shared-url":
+			cfg.RiotSharedURL = *riotSharedURL
+		case "riot-shard-urls":
+			cfg.RiotShardURLs = splitMap(*riotShardURLs)
+		case "request-timeout":
+			cfg.RequestTimeout = *requestTimeout
+		case "riot-timeout":
+			cfg.RiotTimeout = *riotTimeout
+		case "riot-timeouts":
+			cfg.RiotCallTimeouts = splitMap(*riotTimeouts)
+		case "riot-rate-limit":
+			cfg.RiotRateLimit = *riotRateLimit
+		case "riot-rate-burst":
+			cfg.RiotRateBurst = *riotRateBurst
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile 从YAML或TOML配置文件读取配置
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Println("无法获取工作目录:", err)
-		return err
+		return fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
-	// 首先尝试从当前目录加载.env文件
-	err = godotenv.Load()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s（仅支持.yaml、.yml、.toml）", path)
+	}
 	if err != nil {
-		// 如果失败，尝试从项目根目录加载
-		rootPath := filepath.Join(wd, "../../.env")
-		err = godotenv.Load(rootPath)
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv 从环境变量读取配置，未设置的变量保持原值
+func (c *Config) loadEnv() error {
+	if value := os.Getenv("PORT"); value != "" {
+		c.Port = value
+	}
+	if value := os.Getenv("GIN_MODE"); value != "" {
+		c.GinMode = value
+	}
+	if value := os.Getenv("JWT_SECRET"); value != "" {
+		c.JWTSecret = value
+	}
+	if value := os.Getenv("JWT_EXPIRATION_HOURS"); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("JWT_EXPIRATION_HOURS必须是整数，当前值: %q", value)
+		}
+		c.JWTExpirationHours = hours
+	}
+	if value := os.Getenv("JWT_ACCESS_EXPIRATION"); value != "" {
+		c.JWTAccessExpiration = value
+	}
+	if value := os.Getenv("DATA_PATH"); value != "" {
+		c.DataPath = value
+	}
+	if value := os.Getenv("UPDATE_SKINS_ON_STARTUP"); value != "" {
+		update, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("UPDATE_SKINS_ON_STARTUP必须是true或false，当前值: %q", value)
+		}
+		c.UpdateSkinsOnStartup = update
+	}
+	if value := os.Getenv("ALLOWED_ORIGINS"); value != "" {
+		c.AllowedOrigins = splitList(value)
+	}
+	if value := os.Getenv("SESSION_STORE"); value != "" {
+		c.SessionStore = value
+	}
+	if value := os.Getenv("SKINS_API_BASE_URL"); value != "" {
+		c.SkinsAPIBaseURL = value
+	}
+	if value := os.Getenv("SKINS_LANGUAGE"); value != "" {
+		c.SkinsLanguage = value
+	}
+	if value := os.Getenv("SKINS_DUMP_FILE"); value != "" {
+		c.SkinsDumpFile = value
+	}
+	if value := os.Getenv("OFFERS_REFRESH_HOURS"); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("OFFERS_REFRESH_HOURS必须是整数，当前值: %q", value)
+		}
+		c.OffersRefreshHours = hours
+	}
+	if value := os.Getenv("DISCORD_PUBLIC_KEY"); value != "" {
+		c.DiscordPublicKey = value
+	}
+	if value := os.Getenv("TELEGRAM_BOT_TOKEN"); value != "" {
+		c.TelegramBotToken = value
+	}
+	if value := os.Getenv("TELEGRAM_WEBHOOK_SECRET"); value != "" {
+		c.TelegramWebhookSecret = value
+	}
+	if value := os.Getenv("TELEGRAM_API_BASE_URL"); value != "" {
+		c.TelegramAPIBaseURL = value
+	}
+	if value := os.Getenv("SHOP_IMAGE_FONT"); value != "" {
+		c.ShopImageFont = value
+	}
+	if value := os.Getenv("RIOT_AUTH_URL"); value != "" {
+		c.RiotAuthURL = value
+	}
+	if value := os.Getenv("RIOT_ENTITLEMENTS_URL"); value != "" {
+		c.RiotEntitlementsURL = value
+	}
+	if value := os.Getenv("RIOT_PD_URL"); value != "" {
+		c.RiotPDURL = value
+	}
+	if value := os.Getenv("RIOT_SHARED_URL"); value != "" {
+		c.RiotSharedURL = value
+	}
+	if value := os.Getenv("RIOT_SHARD_URLS"); value != "" {
+		c.RiotShardURLs = splitMap(value)
+	}
+	if value := os.Getenv("REQUEST_TIMEOUT"); value != "" {
+		c.RequestTimeout = value
+	}
+	if value := os.Getenv("RIOT_TIMEOUT"); value != "" {
+		c.RiotTimeout = value
+	}
+	if value := os.Getenv("RIOT_TIMEOUTS"); value != "" {
+		c.RiotCallTimeouts = splitMap(value)
+	}
+	if value := os.Getenv("RIOT_RATE_LIMIT"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("RIOT_RATE_LIMIT必须是数字，当前值: %q", value)
+		}
+		c.RiotRateLimit = rate
+	}
+	if value := os.Getenv("RIOT_RATE_BURST"); value != "" {
+		burst, err := strconv.Atoi(value)
 		if err != nil {
-			log.Println("警告: .env文件未找到，将使用环境变量或默认值")
+			return fmt.Errorf("RIOT_RATE_BURST必须是整数，当前值: %q", value)
+		}
+		c.RiotRateBurst = burst
+	}
+
+	return nil
+}
+
+// Validate 校验配置是否合法
+func (c *Config) Validate() error {
+	var problems []string
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("端口无效: %q", c.Port))
+	}
+
+	switch c.GinMode {
+	case "debug", "release", "test":
+	default:
+		problems = append(problems, fmt.Sprintf("GIN_MODE无效: %q（可选: debug, release, test）", c.GinMode))
+	}
+
+	if c.JWTSecret == "" {
+		problems = append(problems, "JWT_SECRET不能为空")
+	} else if c.GinMode == "release" && (c.JWTSecret == DevelopmentJWTSecret || c.JWTSecret == exampleJWTSecret) {
+		problems = append(problems, "release模式下禁止使用默认的JWT_SECRET，请设置一个安全的随机密钥")
+	}
+
+	if c.JWTExpirationHours <= 0 {
+		problems = append(problems, fmt.Sprintf("JWT_EXPIRATION_HOURS必须大于0，当前值: %d", c.JWTExpirationHours))
+	}
+
+	// 访问令牌应当比刷新令牌先过期，否则刷新令牌没有意义
+	if expiration, err := time.ParseDuration(c.JWTAccessExpiration); err != nil || expiration <= 0 {
+		problems = append(problems, fmt.Sprintf("JWT_ACCESS_EXPIRATION必须是大于0的时间，如15m，当前值: %q", c.JWTAccessExpiration))
+	} else if c.JWTExpirationHours > 0 && expiration >= c.JWTExpiration() {
+		problems = append(problems, fmt.Sprintf("JWT_ACCESS_EXPIRATION必须小于JWT_EXPIRATION_HOURS，当前值: %q", c.JWTAccessExpiration))
+	}
+
+	if c.DataPath == "" {
+		problems = append(problems, "DATA_PATH不能为空")
+	}
+
+	switch c.SessionStore {
+	case "memory", "file", "bolt":
+	default:
+		problems = append(problems, fmt.Sprintf("SESSION_STORE无效: %q（可选: memory, file, bolt）", c.SessionStore))
+	}
+
+	if c.OffersRefreshHours <= 0 {
+		problems = append(problems, fmt.Sprintf("OFFERS_REFRESH_HOURS必须大于0，当前值: %d", c.OffersRefreshHours))
+	}
+
+	if c.DiscordPublicKey != "" {
+		if key, err := hex.DecodeString(c.DiscordPublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			problems = append(problems, "DISCORD_PUBLIC_KEY必须是64位十六进制的Ed25519公钥")
+		}
+	}
+
+	if c.TelegramBotToken != "" {
+		if len(c.TelegramWebhookSecret) < 16 {
+			problems = append(problems, "启用Telegram机器人时TELEGRAM_WEBHOOK_SECRET至少需要16个字符")
+		}
+		if u, err := url.Parse(c.TelegramAPIBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("TELEGRAM_API_BASE_URL无效: %q", c.TelegramAPIBaseURL))
 		}
 	}
 
+	// Riot接口地址和皮肤数据接口地址（RIOT_*_URL、RIOT_SHARD_URLS、SKINS_API_BASE_URL）
+	if err := c.RiotEndpoints().Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if timeout, err := time.ParseDuration(c.RequestTimeout); err != nil || timeout <= 0 {
+		problems = append(problems, fmt.Sprintf("REQUEST_TIMEOUT必须是大于0的时间，如60s，当前值: %q", c.RequestTimeout))
+	}
+
+	if timeout, err := time.ParseDuration(c.RiotTimeout); err != nil || timeout <= 0 {
+		problems = append(problems, fmt.Sprintf("RIOT_TIMEOUT必须是大于0的时间，如30s，当前值: %q", c.RiotTimeout))
+	}
+	validTimeouts := true
+	for _, call := range slices.Sorted(maps.Keys(c.RiotCallTimeouts)) {
+		if _, err := time.ParseDuration(c.RiotCallTimeouts[call]); err != nil {
+			problems = append(problems, fmt.Sprintf("RIOT_TIMEOUTS中%s的超时时间无效: %q", call, c.RiotCallTimeouts[call]))
+			validTimeouts = false
+		}
+	}
+	if validTimeouts {
+		if err := c.RiotTimeouts().Validate(); err != nil {
+			problems = append(problems, "RIOT_TIMEOUTS无效: "+err.Error())
+		}
+	}
+
+	if c.RiotRateLimit < 0 {
+		problems = append(problems, fmt.Sprintf("RIOT_RATE_LIMIT不能为负数，当前值: %v", c.RiotRateLimit))
+	}
+	if c.RiotRateBurst <= 0 {
+		problems = append(problems, fmt.Sprintf("RIOT_RATE_BURST必须大于0，当前值: %d", c.RiotRateBurst))
+	}
+
+	if len(problems) > 0 {
+		return errors.New("配置无效:\n  - " + strings.Join(problems, "\n  - "))
+	}
+
 	return nil
 }
 
+// loadDotEnv 加载.env文件中的配置
+func loadDotEnv() {
+	// 首先尝试从当前目录加载.env文件
+	if err := godotenv.Load(); err == nil {
+		return
+	}
+
+	// 如果失败，尝试从项目根目录加载
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Println("无法获取工作目录:", err)
+		return
+	}
+	rootPath := filepath.Join(wd, "../../.env")
+	if err := godotenv.Load(rootPath); err != nil {
+		log.Println("警告: .env文件未找到，将使用环境变量或默认值")
+	}
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitMap 解析逗号分隔的key=value列表，格式错误的项保留为空值，由Validate报告
+func splitMap(value string) map[string]string {
+	items := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, _ := strings.Cut(item, "=")
+		items[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(val)
+	}
+	return items
+}
+
 // GetEnv 获取环境变量值，如果不存在则返回默认值
 func GetEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
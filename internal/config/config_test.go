@@ -0,0 +1,222 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile 在临时目录中写入配置文件并返回路径
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := "port: \"9001\"\nsession_store: memory\nriot_timeout: 20s\n"
+	tomlFile := "port = \"9001\"\nsession_store = \"memory\"\nriot_timeout = \"20s\"\n"
+
+	tests := []struct {
+		name         string
+		file         string
+		content      string
+		fileFromEnv  bool // 通过CONFIG_FILE而不是-config指定配置文件
+		env          map[string]string
+		args         []string
+		port         string
+		sessionStore string
+		riotTimeout  string
+	}{
+		{
+			name: "默认值",
+			port: "8080", sessionStore: "file", riotTimeout: "30s",
+		},
+		{
+			name: "YAML配置文件覆盖默认值", file: "config.yaml", content: yamlFile,
+			port: "9001", sessionStore: "memory", riotTimeout: "20s",
+		},
+		{
+			name: "TOML配置文件覆盖默认值", file: "config.toml", content: tomlFile,
+			port: "9001", sessionStore: "memory", riotTimeout: "20s",
+		},
+		{
+			name: "环境变量覆盖配置文件", file: "config.yaml", content: yamlFile,
+			env:  map[string]string{"PORT": "9002", "RIOT_TIMEOUT": "10s"},
+			port: "9002", sessionStore: "memory", riotTimeout: "10s",
+		},
+		{
+			name: "命令行参数覆盖环境变量", file: "config.yaml", content: yamlFile,
+			env:  map[string]string{"PORT": "9002", "RIOT_TIMEOUT": "10s"},
+			args: []string{"-port", "9003"},
+			port: "9003", sessionStore: "memory", riotTimeout: "10s",
+		},
+		{
+			name: "CONFIG_FILE环境变量指定配置文件", file: "config.yaml", content: yamlFile, fileFromEnv: true,
+			env:  map[string]string{"SESSION_STORE": "bolt"},
+			port: "9001", sessionStore: "bolt", riotTimeout: "20s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 避免读取仓库中的.env文件
+			t.Chdir(t.TempDir())
+
+			args := tt.args
+			if tt.file != "" {
+				path := writeConfigFile(t, tt.file, tt.content)
+				if tt.fileFromEnv {
+					t.Setenv("CONFIG_FILE", path)
+				} else {
+					args = append([]string{"-config", path}, args...)
+				}
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatalf("加载配置失败: %v", err)
+			}
+			if cfg.Port != tt.port || cfg.SessionStore != tt.sessionStore || cfg.RiotTimeout != tt.riotTimeout {
+				t.Fatalf("得到 port=%s session_store=%s riot_timeout=%s，期望 port=%s session_store=%s riot_timeout=%s",
+					cfg.Port, cfg.SessionStore, cfg.RiotTimeout, tt.port, tt.sessionStore, tt.riotTimeout)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		args    []string
+		want    string
+	}{
+		{name: "不支持的配置文件格式", file: "config.json", content: "{}", want: "不支持的配置文件格式"},
+		{name: "配置文件格式错误", file: "config.yaml", content: "port: [", want: "解析配置文件"},
+		{name: "环境变量不是整数", env: map[string]string{"JWT_EXPIRATION_HOURS": "abc"}, want: "JWT_EXPIRATION_HOURS必须是整数"},
+		{name: "环境变量不是布尔值", env: map[string]string{"UPDATE_SKINS_ON_STARTUP": "maybe"}, want: "UPDATE_SKINS_ON_STARTUP必须是true或false"},
+		{name: "未知的命令行参数", args: []string{"-unknown"}, want: "flag provided but not defined"},
+		{name: "加载后校验", args: []string{"-session-store", "redis"}, want: "SESSION_STORE无效"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, tt.file, tt.content)}, args...)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("应当返回包含 %q 的错误，得到 %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   string // 为空时期望校验通过
+	}{
+		{name: "默认配置", modify: func(cfg *Config) {}},
+		{name: "release模式使用自定义密钥", modify: func(cfg *Config) {
+			cfg.GinMode = "release"
+			cfg.JWTSecret = "a-long-random-production-secret"
+		}},
+		{name: "端口不是数字", modify: func(cfg *Config) { cfg.Port = "http" }, want: "端口无效"},
+		{name: "端口超出范围", modify: func(cfg *Config) { cfg.Port = "70000" }, want: "端口无效"},
+		{name: "GIN_MODE无效", modify: func(cfg *Config) { cfg.GinMode = "production" }, want: "GIN_MODE无效"},
+		{name: "JWT_SECRET为空", modify: func(cfg *Config) { cfg.JWTSecret = "" }, want: "JWT_SECRET不能为空"},
+		{name: "release模式使用开发密钥", modify: func(cfg *Config) {
+			cfg.GinMode = "release"
+		}, want: "release模式下禁止使用默认的JWT_SECRET"},
+		{name: "release模式使用示例密钥", modify: func(cfg *Config) {
+			cfg.GinMode = "release"
+			cfg.JWTSecret = exampleJWTSecret
+		}, want: "release模式下禁止使用默认的JWT_SECRET"},
+		{name: "刷新令牌有效期为0", modify: func(cfg *Config) { cfg.JWTExpirationHours = 0 }, want: "JWT_EXPIRATION_HOURS必须大于0"},
+		{name: "访问令牌有效期格式错误", modify: func(cfg *Config) { cfg.JWTAccessExpiration = "15" }, want: "JWT_ACCESS_EXPIRATION必须是大于0的时间"},
+		{name: "访问令牌比刷新令牌长", modify: func(cfg *Config) { cfg.JWTAccessExpiration = "48h" }, want: "JWT_ACCESS_EXPIRATION必须小于JWT_EXPIRATION_HOURS"},
+		{name: "DATA_PATH为空", modify: func(cfg *Config) { cfg.DataPath = "" }, want: "DATA_PATH不能为空"},
+		{name: "SESSION_STORE无效", modify: func(cfg *Config) { cfg.SessionStore = "redis" }, want: "SESSION_STORE无效"},
+		{name: "价格表刷新间隔为0", modify: func(cfg *Config) { cfg.OffersRefreshHours = 0 }, want: "OFFERS_REFRESH_HOURS必须大于0"},
+		{name: "皮肤数据接口地址无效", modify: func(cfg *Config) { cfg.SkinsAPIBaseURL = "valorant-api.com" }, want: "Riot接口地址Catalog无效"},
+		{name: "Discord公钥无效", modify: func(cfg *Config) { cfg.DiscordPublicKey = "abcd" }, want: "DISCORD_PUBLIC_KEY"},
+		{name: "Telegram密钥过短", modify: func(cfg *Config) {
+			cfg.TelegramBotToken = "123:abc"
+			cfg.TelegramWebhookSecret = "short"
+		}, want: "TELEGRAM_WEBHOOK_SECRET至少需要16个字符"},
+		{name: "Telegram接口地址无效", modify: func(cfg *Config) {
+			cfg.TelegramBotToken = "123:abc"
+			cfg.TelegramWebhookSecret = "0123456789abcdef"
+			cfg.TelegramAPIBaseURL = "ftp://api.telegram.org"
+		}, want: "TELEGRAM_API_BASE_URL无效"},
+		{name: "Riot认证地址无效", modify: func(cfg *Config) { cfg.RiotAuthURL = "auth.riotgames.com" }, want: "Riot接口地址Auth无效"},
+		{name: "分片地址无效", modify: func(cfg *Config) {
+			cfg.RiotShardURLs = map[string]string{"eu": "localhost:9000"}
+		}, want: "Riot接口地址Shards.eu无效"},
+		{name: "请求总超时为0", modify: func(cfg *Config) { cfg.RequestTimeout = "0s" }, want: "REQUEST_TIMEOUT必须是大于0的时间"},
+		{name: "Riot超时格式错误", modify: func(cfg *Config) { cfg.RiotTimeout = "30" }, want: "RIOT_TIMEOUT必须是大于0的时间"},
+		{name: "按请求的超时格式错误", modify: func(cfg *Config) {
+			cfg.RiotCallTimeouts = map[string]string{"storefront": "soon"}
+		}, want: "RIOT_TIMEOUTS中storefront的超时时间无效"},
+		{name: "未知的请求名称", modify: func(cfg *Config) {
+			cfg.RiotCallTimeouts = map[string]string{"shop": "10s"}
+		}, want: "RIOT_TIMEOUTS无效"},
+		{name: "限流为负数", modify: func(cfg *Config) { cfg.RiotRateLimit = -1 }, want: "RIOT_RATE_LIMIT不能为负数"},
+		{name: "突发请求数为0", modify: func(cfg *Config) { cfg.RiotRateBurst = 0 }, want: "RIOT_RATE_BURST必须大于0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("配置应当有效，得到 %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("应当返回包含 %q 的错误，得到 %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := Default()
+	cfg.Port = "0"
+	cfg.SessionStore = "redis"
+	cfg.RiotRateBurst = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("配置应当无效")
+	}
+	for _, want := range []string{"端口无效", "SESSION_STORE无效", "RIOT_RATE_BURST必须大于0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("错误中应当包含 %q，得到 %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package mockriot
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 可以注入错误的接口名称
+const (
+	EndpointAuthorization = "authorization" // 登录和二次验证
+	EndpointAuthorize     = "authorize"     // Cookie重新授权
+	EndpointUserInfo      = "userinfo"
+	EndpointEntitlements  = "entitlements"
+	EndpointNameService   = "name-service"
+	EndpointStorefront    = "storefront"
+	EndpointWallet        = "wallet"
+	EndpointOffers        = "offers"
+	EndpointOwnedItems    = "owned-items"
+	EndpointLoadout       = "loadout"
+	EndpointContent       = "content"
+	EndpointVersion       = "version"
+)
+
+// endpointNames 所有接口名称，用于检查错误配置
+var endpointNames = map[string]bool{
+	EndpointAuthorization: true,
+	EndpointAuthorize:     true,
+	EndpointUserInfo:      true,
+	EndpointEntitlements:  true,
+	EndpointNameService:   true,
+	EndpointStorefront:    true,
+	EndpointWallet:        true,
+	EndpointOffers:        true,
+	EndpointOwnedItems:    true,
+	EndpointLoadout:       true,
+	EndpointContent:       true,
+	EndpointVersion:       true,
+}
+
+// Fault 注入到接口的错误或延迟
+type Fault struct {
+	Endpoint   string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`       // 接口名称，为空时匹配所有接口
+	Status     int    `json:"status,omitempty" yaml:"status,omitempty"`           // 返回的状态码，如401、429、503，为0时只延迟
+	DelayMS    int    `json:"delay_ms,omitempty" yaml:"delay_ms,omitempty"`       // 响应前等待的毫秒数
+	RetryAfter int    `json:"retry_after,omitempty" yaml:"retry_after,omitempty"` // Retry-After响应头的秒数
+	Count      int    `json:"count,omitempty" yaml:"count,omitempty"`             // 生效次数，为0时一直生效
+}
+
+// validate 检查错误配置是否有效
+func (f *Fault) validate() error {
+	if f.Endpoint != "" && !endpointNames[f.Endpoint] {
+		return fmt.Errorf("未知的接口名称: %s", f.Endpoint)
+	}
+	if f.Status != 0 && (f.Status < 400 || f.Status > 599) {
+		return fmt.Errorf("状态码必须是4xx或5xx: %d", f.Status)
+	}
+	if f.Status == 0 && f.DelayMS <= 0 {
+		return fmt.Errorf("至少需要设置status或delay_ms")
+	}
+	if f.DelayMS < 0 || f.RetryAfter < 0 || f.Count < 0 {
+		return fmt.Errorf("delay_ms、retry_after和count不能为负数")
+	}
+	return nil
+}
+
+// SetFaults 替换所有注入的错误
+func (s *Server) SetFaults(faults []Fault) error {
+	for i := range faults {
+		if err := faults[i].validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults = append([]Fault(nil), faults...)
+	return nil
+}
+
+// AddFault 追加一个注入的错误，先添加的错误优先匹配
+func (s *Server) AddFault(fault Fault) error {
+	if err := fault.validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults = append(s.faults, fault)
+	return nil
+}
+
+// ClearFaults 清除所有注入的错误
+func (s *Server) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults = nil
+}
+
+// Faults 返回当前注入的错误，Count为剩余的生效次数
+func (s *Server) Faults() []Fault {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Fault{}, s.faults...)
+}
+
+// takeFault 取出匹配接口的第一个错误，有次数限制的错误用完后移除
+func (s *Server) takeFault(endpoint string) (Fault, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.faults {
+		fault := s.faults[i]
+		if fault.Endpoint != "" && fault.Endpoint != endpoint {
+			continue
+		}
+		if fault.Count > 0 {
+			s.faults[i].Count--
+			if s.faults[i].Count == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return fault, true
+	}
+	return Fault{}, false
+}
+
+// withFaults 在处理请求前应用注入的错误，有状态码时直接返回错误响应
+func (s *Server) withFaults(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fault, exists := s.takeFault(endpoint)
+		if !exists {
+			next(w, r)
+			return
+		}
+
+		if fault.DelayMS > 0 {
+			select {
+			case <-time.After(time.Duration(fault.DelayMS) * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if fault.Status == 0 {
+			next(w, r)
+			return
+		}
+
+		if fault.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(fault.RetryAfter))
+		}
+
+		// 401使用Riot令牌过期时的错误码，后端据此重新认证
+		errorCode := "MOCK_FAULT"
+		if fault.Status == http.StatusUnauthorized {
+			errorCode = "BAD_CLAIMS"
+		}
+		writeRiotError(w, fault.Status, errorCode, "模拟服务注入的错误")
+	}
+}
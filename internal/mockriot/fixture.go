@@ -0,0 +1,157 @@
+package mockriot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emper0r/val-store/server/internal/repositories"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture 模拟服务的数据，可以从JSON或YAML文件加载
+type Fixture struct {
+	Accounts      []Account      `json:"accounts" yaml:"accounts"`
+	Offers        map[string]int `json:"offers" yaml:"offers"`                                     // 价格表，键是皮肤等级ID，值是VP价格
+	TokenLifetime int            `json:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"` // 访问令牌的有效期（秒），默认3600
+	Faults        []Fault        `json:"faults,omitempty" yaml:"faults,omitempty"`                 // 启动时注入的错误
+}
+
+// Account 模拟的Riot账号
+type Account struct {
+	Username string            `json:"username" yaml:"username"`
+	Password string            `json:"password" yaml:"password"`
+	PUUID    string            `json:"puuid" yaml:"puuid"`
+	Email    string            `json:"email,omitempty" yaml:"email,omitempty"`
+	GameName string            `json:"game_name,omitempty" yaml:"game_name,omitempty"`
+	TagLine  string            `json:"tag_line,omitempty" yaml:"tag_line,omitempty"`
+	Region   string            `json:"region" yaml:"region"`                         // na、eu、ap、kr、latam、br
+	MFACode  string            `json:"mfa_code,omitempty" yaml:"mfa_code,omitempty"` // 设置后登录需要提交该验证码
+	Cookies  map[string]string `json:"cookies,omitempty" yaml:"cookies,omitempty"`   // 可用于Cookie登录的Cookie，ssid为空时使用ssid-<puuid>
+	Wallet   Wallet            `json:"wallet" yaml:"wallet"`
+	Owned    []string          `json:"owned,omitempty" yaml:"owned,omitempty"` // 已拥有的皮肤等级ID
+	Shop     Shop              `json:"shop" yaml:"shop"`
+}
+
+// Wallet 账号的货币余额
+type Wallet struct {
+	VP int `json:"vp" yaml:"vp"`
+	RP int `json:"rp" yaml:"rp"`
+	KC int `json:"kc" yaml:"kc"`
+}
+
+// Shop 账号的商店内容，剩余时间为0时每日商店和夜市到下一个UTC零点刷新
+type Shop struct {
+	Daily                []string           `json:"daily" yaml:"daily"` // 皮肤等级ID，价格来自Fixture.Offers
+	DailyRemaining       int64              `json:"daily_remaining,omitempty" yaml:"daily_remaining,omitempty"`
+	Bundles              []Bundle           `json:"bundles,omitempty" yaml:"bundles,omitempty"`
+	NightMarket          []NightMarketOffer `json:"night_market,omitempty" yaml:"night_market,omitempty"`
+	NightMarketRemaining int64              `json:"night_market_remaining,omitempty" yaml:"night_market_remaining,omitempty"`
+}
+
+// Bundle 精选套装
+type Bundle struct {
+	ID            string       `json:"id" yaml:"id"`
+	DataAssetID   string       `json:"data_asset_id" yaml:"data_asset_id"`
+	Items         []BundleItem `json:"items" yaml:"items"`
+	WholesaleOnly bool         `json:"wholesale_only,omitempty" yaml:"wholesale_only,omitempty"`
+	Remaining     int64        `json:"remaining" yaml:"remaining"` // 剩余时间（秒）
+}
+
+// BundleItem 套装中的物品，类型为空时是皮肤等级
+type BundleItem struct {
+	ItemTypeID      string `json:"item_type_id,omitempty" yaml:"item_type_id,omitempty"`
+	ItemID          string `json:"item_id" yaml:"item_id"`
+	Amount          int    `json:"amount,omitempty" yaml:"amount,omitempty"`
+	BasePrice       int    `json:"base_price" yaml:"base_price"`
+	DiscountedPrice int    `json:"discounted_price" yaml:"discounted_price"`
+}
+
+// NightMarketOffer 夜市物品
+type NightMarketOffer struct {
+	ItemID          string `json:"item_id" yaml:"item_id"`
+	BasePrice       int    `json:"base_price" yaml:"base_price"`
+	DiscountPercent int    `json:"discount_percent" yaml:"discount_percent"`
+	Seen            bool   `json:"seen,omitempty" yaml:"seen,omitempty"`
+}
+
+// LoadFixture 从JSON或YAML文件加载数据，按扩展名选择格式
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取数据文件失败: %w", err)
+	}
+
+	var fixture Fixture
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &fixture)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fixture)
+	default:
+		return nil, fmt.Errorf("不支持的数据文件格式: %s（支持.json/.yaml/.yml）", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析数据文件失败: %w", err)
+	}
+
+	if err := fixture.Validate(); err != nil {
+		return nil, err
+	}
+	return &fixture, nil
+}
+
+// Validate 检查账号数据是否完整
+func (f *Fixture) Validate() error {
+	var problems []string
+	usernames := make(map[string]bool)
+	puuids := make(map[string]bool)
+
+	for i, account := range f.Accounts {
+		name := fmt.Sprintf("accounts[%d]", i)
+		if account.Username == "" || account.Password == "" || account.PUUID == "" {
+			problems = append(problems, name+"缺少username、password或puuid")
+		}
+		if usernames[strings.ToLower(account.Username)] {
+			problems = append(problems, fmt.Sprintf("%s的用户名%q重复", name, account.Username))
+		}
+		if puuids[account.PUUID] {
+			problems = append(problems, fmt.Sprintf("%s的puuid%q重复", name, account.PUUID))
+		}
+		usernames[strings.ToLower(account.Username)] = true
+		puuids[account.PUUID] = true
+
+		switch strings.ToLower(account.Region) {
+		case "na", "eu", "ap", "kr", "latam", "br":
+		default:
+			problems = append(problems, fmt.Sprintf("%s的区域%q无效", name, account.Region))
+		}
+	}
+
+	for i, fault := range f.Faults {
+		if err := fault.validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("faults[%d]: %v", i, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("数据文件无效:\n  - " + strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// ssid 账号用于Cookie登录的ssid
+func (a *Account) ssid() string {
+	if ssid := a.Cookies["ssid"]; ssid != "" {
+		return ssid
+	}
+	return "ssid-" + a.PUUID
+}
+
+// shard 账号所在的PD分片
+func (a *Account) shard() string {
+	return repositories.NormalizeRegion(a.Region)
+}
@@ -0,0 +1,673 @@
+package mockriot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+const (
+	// defaultTokenLifetime 访问令牌默认的有效期（秒）
+	defaultTokenLifetime = 3600
+
+	// mockClientVersion 版本接口返回的客户端版本
+	mockClientVersion = "release-mock"
+
+	// loginRedirectURL Cookie无效时重新授权跳转的登录页
+	loginRedirectURL = "https://authenticate.riotgames.com/login"
+
+	// tokenRedirectURL 登录成功后携带访问令牌的地址
+	tokenRedirectURL = "https://playvalorant.com/opt_in"
+)
+
+// Server 模拟Riot的认证、授权、商店、钱包和内容接口
+// 所有服务由同一个地址提供，分片服务通过路径区分，使用Endpoints生成后端的接口地址
+type Server struct {
+	mux           *http.ServeMux
+	accounts      []*Account
+	offers        map[string]int
+	tokenLifetime int
+
+	mutex        sync.Mutex
+	logins       map[string]*Account                      // asid -> 等待二次验证的账号
+	tokens       map[string]*Account                      // 访问令牌 -> 账号
+	entitlements map[string]*Account                      // 授权令牌 -> 账号
+	loadouts     map[string]*models.ValorantPlayerLoadout // PUUID -> 修改过的装备
+	faults       []Fault
+}
+
+// New 创建模拟服务，fixture为nil时使用空数据
+func New(fixture *Fixture) (*Server, error) {
+	if fixture == nil {
+		fixture = &Fixture{}
+	}
+	if err := fixture.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		mux:           http.NewServeMux(),
+		offers:        fixture.Offers,
+		tokenLifetime: fixture.TokenLifetime,
+		logins:        make(map[string]*Account),
+		tokens:        make(map[string]*Account),
+		entitlements:  make(map[string]*Account),
+		loadouts:      make(map[string]*models.ValorantPlayerLoadout),
+		faults:        append([]Fault(nil), fixture.Faults...),
+	}
+	if s.tokenLifetime <= 0 {
+		s.tokenLifetime = defaultTokenLifetime
+	}
+	for i := range fixture.Accounts {
+		account := fixture.Accounts[i]
+		s.accounts = append(s.accounts, &account)
+	}
+
+	s.registerRoutes()
+	return s, nil
+}
+
+// Endpoints 返回指向模拟服务的Riot接口地址，baseURL是模拟服务的地址（如httptest.Server.URL）
+func Endpoints(baseURL string) *repositories.RiotEndpoints {
+	base := strings.TrimRight(baseURL, "/")
+	return &repositories.RiotEndpoints{
+		Auth:         base + "/auth",
+		Entitlements: base + "/entitlements",
+		PD:           base + "/pd/" + repositories.RiotShardPlaceholder,
+		Shared:       base + "/shared/" + repositories.RiotShardPlaceholder,
+		Catalog:      base,
+	}
+}
+
+// ServeHTTP 实现http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// registerRoutes 注册所有模拟接口
+func (s *Server) registerRoutes() {
+	s.mux.HandleFunc("POST /auth/api/v1/authorization", s.withFaults(EndpointAuthorization, s.handleAuthCookies))
+	s.mux.HandleFunc("PUT /auth/api/v1/authorization", s.withFaults(EndpointAuthorization, s.handleLogin))
+	s.mux.HandleFunc("GET /auth/authorize", s.withFaults(EndpointAuthorize, s.handleAuthorize))
+	s.mux.HandleFunc("GET /auth/userinfo", s.withFaults(EndpointUserInfo, s.handleUserInfo))
+	s.mux.HandleFunc("POST /entitlements/api/token/v1", s.withFaults(EndpointEntitlements, s.handleEntitlementsToken))
+
+	s.mux.HandleFunc("PUT /pd/{shard}/name-service/v2/players", s.withFaults(EndpointNameService, s.handleNameService))
+	s.mux.HandleFunc("POST /pd/{shard}/store/v3/storefront/{puuid}", s.withFaults(EndpointStorefront, s.handleStorefront))
+	s.mux.HandleFunc("GET /pd/{shard}/store/v1/wallet/{puuid}", s.withFaults(EndpointWallet, s.handleWallet))
+	s.mux.HandleFunc("GET /pd/{shard}/store/v1/offers/", s.withFaults(EndpointOffers, s.handleOffers))
+	s.mux.HandleFunc("GET /pd/{shard}/store/v1/entitlements/{puuid}/{itemType}", s.withFaults(EndpointOwnedItems, s.handleOwnedItems))
+	s.mux.HandleFunc("GET /pd/{shard}/personalization/v2/players/{puuid}/playerloadout", s.withFaults(EndpointLoadout, s.handleGetLoadout))
+	s.mux.HandleFunc("PUT /pd/{shard}/personalization/v2/players/{puuid}/playerloadout", s.withFaults(EndpointLoadout, s.handleSetLoadout))
+	s.mux.HandleFunc("GET /shared/{shard}/content-service/v3/content", s.withFaults(EndpointContent, s.handleContent))
+	s.mux.HandleFunc("GET /v1/version", s.withFaults(EndpointVersion, s.handleVersion))
+
+	// 控制接口，用于在运行时调整注入的错误
+	s.mux.HandleFunc("GET /_mock/faults", s.handleGetFaults)
+	s.mux.HandleFunc("PUT /_mock/faults", s.handleSetFaults)
+	s.mux.HandleFunc("DELETE /_mock/faults", s.handleClearFaults)
+}
+
+// handleAuthCookies 登录第一步，下发关联登录过程的asid
+func (s *Server) handleAuthCookies(w http.ResponseWriter, r *http.Request) {
+	setCookie(w, "asid", randomToken())
+	writeJSON(w, http.StatusOK, map[string]string{"type": "auth"})
+}
+
+// handleLogin 处理用户名密码登录和二次验证码
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Type     string `json:"type"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeRiotError(w, http.StatusBadRequest, "INVALID_REQUEST", "无效的请求体")
+		return
+	}
+
+	asid := ""
+	if cookie, err := r.Cookie("asid"); err == nil {
+		asid = cookie.Value
+	}
+
+	switch body.Type {
+	case "auth":
+		account := s.findAccount(body.Username)
+		if account == nil || account.Password != body.Password {
+			writeJSON(w, http.StatusOK, map[string]string{"type": "auth", "error": "auth_failure"})
+			return
+		}
+
+		if account.MFACode != "" {
+			if asid == "" {
+				writeRiotError(w, http.StatusBadRequest, "INVALID_SESSION", "缺少asid")
+				return
+			}
+			s.mutex.Lock()
+			s.logins[asid] = account
+			s.mutex.Unlock()
+			writeJSON(w, http.StatusOK, multifactorResponse(account, ""))
+			return
+		}
+		s.writeLoginSuccess(w, account)
+
+	case "multifactor":
+		s.mutex.Lock()
+		account := s.logins[asid]
+		if account != nil && body.Code == account.MFACode {
+			delete(s.logins, asid)
+		}
+		s.mutex.Unlock()
+
+		if account == nil {
+			writeJSON(w, http.StatusOK, map[string]string{"type": "auth", "error": "auth_failure"})
+			return
+		}
+		if body.Code != account.MFACode {
+			writeJSON(w, http.StatusOK, multifactorResponse(account, "multifactor_attempt_failed"))
+			return
+		}
+		s.writeLoginSuccess(w, account)
+
+	default:
+		writeRiotError(w, http.StatusBadRequest, "INVALID_REQUEST", "未知的登录类型: "+body.Type)
+	}
+}
+
+// writeLoginSuccess 返回登录成功的响应，并下发用于Cookie重新授权的ssid
+func (s *Server) writeLoginSuccess(w http.ResponseWriter, account *Account) {
+	setCookie(w, "ssid", account.ssid())
+	setCookie(w, "sub", account.PUUID)
+
+	var resp models.ValorantAuthResponse
+	resp.Type = "response"
+	resp.Response.Parameters.URI = s.issueTokenURI(account)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAuthorize 使用Cookie中的ssid重新授权，成功时跳转到携带访问令牌的地址
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	var account *Account
+	if cookie, err := r.Cookie("ssid"); err == nil {
+		for _, candidate := range s.accounts {
+			if candidate.ssid() == cookie.Value {
+				account = candidate
+				break
+			}
+		}
+	}
+
+	if account == nil {
+		w.Header().Set("Location", loginRedirectURL)
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
+
+	setCookie(w, "ssid", account.ssid())
+	w.Header().Set("Location", s.issueTokenURI(account))
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// handleUserInfo 返回访问令牌对应的用户信息
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	account := s.accountForToken(r)
+	if account == nil {
+		writeRiotError(w, http.StatusUnauthorized, "BAD_CLAIMS", "无效的访问令牌")
+		return
+	}
+
+	var resp models.ValorantUserInfoResponse
+	resp.Sub = account.PUUID
+	resp.Email = account.Email
+	resp.Name = account.GameName
+	resp.Tag = account.TagLine
+	resp.Verified = account.Email != ""
+	resp.Acct.GameName = account.GameName
+	resp.Acct.TagLine = account.TagLine
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleEntitlementsToken 使用访问令牌换取授权令牌
+func (s *Server) handleEntitlementsToken(w http.ResponseWriter, r *http.Request) {
+	account := s.accountForToken(r)
+	if account == nil {
+		writeRiotError(w, http.StatusUnauthorized, "BAD_CLAIMS", "无效的访问令牌")
+		return
+	}
+
+	token := "ent-" + randomToken()
+	s.mutex.Lock()
+	s.entitlements[token] = account
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, models.ValorantEntitlementResponse{EntitlementToken: token})
+}
+
+// handleNameService 只有在玩家所在的分片上返回200，后端据此检测区域
+func (s *Server) handleNameService(w http.ResponseWriter, r *http.Request) {
+	account := s.authorize(w, r)
+	if account == nil {
+		return
+	}
+	if account.shard() != r.PathValue("shard") {
+		writeRiotError(w, http.StatusNotFound, "RESOURCE_NOT_FOUND", "玩家不在该分片")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, []map[string]string{{
+		"Subject":  account.PUUID,
+		"GameName": account.GameName,
+		"TagLine":  account.TagLine,
+	}})
+}
+
+// handleStorefront 返回玩家的每日商店、精选套装和夜市
+func (s *Server) handleStorefront(w http.ResponseWriter, r *http.Request) {
+	account := s.authorizePlayer(w, r)
+	if account == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.buildStorefront(account, time.Now()))
+}
+
+// handleWallet 返回玩家的货币余额
+func (s *Server) handleWallet(w http.ResponseWriter, r *http.Request) {
+	account := s.authorizePlayer(w, r)
+	if account == nil {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.ValorantWalletResponse{
+		Balances: map[string]int{
+			models.CurrencyValorantPoints:  account.Wallet.VP,
+			models.CurrencyRadianitePoints: account.Wallet.RP,
+			models.CurrencyKingdomCredits:  account.Wallet.KC,
+		},
+	})
+}
+
+// handleOffers 返回价格表，包含Fixture.Offers和夜市物品的原价
+func (s *Server) handleOffers(w http.ResponseWriter, r *http.Request) {
+	if s.authorize(w, r) == nil {
+		return
+	}
+
+	prices := make(map[string]int, len(s.offers))
+	for _, account := range s.accounts {
+		for _, offer := range account.Shop.NightMarket {
+			prices[offer.ItemID] = offer.BasePrice
+		}
+	}
+	for itemID, price := range s.offers {
+		prices[itemID] = price
+	}
+
+	resp := models.ValorantOffersResponse{
+		Offers:                make([]models.Offer, 0, len(prices)),
+		UpgradeCurrencyOffers: []models.UpgradeCurrencyOffer{},
+	}
+	for itemID, price := range prices {
+		resp.Offers = append(resp.Offers, skinOffer(itemID, price))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleOwnedItems 返回玩家拥有的皮肤等级，其他物品类型返回空列表
+func (s *Server) handleOwnedItems(w http.ResponseWriter, r *http.Request) {
+	account := s.authorizePlayer(w, r)
+	if account == nil {
+		return
+	}
+
+	itemType := r.PathValue("itemType")
+	resp := models.ValorantEntitlementsResponse{
+		ItemTypeID:   itemType,
+		Entitlements: []models.Entitlement{},
+	}
+	if itemType == models.ItemTypeSkinLevel {
+		for _, itemID := range account.Owned {
+			resp.Entitlements = append(resp.Entitlements, models.Entitlement{TypeID: itemType, ItemID: itemID})
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleGetLoadout 返回玩家的装备，未修改过时返回空装备
+func (s *Server) handleGetLoadout(w http.ResponseWriter, r *http.Request) {
+	account := s.authorizePlayer(w, r)
+	if account == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.loadout(account))
+}
+
+// handleSetLoadout 保存玩家提交的装备，版本号加1后返回
+func (s *Server) handleSetLoadout(w http.ResponseWriter, r *http.Request) {
+	account := s.authorizePlayer(w, r)
+	if account == nil {
+		return
+	}
+
+	var loadout models.ValorantPlayerLoadout
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&loadout); err != nil {
+		writeRiotError(w, http.StatusBadRequest, "INVALID_REQUEST", "无效的装备: "+err.Error())
+		return
+	}
+	loadout.Subject = account.PUUID
+	loadout.Version = s.loadout(account).Version + 1
+
+	s.mutex.Lock()
+	s.loadouts[account.PUUID] = &loadout
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, loadout)
+}
+
+// loadout 返回账号当前装备的副本
+func (s *Server) loadout(account *Account) models.ValorantPlayerLoadout {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if loadout, exists := s.loadouts[account.PUUID]; exists {
+		return *loadout
+	}
+	return models.ValorantPlayerLoadout{
+		Subject: account.PUUID,
+		Version: 1,
+		Guns:    []models.LoadoutGun{},
+		Sprays:  []models.LoadoutSpray{},
+	}
+}
+
+// handleContent 返回空的内容服务数据
+func (s *Server) handleContent(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"DisabledIDs": []string{},
+		"Seasons":     []interface{}{},
+		"Events":      []interface{}{},
+	})
+}
+
+// handleVersion 返回valorant-api.com格式的客户端版本
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": http.StatusOK,
+		"data": map[string]string{
+			"riotClientVersion": mockClientVersion,
+		},
+	})
+}
+
+// handleGetFaults 返回当前注入的错误
+func (s *Server) handleGetFaults(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Faults())
+}
+
+// handleSetFaults 替换注入的错误，请求体是Fault数组
+func (s *Server) handleSetFaults(w http.ResponseWriter, r *http.Request) {
+	var faults []Fault
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&faults); err != nil {
+		writeRiotError(w, http.StatusBadRequest, "INVALID_REQUEST", "无效的错误配置: "+err.Error())
+		return
+	}
+	if err := s.SetFaults(faults); err != nil {
+		writeRiotError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Faults())
+}
+
+// handleClearFaults 清除注入的错误
+func (s *Server) handleClearFaults(w http.ResponseWriter, r *http.Request) {
+	s.ClearFaults()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildStorefront 根据账号数据构建商店响应
+func (s *Server) buildStorefront(account *Account, now time.Time) models.ValorantStoreResponse {
+	shop := account.Shop
+	untilMidnight := secondsUntilMidnight(now)
+
+	var resp models.ValorantStoreResponse
+	resp.SkinsPanelLayout.SingleItemOffers = append([]string{}, shop.Daily...)
+	resp.SkinsPanelLayout.SingleItemOffersRemainingDurationInSeconds = orDefault(shop.DailyRemaining, untilMidnight)
+
+	resp.FeaturedBundle.Bundles = make([]models.Bundle, 0, len(shop.Bundles))
+	for _, bundle := range shop.Bundles {
+		resp.FeaturedBundle.Bundles = append(resp.FeaturedBundle.Bundles, buildBundle(bundle))
+	}
+	if len(resp.FeaturedBundle.Bundles) > 0 {
+		resp.FeaturedBundle.Bundle = resp.FeaturedBundle.Bundles[0]
+		resp.FeaturedBundle.BundleRemainingDurationInSeconds = resp.FeaturedBundle.Bundle.DurationRemainingInSeconds
+	}
+
+	resp.BonusStore.BonusStoreOffers = []models.BonusStoreOffer{}
+	for i, offer := range shop.NightMarket {
+		resp.BonusStore.BonusStoreOffers = append(resp.BonusStore.BonusStoreOffers, models.BonusStoreOffer{
+			BonusOfferID:    fmt.Sprintf("%s-bonus-%d", account.PUUID, i),
+			Offer:           skinOffer(offer.ItemID, offer.BasePrice),
+			DiscountPercent: offer.DiscountPercent,
+			DiscountCosts: map[string]int{
+				models.CurrencyValorantPoints: offer.BasePrice * (100 - offer.DiscountPercent) / 100,
+			},
+			IsSeen: offer.Seen,
+		})
+	}
+	if len(shop.NightMarket) > 0 {
+		resp.BonusStore.BonusStoreRemainingDurationInSeconds = orDefault(shop.NightMarketRemaining, untilMidnight)
+	}
+
+	resp.AccessoryStore.AccessoryStoreOffers = []models.AccessoryStoreOffer{}
+	resp.UpgradeCurrencyStore.UpgradeCurrencyOffers = []models.UpgradeCurrencyOffer{}
+	return resp
+}
+
+// buildBundle 构建精选套装，物品类型默认是皮肤等级
+func buildBundle(bundle Bundle) models.Bundle {
+	result := models.Bundle{
+		ID:                         bundle.ID,
+		DataAssetID:                bundle.DataAssetID,
+		CurrencyID:                 models.CurrencyValorantPoints,
+		Items:                      make([]models.BundleItem, 0, len(bundle.Items)),
+		DurationRemainingInSeconds: bundle.Remaining,
+		WholesaleOnly:              bundle.WholesaleOnly,
+	}
+
+	for _, item := range bundle.Items {
+		itemType := item.ItemTypeID
+		if itemType == "" {
+			itemType = models.ItemTypeSkinLevel
+		}
+		amount := item.Amount
+		if amount <= 0 {
+			amount = 1
+		}
+
+		discountPercent := 0
+		if item.BasePrice > 0 && item.DiscountedPrice < item.BasePrice {
+			discountPercent = (item.BasePrice - item.DiscountedPrice) * 100 / item.BasePrice
+		}
+
+		result.Items = append(result.Items, models.BundleItem{
+			Item: models.ItemInfo{
+				ItemTypeID: itemType,
+				ItemID:     item.ItemID,
+				Amount:     amount,
+			},
+			BasePrice:       item.BasePrice,
+			DiscountPercent: discountPercent,
+			DiscountedPrice: item.DiscountedPrice,
+			Quantity:        amount,
+		})
+	}
+	return result
+}
+
+// skinOffer 构建使用VP购买的皮肤等级价格
+func skinOffer(itemID string, price int) models.Offer {
+	return models.Offer{
+		OfferID:          itemID,
+		IsDirectPurchase: true,
+		StartDate:        "2020-06-02T00:00:00Z",
+		Cost:             map[string]int{models.CurrencyValorantPoints: price},
+		Rewards: []models.OfferReward{{
+			ItemTypeID: models.ItemTypeSkinLevel,
+			ItemID:     itemID,
+			Quantity:   1,
+		}},
+	}
+}
+
+// issueTokenURI 签发访问令牌，返回Riot登录成功时携带令牌的地址
+func (s *Server) issueTokenURI(account *Account) string {
+	token := "mock-" + randomToken()
+
+	s.mutex.Lock()
+	s.tokens[token] = account
+	s.mutex.Unlock()
+
+	fragment := url.Values{}
+	fragment.Set("access_token", token)
+	fragment.Set("token_type", "Bearer")
+	fragment.Set("expires_in", fmt.Sprint(s.tokenLifetime))
+	return tokenRedirectURL + "#" + fragment.Encode()
+}
+
+// accountForToken 返回Authorization头中访问令牌对应的账号
+func (s *Server) accountForToken(r *http.Request) *Account {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.tokens[token]
+}
+
+// authorize 检查分片接口的访问令牌和授权令牌，失败时写入401并返回nil
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) *Account {
+	account := s.accountForToken(r)
+
+	s.mutex.Lock()
+	entitled := s.entitlements[r.Header.Get("X-Riot-Entitlements-JWT")]
+	s.mutex.Unlock()
+
+	if account == nil || entitled != account {
+		writeRiotError(w, http.StatusUnauthorized, "BAD_CLAIMS", "无效的访问令牌或授权令牌")
+		return nil
+	}
+	return account
+}
+
+// authorizePlayer 在authorize的基础上检查地址中的玩家ID和分片
+func (s *Server) authorizePlayer(w http.ResponseWriter, r *http.Request) *Account {
+	account := s.authorize(w, r)
+	if account == nil {
+		return nil
+	}
+
+	if r.PathValue("puuid") != account.PUUID {
+		writeRiotError(w, http.StatusForbidden, "FORBIDDEN", "无权访问其他玩家的数据")
+		return nil
+	}
+	if r.PathValue("shard") != account.shard() {
+		writeRiotError(w, http.StatusNotFound, "RESOURCE_NOT_FOUND", "玩家不在该分片")
+		return nil
+	}
+	return account
+}
+
+// findAccount 按用户名查找账号，不区分大小写
+func (s *Server) findAccount(username string) *Account {
+	for _, account := range s.accounts {
+		if strings.EqualFold(account.Username, username) {
+			return account
+		}
+	}
+	return nil
+}
+
+// multifactorResponse 构建需要二次验证的响应
+func multifactorResponse(account *Account, errorCode string) models.ValorantAuthResponse {
+	resp := models.ValorantAuthResponse{
+		Type:  "multifactor",
+		Error: errorCode,
+		Multifactor: models.ValorantMultifactorInfo{
+			Email:                 maskEmail(account.Email),
+			Method:                "email",
+			Methods:               []string{"email"},
+			MultiFactorCodeLength: len(account.MFACode),
+			MfaVersion:            "v2",
+		},
+	}
+	return resp
+}
+
+// maskEmail 隐藏邮箱的用户名部分，如 j***@gmail.com
+func maskEmail(email string) string {
+	name, domain, found := strings.Cut(email, "@")
+	if !found || name == "" {
+		return email
+	}
+	return name[:1] + "***@" + domain
+}
+
+// secondsUntilMidnight 距离下一个UTC零点的秒数
+func secondsUntilMidnight(now time.Time) int64 {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return int64(midnight.Sub(now).Seconds())
+}
+
+// orDefault value为0时返回fallback
+func orDefault(value, fallback int64) int64 {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// randomToken 生成随机令牌
+func randomToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成随机令牌失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// setCookie 下发整个域名可用的Cookie
+func setCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Value: value, Path: "/", HttpOnly: true})
+}
+
+// writeRiotError 返回Riot格式的错误
+func writeRiotError(w http.ResponseWriter, status int, errorCode, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"httpStatus": status,
+		"errorCode":  errorCode,
+		"message":    message,
+	})
+}
+
+// writeJSON 写入JSON响应
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		fmt.Printf("写入响应失败: %v\n", err)
+	}
+}
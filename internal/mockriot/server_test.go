@@ -0,0 +1,216 @@
+package mockriot_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emper0r/val-store/server/internal/mockriot"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// testFixture 一个普通账号demo/demo
+func testFixture() *mockriot.Fixture {
+	return &mockriot.Fixture{
+		Offers: map[string]int{"skin-1": 1775},
+		Accounts: []mockriot.Account{{
+			Username: "demo",
+			Password: "demo",
+			PUUID:    "puuid-demo",
+			GameName: "Demo",
+			TagLine:  "MOCK",
+			Region:   "eu",
+			Wallet:   mockriot.Wallet{VP: 5000},
+			Shop:     mockriot.Shop{Daily: []string{"skin-1"}},
+		}},
+	}
+}
+
+// startMock 启动模拟服务，返回模拟服务、服务地址和指向它的ValorantAPI
+func startMock(t *testing.T) (*mockriot.Server, string, *repositories.ValorantAPI) {
+	t.Helper()
+
+	mock, err := mockriot.New(testFixture())
+	if err != nil {
+		t.Fatalf("创建模拟服务失败: %v", err)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+
+	api := repositories.NewValorantAPIWithTransport(server.Client().Transport, "release-mock", mockriot.Endpoints(server.URL))
+	api.SetRateLimit(0, 1)
+	return mock, server.URL, api
+}
+
+// login 使用demo账号登录，返回会话
+func login(t *testing.T, api *repositories.ValorantAPI) *models.UserSession {
+	t.Helper()
+
+	session, pending, err := api.Authenticate(t.Context(), "demo", "demo")
+	if err != nil || pending != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	return session
+}
+
+func TestLoginAndShop(t *testing.T) {
+	_, _, api := startMock(t)
+
+	session := login(t, api)
+	if session.UserID != "puuid-demo" || repositories.NormalizeRegion(session.Region) != "eu" {
+		t.Fatalf("登录后的会话不正确: %+v", session)
+	}
+
+	store, err := api.GetStoreOffers(t.Context(), repositories.SessionAuth(session), session.UserID)
+	if err != nil {
+		t.Fatalf("获取商店失败: %v", err)
+	}
+	if offers := store.SkinsPanelLayout.SingleItemOffers; len(offers) != 1 || offers[0] != "skin-1" {
+		t.Fatalf("每日商店不正确: %v", offers)
+	}
+
+	wallet, err := api.GetWallet(t.Context(), repositories.SessionAuth(session), session.UserID)
+	if err != nil {
+		t.Fatalf("获取钱包失败: %v", err)
+	}
+	if wallet.Balances[models.CurrencyValorantPoints] != 5000 {
+		t.Fatalf("钱包余额不正确: %v", wallet.Balances)
+	}
+}
+
+func TestLoadout(t *testing.T) {
+	_, _, api := startMock(t)
+	session := login(t, api)
+	auth := repositories.SessionAuth(session)
+
+	loadout, err := api.GetPlayerLoadout(t.Context(), auth, session.UserID)
+	if err != nil {
+		t.Fatalf("获取装备失败: %v", err)
+	}
+	if loadout.Subject != session.UserID || loadout.Version != 1 {
+		t.Fatalf("初始装备不正确: %+v", loadout)
+	}
+
+	loadout.Guns = append(loadout.Guns, models.LoadoutGun{ID: "gun", SkinID: "skin", SkinLevelID: "skin-1", ChromaID: "chroma"})
+	updated, err := api.SetPlayerLoadout(t.Context(), auth, session.UserID, loadout)
+	if err != nil {
+		t.Fatalf("修改装备失败: %v", err)
+	}
+	if updated.Version != 2 || len(updated.Guns) != 1 {
+		t.Fatalf("修改后的装备不正确: %+v", updated)
+	}
+
+	loadout, err = api.GetPlayerLoadout(t.Context(), auth, session.UserID)
+	if err != nil {
+		t.Fatalf("获取装备失败: %v", err)
+	}
+	if loadout.Version != 2 || len(loadout.Guns) != 1 || loadout.Guns[0].SkinLevelID != "skin-1" {
+		t.Fatalf("修改没有保存: %+v", loadout)
+	}
+}
+
+func TestFaultCount(t *testing.T) {
+	mock, baseURL, _ := startMock(t)
+
+	if err := mock.AddFault(mockriot.Fault{Endpoint: mockriot.EndpointVersion, Status: http.StatusServiceUnavailable, RetryAfter: 7, Count: 2}); err != nil {
+		t.Fatalf("注入错误失败: %v", err)
+	}
+
+	// 每次生效后剩余次数减1，用完后移除
+	for remaining := 1; remaining >= 0; remaining-- {
+		resp, err := http.Get(baseURL + "/v1/version")
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "7" {
+			t.Fatalf("应当返回注入的503和Retry-After，得到 %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+		}
+
+		faults := mock.Faults()
+		if remaining > 0 && (len(faults) != 1 || faults[0].Count != remaining) {
+			t.Fatalf("剩余次数应当为%d，得到 %+v", remaining, faults)
+		}
+		if remaining == 0 && len(faults) != 0 {
+			t.Fatalf("用完的错误应当被移除，得到 %+v", faults)
+		}
+	}
+
+	resp, err := http.Get(baseURL + "/v1/version")
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("错误用完后应当正常响应，得到 %d", resp.StatusCode)
+	}
+}
+
+func TestFaultReachesValorantAPI(t *testing.T) {
+	mock, _, api := startMock(t)
+	session := login(t, api)
+	auth := repositories.SessionAuth(session)
+
+	if err := mock.SetFaults([]mockriot.Fault{{Endpoint: mockriot.EndpointLoadout, Status: http.StatusUnauthorized, Count: 1}}); err != nil {
+		t.Fatalf("注入错误失败: %v", err)
+	}
+
+	// 401按Riot令牌过期处理
+	if _, err := api.GetPlayerLoadout(t.Context(), auth, session.UserID); !errors.Is(err, repositories.ErrRiotTokenExpired) {
+		t.Fatalf("应当返回ErrRiotTokenExpired，得到 %v", err)
+	}
+	if _, err := api.GetPlayerLoadout(t.Context(), auth, session.UserID); err != nil {
+		t.Fatalf("错误用完后应当正常响应: %v", err)
+	}
+}
+
+func TestFaultsControlEndpoint(t *testing.T) {
+	mock, baseURL, _ := startMock(t)
+
+	request := func(method, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, baseURL+"/_mock/faults", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("创建请求失败: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := request(http.MethodPut, `[{"endpoint":"loadout","status":429,"retry_after":5,"count":2}]`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("设置错误应当返回200，得到 %d", resp.StatusCode)
+	}
+
+	resp = request(http.MethodGet, "")
+	var faults []mockriot.Fault
+	if err := json.NewDecoder(resp.Body).Decode(&faults); err != nil {
+		t.Fatalf("解析错误列表失败: %v", err)
+	}
+	want := mockriot.Fault{Endpoint: mockriot.EndpointLoadout, Status: http.StatusTooManyRequests, RetryAfter: 5, Count: 2}
+	if len(faults) != 1 || faults[0] != want {
+		t.Fatalf("错误列表不正确: %+v", faults)
+	}
+
+	if resp := request(http.MethodPut, `[{"endpoint":"unknown","status":503}]`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("未知的接口名称应当返回400，得到 %d", resp.StatusCode)
+	}
+	if len(mock.Faults()) != 1 {
+		t.Fatal("无效的配置不应替换已有的错误")
+	}
+
+	if resp := request(http.MethodDelete, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("清除错误应当返回204，得到 %d", resp.StatusCode)
+	}
+	if len(mock.Faults()) != 0 {
+		t.Fatal("清除后不应再有注入的错误")
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+// Discord交互类型
+const (
+	DiscordInteractionPing    = 1 // Discord验证交互地址时发送
+	DiscordInteractionCommand = 2 // 斜杠命令
+)
+
+// Discord交互响应类型
+const (
+	DiscordResponsePong            = 1 // 回复Ping
+	DiscordResponseChannelMessage  = 4 // 立即回复消息
+	DiscordResponseDeferredMessage = 5 // 稍后通过后续消息回复
+)
+
+const (
+	// DiscordMessageFlagEphemeral 消息只有命令使用者可见
+	DiscordMessageFlagEphemeral = 64
+	// DiscordMaxEmbeds 每条消息最多的嵌入数量
+	DiscordMaxEmbeds = 10
+)
+
+// DiscordInteraction Discord发送的交互请求
+type DiscordInteraction struct {
+	ID            string                 `json:"id"`
+	ApplicationID string                 `json:"application_id"`
+	Type          int                    `json:"type"`
+	Token         string                 `json:"token"` // 用于发送后续消息
+	Data          DiscordInteractionData `json:"data"`
+	Member        *DiscordMember         `json:"member,omitempty"` // 在服务器中使用时存在
+	User          *DiscordUser           `json:"user,omitempty"`   // 在私信中使用时存在
+}
+
+// DiscordInteractionData 斜杠命令的内容
+type DiscordInteractionData struct {
+	Name string `json:"name"`
+}
+
+// DiscordMember 服务器成员
+type DiscordMember struct {
+	User DiscordUser `json:"user"`
+}
+
+// DiscordUser Discord用户
+type DiscordUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// DiscordInteractionResponse 回复Discord交互的内容
+type DiscordInteractionResponse struct {
+	Type int             `json:"type"`
+	Data *DiscordMessage `json:"data,omitempty"`
+}
+
+// DiscordMessage Discord消息
+type DiscordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []DiscordEmbed `json:"embeds,omitempty"`
+	Flags   int            `json:"flags,omitempty"`
+}
+
+// DiscordEmbed Discord嵌入消息
+type DiscordEmbed struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color,omitempty"`
+	Thumbnail   *DiscordEmbedImage  `json:"thumbnail,omitempty"`
+	Image       *DiscordEmbedImage  `json:"image,omitempty"`
+	Fields      []DiscordEmbedField `json:"fields,omitempty"`
+	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"` // ISO8601
+}
+
+// DiscordEmbedImage 嵌入消息中的图片
+type DiscordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+// DiscordEmbedField 嵌入消息中的字段
+type DiscordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// DiscordEmbedFooter 嵌入消息的页脚
+type DiscordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// DiscordLinkRequest 绑定Discord账号的请求
+type DiscordLinkRequest struct {
+	DiscordUserID string `json:"discord_user_id" binding:"required"`
+}
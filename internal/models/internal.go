@@ -17,6 +17,23 @@ type CookieLoginRequest struct {
 	Region     string `json:"region"` // 可选的区域设置参数
 }
 
+// MFACodeRequest 提交二次验证码的请求
+type MFACodeRequest struct {
+	ChallengeID    string `json:"challenge_id" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	RememberDevice bool   `json:"remember_device"`
+}
+
+// MFAChallengeResponse 登录需要二次验证时返回给客户端的挑战信息
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	ChallengeID string `json:"challenge_id"`
+	Email       string `json:"email"`       // 部分隐藏的邮箱提示
+	Method      string `json:"method"`      // 验证方式，如email
+	CodeLength  int    `json:"code_length"` // 验证码长度
+	ExpiresAt   int64  `json:"expires_at"`  // Unix时间戳，超时后需重新登录
+}
+
 // UserSession 用户会话信息
 type UserSession struct {
 	UserID       string            `json:"user_id"`
@@ -25,21 +42,42 @@ type UserSession struct {
 	Entitlement  string            `json:"entitlement_token"`
 	RiotUsername string            `json:"riot_username"`
 	RiotTagline  string            `json:"riot_tagline"`
-	Region       string            `json:"region"` // 用户区域，如ap、na、eu等
-	Cookies      map[string]string `json:"-"`      // Cookie不会返回给客户端
+	Region       string            `json:"region"`               // 用户区域，如ap、na、eu等
+	Cookies      map[string]string `json:"-"`                    // Cookie不会返回给客户端
+	ExpiresAt    int64             `json:"expires_at,omitempty"` // 会话过期时间（Unix时间戳），0表示不过期
+
+	RiotTokenExpiresAt int64 `json:"riot_token_expires_at,omitempty"` // Riot访问令牌过期时间（Unix时间戳）
 }
 
 // JWTClaims 定义JWT令牌的声明
 type JWTClaims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
+	FamilyID string `json:"fid,omitempty"` // 令牌家族ID，同一次登录签发的所有令牌共享
 	jwt.RegisteredClaims
 }
 
+// RefreshToken 服务端保存的刷新令牌记录，只保存令牌的哈希值
+type RefreshToken struct {
+	TokenHash string `json:"token_hash"`
+	FamilyID  string `json:"family_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	ExpiresAt int64  `json:"expires_at"`
+	Used      bool   `json:"used"` // 已用于换取新令牌，再次使用视为泄露
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 // UserTokensResponse 登录成功后的响应
 type UserTokensResponse struct {
-	Token string `json:"token"` // JWT令牌
-	User  struct {
+	Token        string `json:"token"`         // JWT访问令牌
+	RefreshToken string `json:"refresh_token"` // 刷新令牌，每次刷新后轮换
+	ExpiresAt    int64  `json:"expires_at"`    // 访问令牌过期时间（Unix时间戳）
+	User         struct {
 		Username string `json:"username"`
 		UserID   string `json:"user_id"`
 	} `json:"user"`
@@ -50,6 +88,9 @@ type APIError struct {
 	Status  int    `json:"status"`
 	Message string `json:"message"`
 	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"` // 机器可读的错误码，如NIGHT_MARKET_NOT_ACTIVE
+
+	RetryAfter int `json:"retry_after,omitempty"` // 被限流时建议等待的秒数，同时通过Retry-After响应头返回
 }
 
 // APISuccess 统一API成功响应格式
@@ -64,6 +105,93 @@ type RegionRequest struct {
 	Region string `json:"region" binding:"required"`
 }
 
+// 愿望单匹配到皮肤的位置
+const (
+	WishlistSourceDaily       = "daily"       // 每日商店
+	WishlistSourceBundle      = "bundle"      // 精选套装
+	WishlistSourceNightMarket = "nightmarket" // 夜市
+)
+
+// Wishlist 用户的皮肤愿望单
+type Wishlist struct {
+	UserID    string          `json:"user_id"`
+	SkinIDs   []string        `json:"skin_ids"`   // 皮肤ID（小写）
+	Notify    bool            `json:"notify"`     // 是否参与后台商店检查
+	Matches   []WishlistMatch `json:"matches"`    // 匹配记录，按时间从旧到新
+	UpdatedAt int64           `json:"updated_at"` // Unix时间戳
+}
+
+// WishlistMatch 愿望单中的皮肤出现在商店中的记录
+type WishlistMatch struct {
+	SkinID     string `json:"skin_id"`
+	SkinName   string `json:"skin_name"`
+	Source     string `json:"source"`                // daily、bundle或nightmarket
+	BundleName string `json:"bundle_name,omitempty"` // 来源为套装时的套装名称
+	Price      int    `json:"price"`                 // 匹配时的VP价格，夜市为折扣价
+	Date       string `json:"date"`                  // 每日商店开始的日期（UTC），格式YYYY-MM-DD
+	ExpiresAt  int64  `json:"expires_at"`            // 该物品在商店中的截止时间，Unix时间戳
+	MatchedAt  int64  `json:"matched_at"`            // Unix时间戳
+}
+
+// WishlistRequest 添加愿望单皮肤的请求
+type WishlistRequest struct {
+	SkinID string `json:"skin_id" binding:"required"`
+	Notify *bool  `json:"notify"` // 不传时不修改，新愿望单默认参与后台检查
+}
+
+// WishlistResponse 客户端愿望单响应
+type WishlistResponse struct {
+	Skins  []Skin `json:"skins"`
+	Notify bool   `json:"notify"`
+}
+
+// Webhook事件类型
+const (
+	WebhookEventShopRotated       = "shop.rotated"       // 每日商店已刷新
+	WebhookEventWishlistMatched   = "wishlist.matched"   // 愿望单中的皮肤出现在商店中
+	WebhookEventNightMarketOpened = "nightmarket.opened" // 夜市已开放
+	WebhookEventSessionExpired    = "session.expired"    // Riot会话已失效，需要重新登录
+	WebhookEventPing              = "ping"               // 测试推送，所有Webhook都会收到
+)
+
+// Webhook 用户注册的推送地址
+type Webhook struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"user_id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Secret    string   `json:"secret,omitempty"` // HMAC签名密钥，只在创建时返回
+	CreatedAt int64    `json:"created_at"`       // Unix时间戳
+}
+
+// WebhookRequest 注册Webhook的请求
+type WebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+}
+
+// WebhookEvent 推送给Webhook的JSON内容
+type WebhookEvent struct {
+	ID        string      `json:"id"` // 推送ID，重试时保持不变
+	Event     string      `json:"event"`
+	UserID    string      `json:"user_id"`
+	CreatedAt int64       `json:"created_at"` // Unix时间戳
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// WebhookDelivery 一次推送的记录
+type WebhookDelivery struct {
+	ID          string `json:"id"`
+	WebhookID   string `json:"webhook_id"`
+	Event       string `json:"event"`
+	Attempts    int    `json:"attempts"`              // 已尝试次数
+	StatusCode  int    `json:"status_code,omitempty"` // 最后一次尝试的HTTP状态码
+	Error       string `json:"error,omitempty"`       // 最后一次尝试的错误
+	Success     bool   `json:"success"`
+	CreatedAt   int64  `json:"created_at"`             // Unix时间戳
+	DeliveredAt int64  `json:"delivered_at,omitempty"` // 推送成功的时间，Unix时间戳
+}
+
 // 支持的区域常量
 const (
 	RegionAP    = "ap"    // 亚太地区
@@ -0,0 +1,47 @@
+package models
+
+// TelegramUpdate Telegram Bot API推送的更新
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message,omitempty"`
+}
+
+// TelegramMessage Telegram消息
+type TelegramMessage struct {
+	MessageID int64         `json:"message_id"`
+	From      *TelegramUser `json:"from,omitempty"`
+	Chat      TelegramChat  `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+// TelegramUser Telegram用户
+type TelegramUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// TelegramChat Telegram对话
+type TelegramChat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"` // private、group、supergroup或channel
+}
+
+// TelegramSendMessageRequest Bot API的sendMessage请求
+type TelegramSendMessageRequest struct {
+	ChatID                int64  `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview,omitempty"`
+}
+
+// TelegramAPIResponse Bot API的通用响应
+type TelegramAPIResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description,omitempty"`
+}
+
+// TelegramLinkCodeResponse 绑定Telegram账号的一次性代码
+type TelegramLinkCodeResponse struct {
+	Code      string `json:"code"`
+	ExpiresAt int64  `json:"expires_at"` // Unix时间戳
+}
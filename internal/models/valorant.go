@@ -3,11 +3,22 @@ package models
 // ValorantAuthResponse Riot认证服务返回的响应
 type ValorantAuthResponse struct {
 	Type     string `json:"type"`
+	Error    string `json:"error,omitempty"`
 	Response struct {
 		Parameters struct {
 			URI string `json:"uri"`
 		} `json:"parameters"`
 	} `json:"response"`
+	Multifactor ValorantMultifactorInfo `json:"multifactor"`
+}
+
+// ValorantMultifactorInfo 开启二次验证的账号在登录时返回的验证信息
+type ValorantMultifactorInfo struct {
+	Email                 string   `json:"email"`   // 部分隐藏的邮箱，如 j***@gmail.com
+	Method                string   `json:"method"`  // 当前验证方式，通常为email
+	Methods               []string `json:"methods"` // 可用的验证方式
+	MultiFactorCodeLength int      `json:"multiFactorCodeLength"`
+	MfaVersion            string   `json:"mfaVersion"`
 }
 
 // ValorantTokenResponse 包含Riot的访问令牌
@@ -99,7 +110,7 @@ type BonusStore struct {
 // BonusStoreOffer 特惠商店中的物品
 type BonusStoreOffer struct {
 	BonusOfferID    string         `json:"BonusOfferID"`
-	Offer           ItemInfo       `json:"Offer"`
+	Offer           Offer          `json:"Offer"`
 	DiscountPercent int            `json:"DiscountPercent"`
 	DiscountCosts   map[string]int `json:"DiscountCosts"`
 	IsSeen          bool           `json:"IsSeen"`
@@ -111,10 +122,11 @@ type AccessoryStore struct {
 	AccessoryStoreRemainingDurationInSeconds int64                 `json:"AccessoryStoreRemainingDurationInSeconds"`
 }
 
-// AccessoryStoreOffer 配件商店中的物品
+// AccessoryStoreOffer 配件商店中的物品，使用王国信用点购买
 type AccessoryStoreOffer struct {
-	Offer      ItemInfo `json:"Offer"`
-	ContractID string   `json:"ContractID"`
+	Offer            Offer  `json:"Offer"`
+	StorefrontItemID string `json:"StorefrontItemID"`
+	ContractID       string `json:"ContractID"` // 物品所属的合约
 }
 
 // UpgradeCurrencyStore 升级币商店
@@ -130,6 +142,96 @@ type UpgradeCurrencyOffer struct {
 	Cost             int      `json:"Cost"`
 }
 
+// ValorantOffersResponse 商店所有可购买物品的价格表
+type ValorantOffersResponse struct {
+	Offers                []Offer                `json:"Offers"`
+	UpgradeCurrencyOffers []UpgradeCurrencyOffer `json:"UpgradeCurrencyOffers"`
+}
+
+// Offer 可购买的物品及其价格
+type Offer struct {
+	OfferID          string         `json:"OfferID"`
+	IsDirectPurchase bool           `json:"IsDirectPurchase"`
+	StartDate        string         `json:"StartDate"`
+	Cost             map[string]int `json:"Cost"` // 键是货币ID，值是价格
+	Rewards          []OfferReward  `json:"Rewards"`
+}
+
+// OfferReward 购买后获得的物品
+type OfferReward struct {
+	ItemTypeID string `json:"ItemTypeID"`
+	ItemID     string `json:"ItemID"`
+	Quantity   int    `json:"Quantity"`
+}
+
+// 货币ID
+const (
+	CurrencyValorantPoints  = "85ad13f7-3d1b-5128-9eb2-7cd8ee0b5741" // VP
+	CurrencyRadianitePoints = "e59aa87c-4cbf-517a-5983-6e81511be9b7" // RP
+	CurrencyKingdomCredits  = "85ca954a-41f2-ce94-9b45-8ca3dd39a00d" // KC
+)
+
+// 物品类型ID
+const (
+	ItemTypeSkinLevel   = "e7c63390-eda7-46e0-bb7a-a6abdacd2433" // 皮肤等级
+	ItemTypeSkinChroma  = "3ad1b2b2-acdb-4524-852f-954a76ddae0a" // 皮肤颜色变体
+	ItemTypeBuddy       = "dd3bf334-87f3-40bd-b043-682a57a8dc3a" // 枪挂
+	ItemTypeSpray       = "d5f120f8-ff8c-4aac-92ea-f2b5acbe9475" // 喷漆
+	ItemTypePlayerCard  = "3f296c07-64c3-494c-923b-fe692a4fa1bd" // 玩家卡面
+	ItemTypePlayerTitle = "de7caa6b-adf7-4588-bbd1-143831e786c6" // 玩家称号
+)
+
+// ValorantEntitlementsResponse 用户拥有的某一类物品
+type ValorantEntitlementsResponse struct {
+	ItemTypeID   string        `json:"ItemTypeID"`
+	Entitlements []Entitlement `json:"Entitlements"`
+}
+
+// Entitlement 用户拥有的单个物品
+type Entitlement struct {
+	TypeID     string `json:"TypeID"`
+	ItemID     string `json:"ItemID"`
+	InstanceID string `json:"InstanceID,omitempty"`
+}
+
+// ValorantPlayerLoadout 玩家当前的装备（武器皮肤、枪挂、喷漆、卡面和称号）
+type ValorantPlayerLoadout struct {
+	Subject   string          `json:"Subject"`
+	Version   int             `json:"Version"`
+	Guns      []LoadoutGun    `json:"Guns"`
+	Sprays    []LoadoutSpray  `json:"Sprays"`
+	Identity  LoadoutIdentity `json:"Identity"`
+	Incognito bool            `json:"Incognito"`
+}
+
+// LoadoutGun 单把武器的装备
+type LoadoutGun struct {
+	ID              string        `json:"ID"` // 武器ID
+	SkinID          string        `json:"SkinID"`
+	SkinLevelID     string        `json:"SkinLevelID"`
+	ChromaID        string        `json:"ChromaID"`
+	CharmInstanceID string        `json:"CharmInstanceID,omitempty"`
+	CharmID         string        `json:"CharmID,omitempty"`
+	CharmLevelID    string        `json:"CharmLevelID,omitempty"`
+	Attachments     []interface{} `json:"Attachments"`
+}
+
+// LoadoutSpray 喷漆栏位
+type LoadoutSpray struct {
+	EquipSlotID  string  `json:"EquipSlotID"`
+	SprayID      string  `json:"SprayID"`
+	SprayLevelID *string `json:"SprayLevelID"`
+}
+
+// LoadoutIdentity 玩家卡面、称号等身份信息
+type LoadoutIdentity struct {
+	PlayerCardID           string `json:"PlayerCardID"`
+	PlayerTitleID          string `json:"PlayerTitleID"`
+	AccountLevel           int    `json:"AccountLevel"`
+	PreferredLevelBorderID string `json:"PreferredLevelBorderID"`
+	HideAccountLevel       bool   `json:"HideAccountLevel"`
+}
+
 // ValorantWalletResponse 用户钱包/余额信息
 type ValorantWalletResponse struct {
 	Balances map[string]int `json:"Balances"` // 键是货币ID，值是数量
@@ -137,41 +239,224 @@ type ValorantWalletResponse struct {
 
 // Skin 皮肤信息
 type Skin struct {
-	UUID       string `json:"uuid"`
-	Name       string `json:"name"`
-	IconURL    string `json:"icon_url"`
-	TierUUID   string `json:"tier_uuid"`
-	TierName   string `json:"tier_name"`
-	Price      int    `json:"price"`
-	WeaponID   string `json:"weapon_id"`
-	WeaponName string `json:"weapon_name"`
+	UUID       string         `json:"uuid"`
+	Name       string         `json:"name"`
+	IconURL    string         `json:"icon_url"`
+	TierUUID   string         `json:"tier_uuid"`
+	TierName   string         `json:"tier_name"`
+	Price      int            `json:"price"`           // VP价格
+	Costs      map[string]int `json:"costs,omitempty"` // 所有货币的价格，键是货币ID
+	WeaponID   string         `json:"weapon_id"`
+	WeaponName string         `json:"weapon_name"`
+	Default    bool           `json:"default,omitempty"` // 武器的默认皮肤，所有玩家都拥有
+	Levels     []SkinLevel    `json:"levels,omitempty"`  // 商店中的皮肤ID为第一个等级的UUID
+	Chromas    []SkinChroma   `json:"chromas,omitempty"` // 皮肤的颜色变体
+}
+
+// SkinLevel 皮肤等级
+type SkinLevel struct {
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// SkinChroma 皮肤颜色变体
+type SkinChroma struct {
+	UUID      string `json:"uuid"`
+	Name      string `json:"name"`
+	IconURL   string `json:"icon_url,omitempty"`
+	SwatchURL string `json:"swatch_url,omitempty"`
+}
+
+// 饰品类型
+const (
+	CosmeticBuddy       = "buddy"       // 枪挂
+	CosmeticSpray       = "spray"       // 喷漆
+	CosmeticPlayerCard  = "playercard"  // 玩家卡面
+	CosmeticPlayerTitle = "playertitle" // 玩家称号
+)
+
+// Cosmetic 枪挂、喷漆、玩家卡面、称号等饰品信息
+type Cosmetic struct {
+	UUID       string   `json:"uuid"`
+	Type       string   `json:"type"`
+	Name       string   `json:"name"`
+	IconURL    string   `json:"icon_url,omitempty"`
+	LevelUUIDs []string `json:"level_uuids,omitempty"` // 枪挂和喷漆的等级UUID，拥有和购买时使用的是等级UUID
+}
+
+// BundleInfo 套装的名称、描述和图片
+type BundleInfo struct {
+	UUID          string `json:"uuid"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	IconURL       string `json:"icon_url,omitempty"`
+	PromoImageURL string `json:"promo_image_url,omitempty"`
 }
 
 // SkinsDatabase 所有皮肤的本地数据库
 type SkinsDatabase struct {
-	Skins []Skin `json:"skins"`
+	Skins     []Skin       `json:"skins"`
+	Cosmetics []Cosmetic   `json:"cosmetics,omitempty"`
+	Bundles   []BundleInfo `json:"bundles,omitempty"`
 }
 
-// ShopItem 商店物品，包含完整的皮肤信息
+// ShopItem 商店物品，皮肤物品包含完整的皮肤信息，其他物品（套装中的枪挂、卡面等）包含饰品信息
 type ShopItem struct {
-	Skin            Skin `json:"skin"`
-	DiscountPercent int  `json:"discount_percent,omitempty"`
-	FinalPrice      int  `json:"final_price"`
+	ItemType        string    `json:"item_type,omitempty"` // skin或饰品类型
+	Skin            Skin      `json:"skin,omitzero"`
+	Item            *Cosmetic `json:"item,omitempty"`
+	Quantity        int       `json:"quantity,omitempty"`
+	BasePrice       int       `json:"base_price,omitempty"`
+	DiscountPercent int       `json:"discount_percent,omitempty"`
+	FinalPrice      int       `json:"final_price"`
+	Owned           bool      `json:"owned"` // 用户是否已拥有
+}
+
+// 商店物品类型中的皮肤
+const ShopItemSkin = "skin"
+
+// OwnedSkin 用户拥有的皮肤及已解锁的等级和颜色变体
+type OwnedSkin struct {
+	Skin    Skin         `json:"skin"`
+	Levels  []SkinLevel  `json:"owned_levels"`
+	Chromas []SkinChroma `json:"owned_chromas"`
+}
+
+// InventoryResponse 用户库存响应
+type InventoryResponse struct {
+	Skins        []OwnedSkin `json:"skins"`
+	Buddies      []Cosmetic  `json:"buddies"`
+	Sprays       []Cosmetic  `json:"sprays"`
+	PlayerCards  []Cosmetic  `json:"player_cards"`
+	PlayerTitles []Cosmetic  `json:"player_titles"`
+}
+
+// ShopBundle 商店中的精选套装
+type ShopBundle struct {
+	ID              string     `json:"id"`
+	UUID            string     `json:"uuid"` // 套装内容ID（DataAssetID）
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	IconURL         string     `json:"icon_url,omitempty"`
+	PromoImageURL   string     `json:"promo_image_url,omitempty"`
+	Items           []ShopItem `json:"items"`
+	Price           int        `json:"price"`
+	DiscountedPrice int        `json:"discounted_price"`
+	Savings         int        `json:"savings"`        // 整套购买节省的价格
+	WholesaleOnly   bool       `json:"wholesale_only"` // 只能整套购买
+	ExpiresAt       int64      `json:"expires_at"`     // Unix时间戳
 }
 
 // ShopResponse 客户端商店响应
 type ShopResponse struct {
-	DailyOffers    []ShopItem `json:"daily_offers"`
-	FeaturedBundle struct {
-		Name            string     `json:"name"`
-		Description     string     `json:"description"`
-		Items           []ShopItem `json:"items"`
-		Price           int        `json:"price"`
-		DiscountedPrice int        `json:"discounted_price"`
-		ExpiresAt       int64      `json:"expires_at"` // Unix时间戳
-	} `json:"featured_bundle"`
-	BonusOffers []ShopItem `json:"bonus_offers,omitempty"` // 夜市
-	ExpiresAt   int64      `json:"expires_at"`             // Unix时间戳
+	DailyOffers        []ShopItem        `json:"daily_offers"`
+	FeaturedBundle     ShopBundle        `json:"featured_bundle"`                // 第一个精选套装，兼容旧客户端
+	FeaturedBundles    []ShopBundle      `json:"featured_bundles"`               // 所有精选套装
+	BonusOffers        []NightMarketItem `json:"bonus_offers,omitempty"`         // 夜市
+	BonusExpiresAt     int64             `json:"bonus_expires_at,omitempty"`     // 夜市结束时间，Unix时间戳
+	AccessoryOffers    []AccessoryItem   `json:"accessory_offers,omitempty"`     // 配件商店
+	AccessoryExpiresAt int64             `json:"accessory_expires_at,omitempty"` // 配件商店刷新时间，Unix时间戳
+	ExpiresAt          int64             `json:"expires_at"`                     // 每日商店刷新时间，Unix时间戳
+	NextRotationAt     int64             `json:"next_rotation_at"`               // 最早的商店刷新时间，Unix时间戳
+}
+
+// NightMarketItem 夜市中的物品
+type NightMarketItem struct {
+	ShopItem
+	BonusOfferID  string         `json:"bonus_offer_id"`
+	Costs         map[string]int `json:"costs"`          // 原价，键是货币ID
+	DiscountCosts map[string]int `json:"discount_costs"` // 折扣价，键是货币ID
+	Seen          bool           `json:"seen"`           // 是否已翻开
+}
+
+// NightMarketResponse 客户端夜市响应
+type NightMarketResponse struct {
+	Offers    []NightMarketItem `json:"offers"`
+	ExpiresAt int64             `json:"expires_at"` // Unix时间戳
+}
+
+// ShopSnapshot 某一轮每日商店的历史快照，每个账号每轮只保存一份
+type ShopSnapshot struct {
+	Date            string            `json:"date"`                   // 每日商店开始的日期（UTC），格式YYYY-MM-DD
+	RecordedAt      int64             `json:"recorded_at"`            // 首次记录时间，Unix时间戳
+	UpdatedAt       int64             `json:"updated_at"`             // 最后更新时间，Unix时间戳
+	ExpiresAt       int64             `json:"expires_at"`             // 每日商店刷新时间，Unix时间戳
+	DailyOffers     []ShopItem        `json:"daily_offers"`           // 每日商店
+	FeaturedBundles []ShopBundle      `json:"featured_bundles"`       // 精选套装
+	BonusOffers     []NightMarketItem `json:"bonus_offers,omitempty"` // 夜市
+}
+
+// ShopHistoryResponse 客户端商店历史响应，按日期从新到旧排列
+type ShopHistoryResponse struct {
+	Snapshots []ShopSnapshot `json:"snapshots"`
+	Total     int            `json:"total"` // 符合条件的快照总数
+	Page      int            `json:"page"`
+	PageSize  int            `json:"page_size"`
+}
+
+// AccessoryItem 配件商店中的物品
+type AccessoryItem struct {
+	OfferID    string         `json:"offer_id"`
+	Item       Cosmetic       `json:"item"`
+	Quantity   int            `json:"quantity"`
+	Price      int            `json:"price"`           // 王国信用点价格
+	Costs      map[string]int `json:"costs,omitempty"` // 所有货币的价格，键是货币ID
+	ContractID string         `json:"contract_id"`
+	ExpiresAt  int64          `json:"expires_at"` // Unix时间戳
+}
+
+// AccessoryStoreResponse 客户端配件商店响应
+type AccessoryStoreResponse struct {
+	Offers    []AccessoryItem `json:"offers"`
+	ExpiresAt int64           `json:"expires_at"` // Unix时间戳
+}
+
+// EquippedGun 武器当前装备的皮肤和枪挂
+type EquippedGun struct {
+	WeaponID   string     `json:"weapon_id"`
+	WeaponName string     `json:"weapon_name"`
+	Skin       Skin       `json:"skin"`
+	SkinLevel  SkinLevel  `json:"skin_level"`
+	Chroma     SkinChroma `json:"chroma"`
+	Buddy      *Cosmetic  `json:"buddy,omitempty"`
+}
+
+// EquippedSpray 喷漆栏位当前装备的喷漆
+type EquippedSpray struct {
+	SlotID string   `json:"slot_id"`
+	Spray  Cosmetic `json:"spray"`
+}
+
+// LoadoutResponse 客户端装备响应
+type LoadoutResponse struct {
+	Guns        []EquippedGun   `json:"guns"`
+	Sprays      []EquippedSpray `json:"sprays"`
+	PlayerCard  Cosmetic        `json:"player_card"`
+	PlayerTitle Cosmetic        `json:"player_title"`
+	Incognito   bool            `json:"incognito"`
+}
+
+// LoadoutUpdateRequest 修改装备请求，未列出的武器和栏位保持不变
+type LoadoutUpdateRequest struct {
+	Guns          []LoadoutGunUpdate   `json:"guns"`
+	Sprays        []LoadoutSprayUpdate `json:"sprays"`
+	PlayerCardID  string               `json:"player_card_id"`  // 为空时不修改
+	PlayerTitleID string               `json:"player_title_id"` // 为空时不修改
+}
+
+// LoadoutGunUpdate 修改单把武器的装备
+type LoadoutGunUpdate struct {
+	WeaponID     string  `json:"weapon_id" binding:"required"`
+	SkinLevelID  string  `json:"skin_level_id"`  // 为空时不修改
+	ChromaID     string  `json:"chroma_id"`      // 为空时不修改，更换皮肤时默认使用第一个颜色变体
+	BuddyLevelID *string `json:"buddy_level_id"` // 不传时不修改，为空字符串时卸下枪挂
+}
+
+// LoadoutSprayUpdate 修改喷漆栏位
+type LoadoutSprayUpdate struct {
+	SlotID  string `json:"slot_id" binding:"required"`
+	SprayID string `json:"spray_id" binding:"required"`
 }
 
 // WalletResponse 客户端钱包/余额响应
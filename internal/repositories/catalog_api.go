@@ -0,0 +1,346 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+const (
+	// DefaultCatalogBaseURL valorant-api.com的默认地址
+	DefaultCatalogBaseURL = "https://valorant-api.com"
+
+	catalogWeaponsPath      = "/v1/weapons"
+	catalogSkinsPath        = "/v1/weapons/skins"
+	catalogContentTiersPath = "/v1/contenttiers"
+	catalogBuddiesPath      = "/v1/buddies"
+	catalogSpraysPath       = "/v1/sprays"
+	catalogPlayerCardsPath  = "/v1/playercards"
+	catalogPlayerTitlesPath = "/v1/playertitles"
+	catalogBundlesPath      = "/v1/bundles"
+)
+
+// CatalogWeapon valorant-api.com中的武器
+type CatalogWeapon struct {
+	UUID            string        `json:"uuid"`
+	DisplayName     string        `json:"displayName"`
+	DefaultSkinUUID string        `json:"defaultSkinUuid"`
+	Skins           []CatalogSkin `json:"skins"`
+}
+
+// CatalogSkin valorant-api.com中的武器皮肤
+type CatalogSkin struct {
+	UUID            string  `json:"uuid"`
+	DisplayName     string  `json:"displayName"`
+	ContentTierUUID *string `json:"contentTierUuid"`
+	DisplayIcon     *string `json:"displayIcon"`
+	Levels          []struct {
+		UUID        string  `json:"uuid"`
+		DisplayName string  `json:"displayName"`
+		DisplayIcon *string `json:"displayIcon"`
+	} `json:"levels"`
+	Chromas []struct {
+		UUID        string  `json:"uuid"`
+		DisplayName string  `json:"displayName"`
+		DisplayIcon *string `json:"displayIcon"`
+		FullRender  *string `json:"fullRender"`
+		Swatch      *string `json:"swatch"`
+	} `json:"chromas"`
+}
+
+// CatalogContentTier valorant-api.com中的皮肤等级（如Deluxe、Premium）
+type CatalogContentTier struct {
+	UUID           string `json:"uuid"`
+	DisplayName    string `json:"displayName"`
+	DevName        string `json:"devName"`
+	Rank           int    `json:"rank"`
+	HighlightColor string `json:"highlightColor"`
+	DisplayIcon    string `json:"displayIcon"`
+}
+
+// CatalogCosmetic valorant-api.com中的枪挂、喷漆、玩家卡面和称号
+// 四个接口的字段大体相同，缺少的字段保持为空
+type CatalogCosmetic struct {
+	UUID                string  `json:"uuid"`
+	DisplayName         string  `json:"displayName"`
+	DisplayIcon         *string `json:"displayIcon"`
+	FullTransparentIcon *string `json:"fullTransparentIcon"`
+	TitleText           *string `json:"titleText"`
+	Levels              []struct {
+		UUID string `json:"uuid"`
+	} `json:"levels"`
+}
+
+// CatalogBundle valorant-api.com中的套装
+type CatalogBundle struct {
+	UUID               string  `json:"uuid"`
+	DisplayName        string  `json:"displayName"`
+	Description        string  `json:"description"`
+	ExtraDescription   *string `json:"extraDescription"`
+	DisplayIcon        string  `json:"displayIcon"`
+	DisplayIcon2       string  `json:"displayIcon2"`
+	VerticalPromoImage *string `json:"verticalPromoImage"`
+}
+
+// CatalogDump 导入皮肤数据库所需的全部数据，也是离线导入文件的格式
+type CatalogDump struct {
+	Weapons      []CatalogWeapon      `json:"weapons"`
+	Skins        []CatalogSkin        `json:"skins"`
+	ContentTiers []CatalogContentTier `json:"contenttiers"`
+	Buddies      []CatalogCosmetic    `json:"buddies,omitempty"`
+	Sprays       []CatalogCosmetic    `json:"sprays,omitempty"`
+	PlayerCards  []CatalogCosmetic    `json:"playercards,omitempty"`
+	PlayerTitles []CatalogCosmetic    `json:"playertitles,omitempty"`
+	Bundles      []CatalogBundle      `json:"bundles,omitempty"`
+}
+
+// CatalogAPI 从valorant-api.com（或兼容的本地服务）获取游戏内容数据
+type CatalogAPI struct {
+	baseURL  string
+	language string
+	client   *http.Client
+}
+
+// NewCatalogAPI 创建内容数据客户端，baseURL为空时使用valorant-api.com
+func NewCatalogAPI(baseURL, language string) *CatalogAPI {
+	if baseURL == "" {
+		baseURL = DefaultCatalogBaseURL
+	}
+
+	return &CatalogAPI{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		language: language,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// FetchCatalog 获取武器、皮肤、皮肤等级和饰品数据
+// ctx取消时停止请求
+func (c *CatalogAPI) FetchCatalog(ctx context.Context) (*CatalogDump, error) {
+	dump := &CatalogDump{}
+
+	if err := c.fetch(ctx, catalogWeaponsPath, &dump.Weapons); err != nil {
+		return nil, fmt.Errorf("获取武器数据失败: %w", err)
+	}
+	if err := c.fetch(ctx, catalogSkinsPath, &dump.Skins); err != nil {
+		return nil, fmt.Errorf("获取皮肤数据失败: %w", err)
+	}
+	if err := c.fetch(ctx, catalogContentTiersPath, &dump.ContentTiers); err != nil {
+		return nil, fmt.Errorf("获取皮肤等级数据失败: %w", err)
+	}
+	if err := c.fetch(ctx, catalogBuddiesPath, &dump.Buddies); err != nil {
+		return nil, fmt.Errorf("获取枪挂数据失败: %w", err)
+	}
+	if err := c.fetch(ctx, catalogSpraysPath, &dump.Sprays); err != nil {
+		return nil, fmt.Errorf("获取喷漆数据失败: %w", err)
+	}
+	if err := c.fetch(ctx, catalogPlayerCardsPath, &dump.PlayerCards); err != nil {
+		return nil, fmt.Errorf("获取玩家卡面数据失败: %w", err)
+	}
+	if err := c.fetch(ctx, catalogPlayerTitlesPath, &dump.PlayerTitles); err != nil {
+		return nil, fmt.Errorf("获取玩家称号数据失败: %w", err)
+	}
+	if err := c.fetch(ctx, catalogBundlesPath, &dump.Bundles); err != nil {
+		return nil, fmt.Errorf("获取套装数据失败: %w", err)
+	}
+
+	return dump, nil
+}
+
+// fetch 请求valorant-api.com接口并解析响应中的data字段
+func (c *CatalogAPI) fetch(ctx context.Context, path string, result interface{}) error {
+	requestURL := c.baseURL + path
+	if c.language != "" {
+		requestURL += "?language=" + url.QueryEscape(c.language)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "val-store-backend")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 %s 失败，状态码: %d", path, resp.StatusCode)
+	}
+
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: result}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", path, err)
+	}
+
+	return nil
+}
+
+// LoadCatalogDump 从离线导出的JSON文件读取内容数据
+func LoadCatalogDump(filePath string) (*CatalogDump, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取离线皮肤数据失败: %w", err)
+	}
+
+	var dump CatalogDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("解析离线皮肤数据失败: %w", err)
+	}
+
+	return &dump, nil
+}
+
+// BuildSkins 将内容数据转换为皮肤数据库中的皮肤列表
+func BuildSkins(dump *CatalogDump) []models.Skin {
+	// 内容等级UUID -> 名称
+	tierNames := make(map[string]string, len(dump.ContentTiers))
+	for _, tier := range dump.ContentTiers {
+		tierNames[strings.ToLower(tier.UUID)] = tier.DisplayName
+	}
+
+	// 皮肤UUID -> 所属武器
+	skinWeapons := make(map[string]CatalogWeapon)
+	for _, weapon := range dump.Weapons {
+		for _, skin := range weapon.Skins {
+			skinWeapons[strings.ToLower(skin.UUID)] = weapon
+		}
+	}
+
+	// 优先使用皮肤接口的数据，离线文件中缺少时退回武器中嵌套的皮肤
+	catalogSkins := dump.Skins
+	if len(catalogSkins) == 0 {
+		for _, weapon := range dump.Weapons {
+			catalogSkins = append(catalogSkins, weapon.Skins...)
+		}
+	}
+
+	skins := make([]models.Skin, 0, len(catalogSkins))
+	for _, catalogSkin := range catalogSkins {
+		skin := models.Skin{
+			UUID: strings.ToLower(catalogSkin.UUID),
+			Name: catalogSkin.DisplayName,
+		}
+
+		if catalogSkin.ContentTierUUID != nil {
+			skin.TierUUID = strings.ToLower(*catalogSkin.ContentTierUUID)
+			skin.TierName = tierNames[skin.TierUUID]
+		}
+
+		if weapon, found := skinWeapons[skin.UUID]; found {
+			skin.WeaponID = strings.ToLower(weapon.UUID)
+			skin.WeaponName = weapon.DisplayName
+			skin.Default = strings.EqualFold(weapon.DefaultSkinUUID, skin.UUID)
+		}
+
+		for _, level := range catalogSkin.Levels {
+			skin.Levels = append(skin.Levels, models.SkinLevel{
+				UUID:    strings.ToLower(level.UUID),
+				Name:    level.DisplayName,
+				IconURL: stringValue(level.DisplayIcon),
+			})
+		}
+
+		for _, chroma := range catalogSkin.Chromas {
+			icon := stringValue(chroma.FullRender)
+			if icon == "" {
+				icon = stringValue(chroma.DisplayIcon)
+			}
+			skin.Chromas = append(skin.Chromas, models.SkinChroma{
+				UUID:      strings.ToLower(chroma.UUID),
+				Name:      chroma.DisplayName,
+				IconURL:   icon,
+				SwatchURL: stringValue(chroma.Swatch),
+			})
+		}
+
+		// 标准皮肤没有displayIcon，依次使用第一个等级和第一个颜色变体的图片
+		skin.IconURL = stringValue(catalogSkin.DisplayIcon)
+		if skin.IconURL == "" && len(skin.Levels) > 0 {
+			skin.IconURL = skin.Levels[0].IconURL
+		}
+		if skin.IconURL == "" && len(skin.Chromas) > 0 {
+			skin.IconURL = skin.Chromas[0].IconURL
+		}
+
+		skins = append(skins, skin)
+	}
+
+	return skins
+}
+
+// BuildCosmetics 将内容数据转换为饰品列表
+func BuildCosmetics(dump *CatalogDump) []models.Cosmetic {
+	var cosmetics []models.Cosmetic
+
+	add := func(cosmeticType string, items []CatalogCosmetic) {
+		for _, item := range items {
+			cosmetic := models.Cosmetic{
+				UUID:    strings.ToLower(item.UUID),
+				Type:    cosmeticType,
+				Name:    item.DisplayName,
+				IconURL: stringValue(item.DisplayIcon),
+			}
+			// 喷漆优先使用透明背景的图片
+			if icon := stringValue(item.FullTransparentIcon); icon != "" {
+				cosmetic.IconURL = icon
+			}
+			// 部分称号没有displayName，使用称号文字
+			if cosmetic.Name == "" {
+				cosmetic.Name = stringValue(item.TitleText)
+			}
+			for _, level := range item.Levels {
+				cosmetic.LevelUUIDs = append(cosmetic.LevelUUIDs, strings.ToLower(level.UUID))
+			}
+			cosmetics = append(cosmetics, cosmetic)
+		}
+	}
+
+	add(models.CosmeticBuddy, dump.Buddies)
+	add(models.CosmeticSpray, dump.Sprays)
+	add(models.CosmeticPlayerCard, dump.PlayerCards)
+	add(models.CosmeticPlayerTitle, dump.PlayerTitles)
+
+	return cosmetics
+}
+
+// BuildBundles 将内容数据转换为套装列表
+func BuildBundles(dump *CatalogDump) []models.BundleInfo {
+	bundles := make([]models.BundleInfo, 0, len(dump.Bundles))
+	for _, bundle := range dump.Bundles {
+		description := bundle.Description
+		if extra := stringValue(bundle.ExtraDescription); extra != "" {
+			description = extra
+		}
+
+		bundles = append(bundles, models.BundleInfo{
+			UUID:          strings.ToLower(bundle.UUID),
+			Name:          bundle.DisplayName,
+			Description:   description,
+			IconURL:       bundle.DisplayIcon,
+			PromoImageURL: stringValue(bundle.VerticalPromoImage),
+		})
+	}
+	return bundles
+}
+
+// stringValue 返回字符串指针的值，nil时返回空字符串
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Riot请求失败的原因，使用errors.Is判断
+// 错误中不包含Riot的响应体，响应体只用于判断错误类型，不会写入日志或返回给客户端
+var (
+	// ErrInvalidCredentials 用户名、密码或Cookie无效
+	ErrInvalidCredentials = errors.New("用户名、密码或Cookie无效")
+
+	// ErrMFARequired 需要有效的二次验证码，提交的验证码错误时返回
+	ErrMFARequired = errors.New("需要有效的二次验证码")
+
+	// ErrCaptchaRequired Riot要求完成人机验证，无法使用用户名和密码登录
+	ErrCaptchaRequired = errors.New("Riot要求完成人机验证")
+
+	// ErrRiotTokenExpired Riot访问令牌已过期或被拒绝，需要重新认证
+	ErrRiotTokenExpired = errors.New("Riot访问令牌已过期")
+
+	// ErrUpstreamUnavailable Riot服务返回5xx或无法连接
+	ErrUpstreamUnavailable = errors.New("Riot服务暂时不可用")
+
+	// ErrRegionMismatch 玩家数据接口返回404，通常是会话的区域与账号所在区域不一致
+	ErrRegionMismatch = errors.New("账号不属于当前区域")
+
+	// ErrNotFound 请求的资源不存在
+	ErrNotFound = errors.New("资源不存在")
+)
+
+// playerCalls 按玩家PUUID请求的接口，返回404表示区域不正确
+var playerCalls = map[string]bool{
+	RiotCallStorefront: true,
+	RiotCallWallet:     true,
+	RiotCallOwnedItems: true,
+	RiotCallLoadout:    true,
+}
+
+// RiotStatusError Riot返回了非预期的状态码
+type RiotStatusError struct {
+	Call       string // 请求名称，见RiotCall*常量
+	StatusCode int
+}
+
+func (e *RiotStatusError) Error() string {
+	return fmt.Sprintf("Riot请求%s失败，状态码: %d", e.Call, e.StatusCode)
+}
+
+// Is 按状态码对应到ErrUpstreamUnavailable、ErrRegionMismatch或ErrNotFound
+func (e *RiotStatusError) Is(target error) bool {
+	switch target {
+	case ErrUpstreamUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	case ErrRegionMismatch:
+		return e.StatusCode == http.StatusNotFound && playerCalls[e.Call]
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound && !playerCalls[e.Call]
+	}
+	return false
+}
+
+// riotStatusError 根据Riot的失败响应构建错误，body只用于判断错误类型
+func riotStatusError(call string, statusCode int, body string) error {
+	if isTokenRejected(statusCode, body) {
+		return ErrRiotTokenExpired
+	}
+	if call == RiotCallAuthorization && strings.Contains(body, "captcha") {
+		return ErrCaptchaRequired
+	}
+	return &RiotStatusError{Call: call, StatusCode: statusCode}
+}
+
+// isTokenRejected 判断Riot是否因为令牌失效拒绝了请求
+// 令牌过期时PD接口返回401，部分接口返回400并带有BAD_CLAIMS错误码
+func isTokenRejected(statusCode int, body string) bool {
+	if statusCode == http.StatusUnauthorized {
+		return true
+	}
+	return statusCode == http.StatusBadRequest && strings.Contains(body, "BAD_CLAIMS")
+}
+
+// ErrRateLimited Riot限制了请求频率，使用errors.As获取*RateLimitError中的等待时间
+var ErrRateLimited = errors.New("Riot请求过于频繁")
+
+// RateLimitError Riot返回429或本地限流的等待时间超过了请求期限
+type RateLimitError struct {
+	Host       string
+	RetryAfter time.Duration // 建议的等待时间
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v（%s），请在%v后重试", ErrRateLimited, e.Host, e.RetryAfter.Round(time.Second))
+}
+
+// Is 使errors.Is(err, ErrRateLimited)成立
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
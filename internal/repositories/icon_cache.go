@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// IconCacheDirName 图标缓存在数据目录下的目录名
+	IconCacheDirName = "icon_cache"
+
+	// maxIconSize 单个图标的最大长度
+	maxIconSize = 8 << 20
+)
+
+// IconCache 将皮肤和套装图标缓存到磁盘，图标地址中包含资源ID，内容不会变化，因此不设置过期时间
+type IconCache struct {
+	dir    string
+	client *http.Client
+}
+
+// NewIconCache 创建图标缓存
+func NewIconCache(dir string) (*IconCache, error) {
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+
+	return &IconCache{
+		dir:    absPath,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Get 获取图标内容，缓存中没有时下载并写入缓存
+func (i *IconCache) Get(ctx context.Context, iconURL string) ([]byte, error) {
+	sum := sha256.Sum256([]byte(iconURL))
+	path := filepath.Join(i.dir, hex.EncodeToString(sum[:]))
+
+	if data, err := os.ReadFile(path); err == nil {
+		return data, nil
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取图标缓存失败: %w", err)
+	}
+
+	data, err := i.download(ctx, iconURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// 缓存写入失败不影响本次使用
+	if err := i.save(path, data); err != nil {
+		fmt.Printf("写入图标缓存失败: %v\n", err)
+	}
+	return data, nil
+}
+
+// download 下载图标
+func (i *IconCache) download(ctx context.Context, iconURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, iconURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建图标请求失败: %w", err)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载图标失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载图标失败，状态码: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取图标失败: %w", err)
+	}
+	if len(data) > maxIconSize {
+		return nil, fmt.Errorf("图标超过%d字节", maxIconSize)
+	}
+	return data, nil
+}
+
+// save 写入缓存文件，多个请求同时下载同一个图标时后写入的覆盖先写入的
+func (i *IconCache) save(path string, data []byte) error {
+	if err := os.MkdirAll(i.dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免读取到写了一半的图标
+	tmp, err := os.CreateTemp(i.dir, "icon-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// LinksFileName 第三方账号绑定在数据目录下的文件名
+	LinksFileName = "integration_links.json"
+
+	// 支持绑定的第三方平台
+	LinkPlatformDiscord  = "discord"
+	LinkPlatformTelegram = "telegram"
+)
+
+// ErrLinkConflict 第三方账号已绑定到其他用户
+var ErrLinkConflict = errors.New("该账号已绑定到其他用户")
+
+// LinkStore 保存第三方平台账号（如Discord用户ID）与val-store用户的绑定关系
+// 所有绑定保存在内存中，每次修改后整体写回文件
+type LinkStore struct {
+	links    map[string]map[string]string // 平台 -> 第三方账号ID -> 用户ID
+	filePath string
+	mutex    sync.RWMutex
+}
+
+// NewLinkStore 创建绑定存储，并加载已有的数据文件
+func NewLinkStore(filePath string) (*LinkStore, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+
+	store := &LinkStore{
+		links:    make(map[string]map[string]string),
+		filePath: absPath,
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		// 文件不存在不是错误
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("读取绑定文件失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.links); err != nil {
+		return nil, fmt.Errorf("解析绑定文件失败: %w", err)
+	}
+	if store.links == nil {
+		store.links = make(map[string]map[string]string)
+	}
+
+	return store, nil
+}
+
+// saveLocked 将所有绑定写回文件，调用方需持有写锁
+func (l *LinkStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(l.filePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(l.links, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化绑定失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := l.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("写入绑定文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, l.filePath); err != nil {
+		return fmt.Errorf("替换绑定文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserID 获取第三方账号绑定的用户ID
+func (l *LinkStore) GetUserID(platform, externalID string) (string, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	userID, exists := l.links[platform][externalID]
+	return userID, exists
+}
+
+// ListByUser 列出用户在平台上绑定的所有第三方账号ID
+func (l *LinkStore) ListByUser(platform, userID string) []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	externalIDs := make([]string, 0)
+	for externalID, linkedUserID := range l.links[platform] {
+		if linkedUserID == userID {
+			externalIDs = append(externalIDs, externalID)
+		}
+	}
+	return externalIDs
+}
+
+// ListUsers 列出在平台上绑定了第三方账号的所有用户ID
+func (l *LinkStore) ListUsers(platform string) []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	seen := make(map[string]bool)
+	userIDs := make([]string, 0)
+	for _, userID := range l.links[platform] {
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs
+}
+
+// Link 绑定第三方账号，已绑定到其他用户时返回ErrLinkConflict
+func (l *LinkStore) Link(platform, externalID, userID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if linkedUserID, exists := l.links[platform][externalID]; exists {
+		if linkedUserID == userID {
+			return nil
+		}
+		return ErrLinkConflict
+	}
+
+	if l.links[platform] == nil {
+		l.links[platform] = make(map[string]string)
+	}
+	l.links[platform][externalID] = userID
+	if err := l.saveLocked(); err != nil {
+		delete(l.links[platform], externalID)
+		return err
+	}
+	return nil
+}
+
+// Unlink 解除第三方账号的绑定，返回是否存在该绑定
+func (l *LinkStore) Unlink(platform, externalID string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, exists := l.links[platform][externalID]; !exists {
+		return false, nil
+	}
+	delete(l.links[platform], externalID)
+	return true, l.saveLocked()
+}
+
+// UnlinkUser 解除用户在平台上的所有绑定，返回解除的数量
+func (l *LinkStore) UnlinkUser(platform, userID string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	removed := 0
+	for externalID, linkedUserID := range l.links[platform] {
+		if linkedUserID == userID {
+			delete(l.links[platform], externalID)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, l.saveLocked()
+}
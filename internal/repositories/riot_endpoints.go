@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Riot各服务的默认地址，分片相关的地址使用{shard}占位符
+	DefaultRiotAuthURL         = "https://auth.riotgames.com"
+	DefaultRiotEntitlementsURL = "https://entitlements.auth.riotgames.com"
+	DefaultRiotPDURL           = "https://pd.{shard}.a.pvp.net"
+	DefaultRiotSharedURL       = "https://shared.{shard}.a.pvp.net"
+
+	// RiotShardPlaceholder 分片地址中的分片占位符，如na、eu、ap、kr
+	RiotShardPlaceholder = "{shard}"
+)
+
+// RiotEndpoints Riot接口地址的注册表，所有请求地址都由这里构建
+// 将各个地址指向本地的替代服务，就可以在不连接Riot的情况下运行整个后端
+type RiotEndpoints struct {
+	Auth         string            // 登录、授权和用户信息
+	Entitlements string            // 授权令牌（entitlement）
+	PD           string            // 商店、钱包、库存和装备，可以包含{shard}
+	Shared       string            // 内容服务，可以包含{shard}
+	Catalog      string            // valorant-api.com，用于获取客户端版本
+	Shards       map[string]string // 按分片覆盖PD和Shared的地址，键是分片代码
+}
+
+// DefaultRiotEndpoints 返回Riot正式服务的地址
+func DefaultRiotEndpoints() *RiotEndpoints {
+	return &RiotEndpoints{
+		Auth:         DefaultRiotAuthURL,
+		Entitlements: DefaultRiotEntitlementsURL,
+		PD:           DefaultRiotPDURL,
+		Shared:       DefaultRiotSharedURL,
+		Catalog:      DefaultCatalogBaseURL,
+	}
+}
+
+// Validate 检查所有地址是否是有效的http(s)地址
+func (e *RiotEndpoints) Validate() error {
+	bases := map[string]string{
+		"Auth":         e.Auth,
+		"Entitlements": e.Entitlements,
+		"PD":           e.PD,
+		"Shared":       e.Shared,
+		"Catalog":      e.Catalog,
+	}
+	for shard, base := range e.Shards {
+		bases["Shards."+shard] = base
+	}
+
+	for name, base := range bases {
+		u, err := url.Parse(strings.ReplaceAll(base, RiotShardPlaceholder, "na"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Riot接口地址%s无效: %q", name, base)
+		}
+	}
+	return nil
+}
+
+// shardBase 返回分片服务的基础地址，Shards中的地址优先
+func (e *RiotEndpoints) shardBase(template, shard string) string {
+	if base, exists := e.Shards[shard]; exists {
+		return strings.TrimRight(base, "/")
+	}
+	return strings.TrimRight(strings.ReplaceAll(template, RiotShardPlaceholder, shard), "/")
+}
+
+// authURL 拼接认证服务的地址
+func (e *RiotEndpoints) authURL(path string) string {
+	return strings.TrimRight(e.Auth, "/") + path
+}
+
+// pdURL 拼接分片PD服务的地址
+func (e *RiotEndpoints) pdURL(shard, path string) string {
+	return e.shardBase(e.PD, shard) + path
+}
+
+// AuthorizationURL 用户名密码登录和二次验证的地址
+func (e *RiotEndpoints) AuthorizationURL() string {
+	return e.authURL("/api/v1/authorization")
+}
+
+// AuthorizeURL 使用Cookie重新授权的地址，query为不带?的查询参数
+func (e *RiotEndpoints) AuthorizeURL(query string) string {
+	return e.authURL("/authorize?" + query)
+}
+
+// UserInfoURL 用户信息的地址
+func (e *RiotEndpoints) UserInfoURL() string {
+	return e.authURL("/userinfo")
+}
+
+// AuthTokenURL 认证服务令牌接口的地址
+func (e *RiotEndpoints) AuthTokenURL() string {
+	return e.authURL("/api/token/v1")
+}
+
+// EntitlementsTokenURL 获取授权令牌的地址
+func (e *RiotEndpoints) EntitlementsTokenURL() string {
+	return strings.TrimRight(e.Entitlements, "/") + "/api/token/v1"
+}
+
+// StorefrontURL 玩家商店的地址
+func (e *RiotEndpoints) StorefrontURL(shard, puuid string) string {
+	return e.pdURL(shard, "/store/v3/storefront/"+puuid)
+}
+
+// WalletURL 玩家钱包的地址
+func (e *RiotEndpoints) WalletURL(shard, puuid string) string {
+	return e.pdURL(shard, "/store/v1/wallet/"+puuid)
+}
+
+// OffersURL 商店价格表的地址
+func (e *RiotEndpoints) OffersURL(shard string) string {
+	return e.pdURL(shard, "/store/v1/offers/")
+}
+
+// EntitlementItemsURL 玩家拥有的某类物品的地址
+func (e *RiotEndpoints) EntitlementItemsURL(shard, puuid, itemTypeID string) string {
+	return e.pdURL(shard, "/store/v1/entitlements/"+puuid+"/"+itemTypeID)
+}
+
+// PlayerLoadoutURL 玩家装备的地址
+func (e *RiotEndpoints) PlayerLoadoutURL(shard, puuid string) string {
+	return e.pdURL(shard, "/personalization/v2/players/"+puuid+"/playerloadout")
+}
+
+// NameServiceURL 玩家名称服务的地址，用于检测玩家所在的区域
+func (e *RiotEndpoints) NameServiceURL(shard string) string {
+	return e.pdURL(shard, "/name-service/v2/players")
+}
+
+// ContentURL 内容服务的地址
+func (e *RiotEndpoints) ContentURL(shard string) string {
+	return e.shardBase(e.Shared, shard) + "/content-service/v3/content"
+}
+
+// VersionURL 获取最新客户端版本的地址
+func (e *RiotEndpoints) VersionURL() string {
+	return strings.TrimRight(e.Catalog, "/") + "/v1/version"
+}
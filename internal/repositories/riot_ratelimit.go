@@ -0,0 +1,318 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultRiotRateLimit 每个Riot主机每秒允许的请求数
+	DefaultRiotRateLimit = 10.0
+	// DefaultRiotRateBurst 每个Riot主机允许的突发请求数
+	DefaultRiotRateBurst = 20
+
+	// 重试的基础等待时间和最大等待时间
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// retryPolicy 单种Riot请求的重试策略
+type retryPolicy struct {
+	maxRetries    int
+	statuses      []int         // 需要重试的状态码
+	maxRetryAfter time.Duration // Retry-After超过该时间时不再重试，直接返回限流错误
+	// noNetworkRetry 网络错误时不重试：请求可能已经被Riot处理，重复提交密码或验证码不安全
+	noNetworkRetry bool
+}
+
+// defaultRetryPolicy 未单独配置的请求使用的重试策略
+var defaultRetryPolicy = retryPolicy{
+	maxRetries:    2,
+	statuses:      []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	maxRetryAfter: retryMaxDelay,
+}
+
+// riotRetryPolicies 按请求名称配置的重试策略
+// 认证接口的限流最严格，只在Retry-After较短时重试一次；区域检测依次尝试各个分片，不重试
+// 提交密码和验证码的请求发生网络错误时无法确定Riot是否已经处理，不重试
+var riotRetryPolicies = map[string]retryPolicy{
+	RiotCallAuthorization: {maxRetries: 1, statuses: []int{http.StatusTooManyRequests}, maxRetryAfter: 5 * time.Second, noNetworkRetry: true},
+	RiotCallAuthorize:     {maxRetries: 1, statuses: []int{http.StatusTooManyRequests}, maxRetryAfter: 5 * time.Second},
+	RiotCallNameService:   {},
+	RiotCallStorefront:    {maxRetries: 3, statuses: defaultRetryPolicy.statuses, maxRetryAfter: retryMaxDelay},
+	RiotCallWallet:        {maxRetries: 3, statuses: defaultRetryPolicy.statuses, maxRetryAfter: retryMaxDelay},
+}
+
+// retryPolicyFor 返回指定请求的重试策略
+func retryPolicyFor(call string) retryPolicy {
+	if policy, exists := riotRetryPolicies[call]; exists {
+		return policy
+	}
+	return defaultRetryPolicy
+}
+
+// send 发送Riot请求，按该请求的重试策略处理网络错误、429和5xx
+// 每次尝试前经过所在主机的限流器，最终仍被限流时返回*RateLimitError
+// 请求体必须支持GetBody（bytes.Buffer、bytes.Reader、strings.Reader），否则无法重试
+// 超时时间包括所有重试，剩余时间不够等待下一次重试时直接返回最后一次的结果，而不是等到超时
+func (v *ValorantAPI) send(client *http.Client, call string, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	policy := retryPolicyFor(call)
+	host := req.URL.Host
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// 请求体已在上一次尝试中读取，需要重新生成
+			retry := req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("重新生成请求体失败: %w", err)
+				}
+				retry.Body = body
+			}
+			req = retry
+		}
+
+		if err := v.limiter.wait(ctx, host); err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			// 请求被取消或超时，重试没有意义
+			if ctx.Err() != nil || !isNetworkError(err) {
+				return nil, err
+			}
+			if policy.noNetworkRetry || attempt >= policy.maxRetries || !fitsDeadline(ctx, backoff(attempt)) {
+				return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+			}
+			fmt.Printf("请求 %s %s 网络错误: %v，将重试\n", req.Method, req.URL.Path, err)
+			if err := sleepContext(ctx, backoff(attempt)); err != nil {
+				return nil, fmt.Errorf("请求已取消，放弃重试: %w", err)
+			}
+			continue
+		}
+
+		retryAfter, hasRetryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		wait := backoff(attempt)
+		if hasRetryAfter {
+			wait = retryAfter
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			// 429对所有用户生效，在等待时间内暂停该主机的所有请求
+			v.limiter.pause(host, wait)
+		}
+
+		retryable := slices.Contains(policy.statuses, resp.StatusCode) && attempt < policy.maxRetries && wait <= policy.maxRetryAfter
+		if retryable && fitsDeadline(ctx, wait) {
+			drainBody(resp)
+			fmt.Printf("请求 %s %s 返回状态码 %d，%v后第%d次重试\n", req.Method, req.URL.Path, resp.StatusCode, wait, attempt+1)
+			// 429的等待由限流器完成，其他状态码在这里等待
+			if resp.StatusCode != http.StatusTooManyRequests {
+				if err := sleepContext(ctx, wait); err != nil {
+					return nil, fmt.Errorf("请求已取消，放弃重试: %w", err)
+				}
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			drainBody(resp)
+			fmt.Printf("请求 %s %s 被Riot限流，%v后可以重试\n", req.Method, req.URL.Path, wait)
+			return nil, &RateLimitError{Host: host, RetryAfter: wait}
+		}
+		return resp, nil
+	}
+}
+
+// backoff 第attempt次失败后的指数退避时间
+func backoff(attempt int) time.Duration {
+	wait := retryBaseDelay << uint(attempt)
+	if wait > retryMaxDelay || wait <= 0 {
+		return retryMaxDelay
+	}
+	return wait
+}
+
+// fitsDeadline 判断ctx的剩余时间是否足够等待d后再发起一次请求
+func fitsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, exists := ctx.Deadline()
+	return !exists || time.Until(deadline) > d
+}
+
+// sleepContext 等待指定时间，ctx取消时立即返回ctx的错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter 解析Retry-After响应头，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// urlHost 返回地址中的主机，用于限流和错误信息
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// drainBody 读取并关闭不再使用的响应体，使连接可以复用
+func drainBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// isNetworkError 检查是否为可以重试的网络错误（连接失败、连接被重置、网络超时）
+// 请求被取消或超过ctx的期限不算网络错误
+func isNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// rateLimiter 按Riot主机限流的令牌桶，在所有用户之间共享
+// Riot返回429后在Retry-After内暂停该主机的所有请求
+type rateLimiter struct {
+	mutex sync.Mutex
+	rate  float64 // 每秒补充的令牌数，为0时不限流
+	burst float64
+	hosts map[string]*hostBucket
+}
+
+// hostBucket 单个主机的令牌桶
+type hostBucket struct {
+	tokens      float64
+	updatedAt   time.Time
+	pausedUntil time.Time
+}
+
+// newRateLimiter 创建限流器，rate为0时只处理429的暂停
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:  rate,
+		burst: float64(max(burst, 1)),
+		hosts: make(map[string]*hostBucket),
+	}
+}
+
+// bucket 返回主机的令牌桶并补充令牌，调用方需要持有锁
+func (l *rateLimiter) bucket(host string, now time.Time) *hostBucket {
+	bucket, exists := l.hosts[host]
+	if !exists {
+		bucket = &hostBucket{tokens: l.burst, updatedAt: now}
+		l.hosts[host] = bucket
+	}
+	if l.rate > 0 {
+		bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*l.rate)
+	}
+	bucket.updatedAt = now
+	return bucket
+}
+
+// reserve 尝试取出一个令牌，失败时返回需要等待的时间和是否因429暂停
+func (l *rateLimiter) reserve(host string) (time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket := l.bucket(host, now)
+	if now.Before(bucket.pausedUntil) {
+		return bucket.pausedUntil.Sub(now), true
+	}
+	if l.rate <= 0 {
+		return 0, false
+	}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, false
+	}
+	return time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second)), false
+}
+
+// wait 等待主机有可用的令牌
+// 需要等待的时间超过ctx的期限时立即返回*RateLimitError，ctx取消时返回ctx的错误
+func (l *rateLimiter) wait(ctx context.Context, host string) error {
+	for {
+		wait, paused := l.reserve(host)
+		if wait <= 0 {
+			return nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			if !paused {
+				fmt.Printf("主机 %s 的请求过多，本地限流\n", host)
+			}
+			return &RateLimitError{Host: host, RetryAfter: wait}
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// pause 在指定时间内暂停主机的所有请求
+func (l *rateLimiter) pause(host string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket := l.bucket(host, now)
+	if until := now.Add(d); until.After(bucket.pausedUntil) {
+		bucket.pausedUntil = until
+	}
+}
@@ -0,0 +1,183 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/mockriot"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// newMockSession 启动模拟Riot服务并登录，返回模拟服务、指向它的ValorantAPI和会话
+func newMockSession(t *testing.T) (*mockriot.Server, *repositories.ValorantAPI, *models.UserSession) {
+	t.Helper()
+
+	mock, err := mockriot.New(&mockriot.Fixture{
+		Accounts: []mockriot.Account{{Username: "demo", Password: "demo", PUUID: "puuid-demo", Region: "eu"}},
+	})
+	if err != nil {
+		t.Fatalf("创建模拟服务失败: %v", err)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+
+	api := repositories.NewValorantAPIWithTransport(server.Client().Transport, "release-mock", mockriot.Endpoints(server.URL))
+	api.SetRateLimit(0, 1)
+
+	session, _, err := api.Authenticate(t.Context(), "demo", "demo")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	return mock, api, session
+}
+
+// getStore 请求商店并返回耗时
+func getStore(t *testing.T, api *repositories.ValorantAPI, session *models.UserSession) (time.Duration, error) {
+	t.Helper()
+
+	start := time.Now()
+	_, err := api.GetStoreOffers(t.Context(), repositories.SessionAuth(session), session.UserID)
+	return time.Since(start), err
+}
+
+func TestSendRetriesServerErrors(t *testing.T) {
+	mock, api, session := newMockSession(t)
+	if err := mock.AddFault(mockriot.Fault{Endpoint: mockriot.EndpointStorefront, Status: http.StatusServiceUnavailable, Count: 1}); err != nil {
+		t.Fatalf("注入错误失败: %v", err)
+	}
+
+	elapsed, err := getStore(t, api, session)
+	if err != nil {
+		t.Fatalf("503后重试应当成功: %v", err)
+	}
+	if elapsed < 500*time.Millisecond {
+		t.Fatalf("重试前应当按指数退避等待，实际耗时 %v", elapsed)
+	}
+	if faults := mock.Faults(); len(faults) != 0 {
+		t.Fatalf("注入的错误应当已被使用，剩余 %+v", faults)
+	}
+}
+
+func TestSendWaitsForRetryAfter(t *testing.T) {
+	mock, api, session := newMockSession(t)
+	if err := mock.AddFault(mockriot.Fault{Endpoint: mockriot.EndpointStorefront, Status: http.StatusTooManyRequests, RetryAfter: 1, Count: 1}); err != nil {
+		t.Fatalf("注入错误失败: %v", err)
+	}
+
+	elapsed, err := getStore(t, api, session)
+	if err != nil {
+		t.Fatalf("429后重试应当成功: %v", err)
+	}
+	if elapsed < time.Second {
+		t.Fatalf("重试前应当等待Retry-After，实际耗时 %v", elapsed)
+	}
+}
+
+func TestSendReturnsRateLimitErrorForLongRetryAfter(t *testing.T) {
+	mock, api, session := newMockSession(t)
+	if err := mock.AddFault(mockriot.Fault{Endpoint: mockriot.EndpointStorefront, Status: http.StatusTooManyRequests, RetryAfter: 60, Count: 2}); err != nil {
+		t.Fatalf("注入错误失败: %v", err)
+	}
+
+	_, err := getStore(t, api, session)
+	var rateLimitErr *repositories.RateLimitError
+	if !errors.Is(err, repositories.ErrRateLimited) || !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Retry-After过长时应当返回RateLimitError，得到 %v", err)
+	}
+	if rateLimitErr.RetryAfter != 60*time.Second {
+		t.Fatalf("RetryAfter应当为60s，得到 %v", rateLimitErr.RetryAfter)
+	}
+
+	// 只请求了一次，没有重试
+	if faults := mock.Faults(); len(faults) != 1 || faults[0].Count != 1 {
+		t.Fatalf("不应重试，剩余的错误: %+v", faults)
+	}
+
+	// 暂停时间超过请求的超时时间，同一主机的其他请求直接返回限流错误
+	if _, err := api.GetWallet(t.Context(), repositories.SessionAuth(session), session.UserID); !errors.Is(err, repositories.ErrRateLimited) {
+		t.Fatalf("主机暂停期间应当返回ErrRateLimited，得到 %v", err)
+	}
+}
+
+func TestSendStopsRetryingBeforeDeadline(t *testing.T) {
+	mock, api, session := newMockSession(t)
+	api.SetTimeouts(repositories.RiotTimeouts{Calls: map[string]time.Duration{repositories.RiotCallStorefront: 800 * time.Millisecond}})
+	if err := mock.AddFault(mockriot.Fault{Endpoint: mockriot.EndpointStorefront, Status: http.StatusServiceUnavailable}); err != nil {
+		t.Fatalf("注入错误失败: %v", err)
+	}
+
+	// 第一次退避500ms可以完成，第二次退避1s超过剩余时间，返回最后一次的503而不是超时
+	elapsed, err := getStore(t, api, session)
+	if !errors.Is(err, repositories.ErrUpstreamUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("持续的5xx应当返回ErrUpstreamUnavailable，得到 %v", err)
+	}
+	var statusErr *repositories.RiotStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("应当保留最后一次的状态码，得到 %v", err)
+	}
+	if elapsed >= 800*time.Millisecond {
+		t.Fatalf("剩余时间不够时应当立即返回，实际耗时 %v", elapsed)
+	}
+}
+
+// dropConnections 对匹配的请求直接断开连接，模拟网络错误，其他请求交给handler
+type dropConnections struct {
+	handler http.Handler
+	method  string
+	path    string
+
+	mutex   sync.Mutex
+	dropped int
+}
+
+func (d *dropConnections) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != d.method || r.URL.Path != d.path {
+		d.handler.ServeHTTP(w, r)
+		return
+	}
+
+	d.mutex.Lock()
+	d.dropped++
+	d.mutex.Unlock()
+
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+// count 返回已断开的请求数
+func (d *dropConnections) count() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.dropped
+}
+
+func TestSendDoesNotRetryAuthorizationNetworkErrors(t *testing.T) {
+	mock, err := mockriot.New(&mockriot.Fixture{
+		Accounts: []mockriot.Account{{Username: "demo", Password: "demo", PUUID: "puuid-demo", Region: "eu"}},
+	})
+	if err != nil {
+		t.Fatalf("创建模拟服务失败: %v", err)
+	}
+	drop := &dropConnections{handler: mock, method: http.MethodPut, path: "/auth/api/v1/authorization"}
+	server := httptest.NewServer(drop)
+	t.Cleanup(server.Close)
+
+	api := repositories.NewValorantAPIWithTransport(server.Client().Transport, "release-mock", mockriot.Endpoints(server.URL))
+	api.SetRateLimit(0, 1)
+
+	// 提交密码时连接断开，Riot可能已经处理了该请求，不能重复提交
+	if _, _, err := api.Authenticate(t.Context(), "demo", "demo"); !errors.Is(err, repositories.ErrUpstreamUnavailable) {
+		t.Fatalf("网络错误应当返回ErrUpstreamUnavailable，得到 %v", err)
+	}
+	if dropped := drop.count(); dropped != 1 {
+		t.Fatalf("提交密码的请求不应重试，实际发送%d次", dropped)
+	}
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Riot请求的名称，用于按接口配置超时
+const (
+	RiotCallAuthorization = "authorization" // 登录和二次验证
+	RiotCallAuthorize     = "authorize"     // Cookie重新授权
+	RiotCallUserInfo      = "userinfo"
+	RiotCallEntitlements  = "entitlements" // 授权令牌
+	RiotCallNameService   = "name-service" // 区域检测
+	RiotCallStorefront    = "storefront"
+	RiotCallWallet        = "wallet"
+	RiotCallOffers        = "offers"
+	RiotCallOwnedItems    = "owned-items"
+	RiotCallLoadout       = "loadout"
+	RiotCallContent       = "content"
+)
+
+// DefaultRiotTimeout 未单独配置的Riot请求的超时时间
+const DefaultRiotTimeout = 30 * time.Second
+
+// riotCalls 所有可以配置超时的请求名称
+var riotCalls = map[string]bool{
+	RiotCallAuthorization: true,
+	RiotCallAuthorize:     true,
+	RiotCallUserInfo:      true,
+	RiotCallEntitlements:  true,
+	RiotCallNameService:   true,
+	RiotCallStorefront:    true,
+	RiotCallWallet:        true,
+	RiotCallOffers:        true,
+	RiotCallOwnedItems:    true,
+	RiotCallLoadout:       true,
+	RiotCallContent:       true,
+}
+
+// RiotTimeouts 每次Riot请求的超时时间，包括该次请求的所有重试
+type RiotTimeouts struct {
+	Default time.Duration            // 为0时使用DefaultRiotTimeout
+	Calls   map[string]time.Duration // 按请求名称覆盖超时时间
+}
+
+// Validate 检查请求名称和超时时间是否有效
+func (t RiotTimeouts) Validate() error {
+	if t.Default < 0 {
+		return fmt.Errorf("Riot请求的默认超时时间不能为负数: %v", t.Default)
+	}
+
+	calls := make([]string, 0, len(t.Calls))
+	for call := range t.Calls {
+		calls = append(calls, call)
+	}
+	sort.Strings(calls)
+
+	for _, call := range calls {
+		if !riotCalls[call] {
+			return fmt.Errorf("未知的Riot请求名称: %s", call)
+		}
+		if t.Calls[call] <= 0 {
+			return fmt.Errorf("Riot请求%s的超时时间必须大于0: %v", call, t.Calls[call])
+		}
+	}
+	return nil
+}
+
+// forCall 返回指定请求的超时时间
+func (t RiotTimeouts) forCall(call string) time.Duration {
+	if timeout, exists := t.Calls[call]; exists {
+		return timeout
+	}
+	if t.Default > 0 {
+		return t.Default
+	}
+	return DefaultRiotTimeout
+}
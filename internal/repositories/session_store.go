@@ -0,0 +1,447 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// 会话存储类型
+	SessionStoreMemory = "memory" // 仅内存，重启后丢失
+	SessionStoreFile   = "file"   // JSON文件，保存在数据目录下
+	SessionStoreBolt   = "bolt"   // 嵌入式数据库(bbolt)，保存在数据目录下
+
+	sessionsFileName = "sessions.json"
+	sessionsDBName   = "sessions.db"
+	sessionsBucket   = "sessions"
+)
+
+// SessionStore 用户Riot会话的存储接口（UserID -> UserSession）
+// 所有实现都必须是并发安全的，并且返回会话的副本，调用方修改后需通过Put写回
+type SessionStore interface {
+	// Get 获取会话，不存在或已过期时返回false
+	Get(userID string) (*models.UserSession, bool)
+	// Put 保存或覆盖会话
+	Put(userID string, session *models.UserSession) error
+	// Delete 删除会话
+	Delete(userID string) error
+	// List 列出所有未过期的会话
+	List() ([]*models.UserSession, error)
+	// Expire 删除在指定时间之前过期的会话，返回删除的数量
+	Expire(now time.Time) (int, error)
+	// Close 释放存储占用的资源
+	Close() error
+}
+
+// NewSessionStore 根据存储类型创建会话存储
+func NewSessionStore(kind, dataPath string) (SessionStore, error) {
+	switch kind {
+	case SessionStoreMemory:
+		return NewMemorySessionStore(), nil
+	case SessionStoreFile, "":
+		return NewFileSessionStore(filepath.Join(dataPath, sessionsFileName))
+	case SessionStoreBolt:
+		return NewBoltSessionStore(filepath.Join(dataPath, sessionsDBName))
+	default:
+		return nil, fmt.Errorf("不支持的会话存储类型: %s", kind)
+	}
+}
+
+// storedSession 持久化时使用的会话结构，UserSession中的Cookies不参与JSON序列化，这里需要单独保存
+type storedSession struct {
+	models.UserSession
+	Cookies map[string]string `json:"cookies,omitempty"`
+}
+
+// cloneSession 复制会话，避免调用方与存储共享同一个对象
+func cloneSession(session *models.UserSession) *models.UserSession {
+	clone := *session
+	if session.Cookies != nil {
+		clone.Cookies = make(map[string]string, len(session.Cookies))
+		for name, value := range session.Cookies {
+			clone.Cookies[name] = value
+		}
+	}
+	return &clone
+}
+
+// isSessionExpired 检查会话是否已过期
+func isSessionExpired(session *models.UserSession, now time.Time) bool {
+	return session.ExpiresAt > 0 && now.Unix() >= session.ExpiresAt
+}
+
+// encodeSession 将会话序列化为JSON（包含Cookie）
+func encodeSession(session *models.UserSession) ([]byte, error) {
+	return json.Marshal(storedSession{UserSession: *session, Cookies: session.Cookies})
+}
+
+// decodeSession 从JSON反序列化会话
+func decodeSession(data []byte) (*models.UserSession, error) {
+	var stored storedSession
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	session := stored.UserSession
+	session.Cookies = stored.Cookies
+	return &session, nil
+}
+
+// MemorySessionStore 基于内存的会话存储
+type MemorySessionStore struct {
+	sessions map[string]*models.UserSession
+	mutex    sync.RWMutex
+}
+
+// NewMemorySessionStore 创建内存会话存储
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]*models.UserSession),
+	}
+}
+
+// Get 获取会话
+func (m *MemorySessionStore) Get(userID string) (*models.UserSession, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	session, exists := m.sessions[userID]
+	if !exists || isSessionExpired(session, time.Now()) {
+		return nil, false
+	}
+	return cloneSession(session), true
+}
+
+// Put 保存会话
+func (m *MemorySessionStore) Put(userID string, session *models.UserSession) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sessions[userID] = cloneSession(session)
+	return nil
+}
+
+// Delete 删除会话
+func (m *MemorySessionStore) Delete(userID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.sessions, userID)
+	return nil
+}
+
+// List 列出所有未过期的会话
+func (m *MemorySessionStore) List() ([]*models.UserSession, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	now := time.Now()
+	sessions := make([]*models.UserSession, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		if !isSessionExpired(session, now) {
+			sessions = append(sessions, cloneSession(session))
+		}
+	}
+	return sessions, nil
+}
+
+// Expire 删除已过期的会话
+func (m *MemorySessionStore) Expire(now time.Time) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	removed := 0
+	for userID, session := range m.sessions {
+		if isSessionExpired(session, now) {
+			delete(m.sessions, userID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Close 内存存储无需释放资源
+func (m *MemorySessionStore) Close() error {
+	return nil
+}
+
+// FileSessionStore 基于JSON文件的会话存储
+// 所有会话保存在内存中，每次修改后整体写回文件
+type FileSessionStore struct {
+	sessions map[string]*models.UserSession
+	filePath string
+	mutex    sync.RWMutex
+}
+
+// NewFileSessionStore 创建文件会话存储，并加载已有的会话文件
+func NewFileSessionStore(filePath string) (*FileSessionStore, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+
+	store := &FileSessionStore{
+		sessions: make(map[string]*models.UserSession),
+		filePath: absPath,
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		// 文件不存在不是错误
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("读取会话文件失败: %w", err)
+	}
+
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("解析会话文件失败: %w", err)
+	}
+
+	for userID, raw := range stored {
+		session, err := decodeSession(raw)
+		if err != nil {
+			return nil, fmt.Errorf("解析用户 %s 的会话失败: %w", userID, err)
+		}
+		store.sessions[userID] = session
+	}
+
+	return store, nil
+}
+
+// saveLocked 将所有会话写回文件，调用方需持有写锁
+func (f *FileSessionStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(f.filePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	stored := make(map[string]storedSession, len(f.sessions))
+	for userID, session := range f.sessions {
+		stored[userID] = storedSession{UserSession: *session, Cookies: session.Cookies}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := f.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("写入会话文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, f.filePath); err != nil {
+		return fmt.Errorf("替换会话文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// Get 获取会话
+func (f *FileSessionStore) Get(userID string) (*models.UserSession, bool) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	session, exists := f.sessions[userID]
+	if !exists || isSessionExpired(session, time.Now()) {
+		return nil, false
+	}
+	return cloneSession(session), true
+}
+
+// Put 保存会话并写回文件
+func (f *FileSessionStore) Put(userID string, session *models.UserSession) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.sessions[userID] = cloneSession(session)
+	return f.saveLocked()
+}
+
+// Delete 删除会话并写回文件
+func (f *FileSessionStore) Delete(userID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, exists := f.sessions[userID]; !exists {
+		return nil
+	}
+	delete(f.sessions, userID)
+	return f.saveLocked()
+}
+
+// List 列出所有未过期的会话
+func (f *FileSessionStore) List() ([]*models.UserSession, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	now := time.Now()
+	sessions := make([]*models.UserSession, 0, len(f.sessions))
+	for _, session := range f.sessions {
+		if !isSessionExpired(session, now) {
+			sessions = append(sessions, cloneSession(session))
+		}
+	}
+	return sessions, nil
+}
+
+// Expire 删除已过期的会话
+func (f *FileSessionStore) Expire(now time.Time) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	removed := 0
+	for userID, session := range f.sessions {
+		if isSessionExpired(session, now) {
+			delete(f.sessions, userID)
+			removed++
+		}
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, f.saveLocked()
+}
+
+// Close 文件存储在每次修改时已写回，无需额外处理
+func (f *FileSessionStore) Close() error {
+	return nil
+}
+
+// BoltSessionStore 基于bbolt嵌入式数据库的会话存储
+type BoltSessionStore struct {
+	db *bolt.DB
+}
+
+// NewBoltSessionStore 打开（或创建）会话数据库
+func NewBoltSessionStore(filePath string) (*BoltSessionStore, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	db, err := bolt.Open(filePath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("打开会话数据库失败: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(sessionsBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化会话数据库失败: %w", err)
+	}
+
+	return &BoltSessionStore{db: db}, nil
+}
+
+// Get 获取会话
+func (b *BoltSessionStore) Get(userID string) (*models.UserSession, bool) {
+	var session *models.UserSession
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(sessionsBucket)).Get([]byte(userID))
+		if data == nil {
+			return nil
+		}
+		var err error
+		session, err = decodeSession(data)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("读取用户 %s 的会话失败: %v\n", userID, err)
+		return nil, false
+	}
+
+	if session == nil || isSessionExpired(session, time.Now()) {
+		return nil, false
+	}
+	return session, true
+}
+
+// Put 保存会话
+func (b *BoltSessionStore) Put(userID string, session *models.UserSession) error {
+	data, err := encodeSession(session)
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %w", err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(sessionsBucket)).Put([]byte(userID), data)
+	})
+}
+
+// Delete 删除会话
+func (b *BoltSessionStore) Delete(userID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(sessionsBucket)).Delete([]byte(userID))
+	})
+}
+
+// List 列出所有未过期的会话
+func (b *BoltSessionStore) List() ([]*models.UserSession, error) {
+	now := time.Now()
+	sessions := make([]*models.UserSession, 0)
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(sessionsBucket)).ForEach(func(key, data []byte) error {
+			session, err := decodeSession(data)
+			if err != nil {
+				return fmt.Errorf("解析用户 %s 的会话失败: %w", string(key), err)
+			}
+			if !isSessionExpired(session, now) {
+				sessions = append(sessions, session)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Expire 删除已过期的会话
+func (b *BoltSessionStore) Expire(now time.Time) (int, error) {
+	removed := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sessionsBucket))
+
+		// 先收集需要删除的键，遍历过程中不能修改bucket
+		var expiredKeys [][]byte
+		err := bucket.ForEach(func(key, data []byte) error {
+			session, err := decodeSession(data)
+			if err != nil || isSessionExpired(session, now) {
+				expiredKeys = append(expiredKeys, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expiredKeys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		removed = len(expiredKeys)
+		return nil
+	})
+
+	return removed, err
+}
+
+// Close 关闭数据库
+func (b *BoltSessionStore) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,205 @@
+package repositories_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+var sessionStoreKinds = []string{
+	repositories.SessionStoreMemory,
+	repositories.SessionStoreFile,
+	repositories.SessionStoreBolt,
+}
+
+// openSessionStore 在dataPath中打开指定类型的会话存储，测试结束时关闭
+func openSessionStore(t *testing.T, kind, dataPath string) repositories.SessionStore {
+	t.Helper()
+
+	store, err := repositories.NewSessionStore(kind, dataPath)
+	if err != nil {
+		t.Fatalf("打开%s会话存储失败: %v", kind, err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testSession 创建一个包含Cookie、在一小时后过期的会话
+func testSession(userID string) *models.UserSession {
+	return &models.UserSession{
+		UserID:             userID,
+		Username:           "player-" + userID,
+		AccessToken:        "access-" + userID,
+		Entitlement:        "entitlement-" + userID,
+		Region:             models.RegionEU,
+		RiotUsername:       "Player",
+		RiotTagline:        "TEST",
+		Cookies:            map[string]string{"ssid": "ssid-" + userID, "clid": "ec1"},
+		RiotTokenExpiresAt: time.Now().Add(time.Hour).Unix(),
+		ExpiresAt:          time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestSessionStoreRoundTrip(t *testing.T) {
+	for _, kind := range sessionStoreKinds {
+		t.Run(kind, func(t *testing.T) {
+			store := openSessionStore(t, kind, t.TempDir())
+
+			if _, exists := store.Get("missing"); exists {
+				t.Fatal("不存在的会话不应返回")
+			}
+
+			session := testSession("user-1")
+			if err := store.Put(session.UserID, session); err != nil {
+				t.Fatalf("保存会话失败: %v", err)
+			}
+			got, exists := store.Get(session.UserID)
+			if !exists {
+				t.Fatal("保存后应当能读取会话")
+			}
+			if !reflect.DeepEqual(got, session) {
+				t.Fatalf("读取的会话与保存的不一致:\n得到 %+v\n期望 %+v", got, session)
+			}
+
+			// 覆盖已有的会话
+			session.AccessToken = "access-rotated"
+			if err := store.Put(session.UserID, session); err != nil {
+				t.Fatalf("覆盖会话失败: %v", err)
+			}
+			if got, _ := store.Get(session.UserID); got.AccessToken != "access-rotated" {
+				t.Fatalf("覆盖后应当读取到新的令牌，得到 %q", got.AccessToken)
+			}
+
+			if err := store.Delete(session.UserID); err != nil {
+				t.Fatalf("删除会话失败: %v", err)
+			}
+			if _, exists := store.Get(session.UserID); exists {
+				t.Fatal("删除后不应再返回会话")
+			}
+		})
+	}
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+	for _, kind := range sessionStoreKinds {
+		t.Run(kind, func(t *testing.T) {
+			store := openSessionStore(t, kind, t.TempDir())
+
+			active := testSession("active")
+			expired := testSession("expired")
+			expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+			permanent := testSession("permanent")
+			permanent.ExpiresAt = 0 // 没有过期时间的会话不会过期
+			for _, session := range []*models.UserSession{active, expired, permanent} {
+				if err := store.Put(session.UserID, session); err != nil {
+					t.Fatalf("保存会话失败: %v", err)
+				}
+			}
+
+			if _, exists := store.Get(expired.UserID); exists {
+				t.Fatal("已过期的会话不应返回")
+			}
+			sessions, err := store.List()
+			if err != nil {
+				t.Fatalf("列出会话失败: %v", err)
+			}
+			if len(sessions) != 2 {
+				t.Fatalf("应当只列出2个未过期的会话，得到%d个", len(sessions))
+			}
+
+			removed, err := store.Expire(time.Now())
+			if err != nil || removed != 1 {
+				t.Fatalf("应当删除1个过期的会话，得到 %d, %v", removed, err)
+			}
+
+			// 指定的时间之后active也过期，permanent仍然保留
+			removed, err = store.Expire(time.Now().Add(2 * time.Hour))
+			if err != nil || removed != 1 {
+				t.Fatalf("应当删除1个过期的会话，得到 %d, %v", removed, err)
+			}
+			if _, exists := store.Get(permanent.UserID); !exists {
+				t.Fatal("没有过期时间的会话不应被删除")
+			}
+		})
+	}
+}
+
+func TestSessionStoreReturnsClones(t *testing.T) {
+	for _, kind := range sessionStoreKinds {
+		t.Run(kind, func(t *testing.T) {
+			store := openSessionStore(t, kind, t.TempDir())
+
+			session := testSession("user-1")
+			if err := store.Put(session.UserID, session); err != nil {
+				t.Fatalf("保存会话失败: %v", err)
+			}
+
+			// 保存之后修改传入的会话不影响存储
+			session.AccessToken = "changed-after-put"
+			session.Cookies["ssid"] = "changed-after-put"
+
+			// 修改读取的会话不影响存储
+			got, _ := store.Get(session.UserID)
+			got.AccessToken = "changed-after-get"
+			got.Cookies["ssid"] = "changed-after-get"
+
+			listed, err := store.List()
+			if err != nil || len(listed) != 1 {
+				t.Fatalf("列出会话失败: %v", err)
+			}
+			listed[0].Cookies["ssid"] = "changed-after-list"
+
+			stored, _ := store.Get(session.UserID)
+			if stored.AccessToken != "access-user-1" || stored.Cookies["ssid"] != "ssid-user-1" {
+				t.Fatalf("存储中的会话被外部修改: %+v", stored)
+			}
+		})
+	}
+}
+
+func TestSessionStorePersistsAcrossReopen(t *testing.T) {
+	for _, kind := range []string{repositories.SessionStoreFile, repositories.SessionStoreBolt} {
+		t.Run(kind, func(t *testing.T) {
+			dataPath := t.TempDir()
+
+			store, err := repositories.NewSessionStore(kind, dataPath)
+			if err != nil {
+				t.Fatalf("打开会话存储失败: %v", err)
+			}
+			kept := testSession("kept")
+			deleted := testSession("deleted")
+			for _, session := range []*models.UserSession{kept, deleted} {
+				if err := store.Put(session.UserID, session); err != nil {
+					t.Fatalf("保存会话失败: %v", err)
+				}
+			}
+			if err := store.Delete(deleted.UserID); err != nil {
+				t.Fatalf("删除会话失败: %v", err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatalf("关闭会话存储失败: %v", err)
+			}
+
+			reopened := openSessionStore(t, kind, dataPath)
+			got, exists := reopened.Get(kept.UserID)
+			if !exists {
+				t.Fatal("重新打开后应当能读取保存的会话")
+			}
+			if !reflect.DeepEqual(got, kept) {
+				t.Fatalf("重新打开后的会话与保存的不一致（包括Cookie）:\n得到 %+v\n期望 %+v", got, kept)
+			}
+			if _, exists := reopened.Get(deleted.UserID); exists {
+				t.Fatal("删除的会话在重新打开后不应出现")
+			}
+		})
+	}
+}
+
+func TestNewSessionStoreRejectsUnknownKind(t *testing.T) {
+	if _, err := repositories.NewSessionStore("redis", t.TempDir()); err == nil {
+		t.Fatal("不支持的存储类型应当返回错误")
+	}
+}
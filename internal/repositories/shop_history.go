@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+const (
+	// ShopHistoryDirName 商店历史在数据目录下的目录名，每个账号一个JSON文件
+	ShopHistoryDirName = "shop_history"
+)
+
+// ErrShopSnapshotNotFound 指定日期没有商店快照
+var ErrShopSnapshotNotFound = errors.New("商店快照不存在")
+
+// historyUserIDPattern 用户ID会作为文件名，只允许安全字符
+var historyUserIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ShopHistoryStore 按Riot账号保存每日商店快照
+// 快照按日期升序保存在 <dir>/<userID>.json 中
+type ShopHistoryStore struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+// NewShopHistoryStore 创建商店历史存储
+func NewShopHistoryStore(dir string) (*ShopHistoryStore, error) {
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+
+	if err := os.MkdirAll(absPath, 0755); err != nil {
+		return nil, fmt.Errorf("创建商店历史目录失败: %w", err)
+	}
+
+	return &ShopHistoryStore{dir: absPath}, nil
+}
+
+// userFile 返回账号对应的快照文件路径
+func (s *ShopHistoryStore) userFile(userID string) (string, error) {
+	if !historyUserIDPattern.MatchString(userID) {
+		return "", fmt.Errorf("无效的用户ID: %q", userID)
+	}
+	return filepath.Join(s.dir, userID+".json"), nil
+}
+
+// loadLocked 读取账号的所有快照，调用方需持有锁
+func (s *ShopHistoryStore) loadLocked(userID string) ([]models.ShopSnapshot, error) {
+	path, err := s.userFile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// 文件不存在表示还没有历史
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取商店历史失败: %w", err)
+	}
+
+	var snapshots []models.ShopSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("解析商店历史失败: %w", err)
+	}
+	return snapshots, nil
+}
+
+// saveLocked 将账号的所有快照写回文件，调用方需持有写锁
+func (s *ShopHistoryStore) saveLocked(userID string, snapshots []models.ShopSnapshot) error {
+	path, err := s.userFile(userID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return fmt.Errorf("序列化商店历史失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入商店历史失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("替换商店历史文件失败: %w", err)
+	}
+	return nil
+}
+
+// Save 保存快照，同一日期已有快照时覆盖内容并保留首次记录时间
+func (s *ShopHistoryStore) Save(userID string, snapshot models.ShopSnapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshots, err := s.loadLocked(userID)
+	if err != nil {
+		return err
+	}
+
+	index := sort.Search(len(snapshots), func(i int) bool {
+		return snapshots[i].Date >= snapshot.Date
+	})
+	if index < len(snapshots) && snapshots[index].Date == snapshot.Date {
+		snapshot.RecordedAt = snapshots[index].RecordedAt
+		snapshots[index] = snapshot
+	} else {
+		snapshots = append(snapshots, models.ShopSnapshot{})
+		copy(snapshots[index+1:], snapshots[index:])
+		snapshots[index] = snapshot
+	}
+
+	return s.saveLocked(userID, snapshots)
+}
+
+// List 按日期从新到旧返回账号在[from, to]范围内的快照，from或to为空表示不限制
+func (s *ShopHistoryStore) List(userID, from, to string) ([]models.ShopSnapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshots, err := s.loadLocked(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 日期格式固定为YYYY-MM-DD，可以直接按字符串比较
+	result := make([]models.ShopSnapshot, 0, len(snapshots))
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		date := snapshots[i].Date
+		if (from != "" && date < from) || (to != "" && date > to) {
+			continue
+		}
+		result = append(result, snapshots[i])
+	}
+	return result, nil
+}
+
+// Get 获取账号在指定日期的快照，不存在时返回ErrShopSnapshotNotFound
+func (s *ShopHistoryStore) Get(userID, date string) (*models.ShopSnapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshots, err := s.loadLocked(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range snapshots {
+		if snapshots[i].Date == date {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, ErrShopSnapshotNotFound
+}
+
+// Latest 获取账号最新的快照，没有历史时返回nil
+func (s *ShopHistoryStore) Latest(userID string) (*models.ShopSnapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshots, err := s.loadLocked(userID)
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	return &snapshots[len(snapshots)-1], nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,11 +15,17 @@ import (
 const (
 	// SkinsDBPath 皮肤数据库的默认路径
 	SkinsDBPath = "data/skins.json"
+
+	// SkinsDBFileName 皮肤数据库在数据目录下的文件名
+	SkinsDBFileName = "skins.json"
 )
 
 // SkinDatabase 皮肤数据库结构
 type SkinDatabase struct {
 	db        models.SkinsDatabase
+	index     map[string]int // 皮肤、等级和颜色变体UUID -> 皮肤在列表中的位置
+	cosmetics map[string]int // 饰品及其等级UUID -> 饰品在列表中的位置
+	bundles   map[string]int // 套装UUID -> 套装在列表中的位置
 	filePath  string
 	mutex     sync.RWMutex
 	lastCheck time.Time
@@ -42,6 +49,9 @@ func NewSkinDatabase(filePath string) (*SkinDatabase, error) {
 
 	db := &SkinDatabase{
 		db:        models.SkinsDatabase{Skins: []models.Skin{}},
+		index:     make(map[string]int),
+		cosmetics: make(map[string]int),
+		bundles:   make(map[string]int),
 		filePath:  absPath,
 		lastCheck: time.Time{},
 	}
@@ -81,15 +91,16 @@ func (s *SkinDatabase) loadFromFile() error {
 	}
 
 	s.db = db
+	s.index = buildSkinIndex(db.Skins)
+	s.cosmetics = buildCosmeticIndex(db.Cosmetics)
+	s.bundles = buildBundleIndex(db.Bundles)
 	s.lastCheck = time.Now()
 	return nil
 }
 
 // saveToFile 将皮肤数据库保存到文件
-func (s *SkinDatabase) saveToFile() error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
+// 先写临时文件再重命名，写入失败时不会破坏已有的数据库文件
+func (s *SkinDatabase) saveToFile(db models.SkinsDatabase) error {
 	// 确保目录存在
 	dir := filepath.Dir(s.filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -97,31 +108,98 @@ func (s *SkinDatabase) saveToFile() error {
 	}
 
 	// 将数据库序列化为JSON
-	data, err := json.MarshalIndent(s.db, "", "  ")
+	data, err := json.MarshalIndent(db, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化皮肤数据库失败: %w", err)
 	}
 
 	// 写入文件
-	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+	tmpPath := s.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
 		return fmt.Errorf("写入皮肤数据库文件失败: %w", err)
 	}
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("替换皮肤数据库文件失败: %w", err)
+	}
 
 	return nil
 }
 
-// GetSkinByID 根据ID获取皮肤信息
+// buildSkinIndex 建立UUID到皮肤位置的索引
+// 商店接口返回的是皮肤等级UUID，因此等级和颜色变体的UUID也指向所属皮肤
+func buildSkinIndex(skins []models.Skin) map[string]int {
+	index := make(map[string]int, len(skins))
+	for i, skin := range skins {
+		index[strings.ToLower(skin.UUID)] = i
+		for _, level := range skin.Levels {
+			index[strings.ToLower(level.UUID)] = i
+		}
+		for _, chroma := range skin.Chromas {
+			index[strings.ToLower(chroma.UUID)] = i
+		}
+	}
+	return index
+}
+
+// buildCosmeticIndex 建立UUID到饰品位置的索引，饰品的等级UUID也指向所属饰品
+func buildCosmeticIndex(cosmetics []models.Cosmetic) map[string]int {
+	index := make(map[string]int, len(cosmetics))
+	for i, cosmetic := range cosmetics {
+		index[strings.ToLower(cosmetic.UUID)] = i
+		for _, levelID := range cosmetic.LevelUUIDs {
+			index[strings.ToLower(levelID)] = i
+		}
+	}
+	return index
+}
+
+// GetCosmeticByID 根据ID获取饰品信息，支持饰品及其等级的UUID
+func (s *SkinDatabase) GetCosmeticByID(id string) (models.Cosmetic, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, found := s.cosmetics[strings.ToLower(id)]
+	if !found {
+		return models.Cosmetic{}, false
+	}
+
+	return s.db.Cosmetics[i], true
+}
+
+// buildBundleIndex 建立UUID到套装位置的索引
+func buildBundleIndex(bundles []models.BundleInfo) map[string]int {
+	index := make(map[string]int, len(bundles))
+	for i, bundle := range bundles {
+		index[strings.ToLower(bundle.UUID)] = i
+	}
+	return index
+}
+
+// GetBundleByID 根据ID获取套装信息
+func (s *SkinDatabase) GetBundleByID(id string) (models.BundleInfo, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, found := s.bundles[strings.ToLower(id)]
+	if !found {
+		return models.BundleInfo{}, false
+	}
+
+	return s.db.Bundles[i], true
+}
+
+// GetSkinByID 根据ID获取皮肤信息，支持皮肤、皮肤等级和颜色变体的UUID
 func (s *SkinDatabase) GetSkinByID(skinID string) (models.Skin, bool) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	for _, skin := range s.db.Skins {
-		if skin.UUID == skinID {
-			return skin, true
-		}
+	i, found := s.index[strings.ToLower(skinID)]
+	if !found {
+		return models.Skin{}, false
 	}
 
-	return models.Skin{}, false
+	return s.db.Skins[i], true
 }
 
 // GetAllSkins 获取所有皮肤
@@ -135,14 +213,103 @@ func (s *SkinDatabase) GetAllSkins() []models.Skin {
 	return skins
 }
 
-// UpdateSkinDatabase 使用从Valorant API获取的皮肤信息更新数据库
-func (s *SkinDatabase) UpdateSkinDatabase(skins []models.Skin) error {
+// UpdateSkinDatabase 使用新的内容数据整体替换数据库
+// catalog中的饰品或套装为空时保留原有的数据
+// 文件写入成功后才替换内存中的数据，失败时保留原有数据
+func (s *SkinDatabase) UpdateSkinDatabase(catalog models.SkinsDatabase) error {
+	skins := catalog.Skins
+	if len(skins) == 0 {
+		return fmt.Errorf("皮肤列表为空，拒绝覆盖皮肤数据库")
+	}
+
 	s.mutex.Lock()
-	s.db.Skins = skins
-	s.mutex.Unlock()
+	defer s.mutex.Unlock()
+
+	// 内容数据中不包含价格，保留已合并的商店价格
+	for i := range skins {
+		if skins[i].Costs != nil {
+			continue
+		}
+		if j, found := s.index[strings.ToLower(skins[i].UUID)]; found {
+			skins[i].Price = s.db.Skins[j].Price
+			skins[i].Costs = s.db.Skins[j].Costs
+		}
+	}
+
+	if len(catalog.Cosmetics) == 0 {
+		catalog.Cosmetics = s.db.Cosmetics
+	}
+	if len(catalog.Bundles) == 0 {
+		catalog.Bundles = s.db.Bundles
+	}
 
 	// 保存到文件
-	return s.saveToFile()
+	if err := s.saveToFile(catalog); err != nil {
+		return err
+	}
+
+	s.db = catalog
+	s.index = buildSkinIndex(catalog.Skins)
+	s.cosmetics = buildCosmeticIndex(catalog.Cosmetics)
+	s.bundles = buildBundleIndex(catalog.Bundles)
+	s.lastCheck = time.Now()
+	return nil
+}
+
+// UpdatePrices 将价格表合并到皮肤数据库，costs的键是皮肤或皮肤等级的UUID
+// 返回价格发生变化的皮肤数量，有变化时写回文件
+func (s *SkinDatabase) UpdatePrices(costs map[string]map[string]int) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	skins := make([]models.Skin, len(s.db.Skins))
+	copy(skins, s.db.Skins)
+
+	changed := 0
+	for id, cost := range costs {
+		i, found := s.index[strings.ToLower(id)]
+		if !found {
+			continue
+		}
+
+		price := cost[models.CurrencyValorantPoints]
+		if skins[i].Price == price && sameCosts(skins[i].Costs, cost) {
+			continue
+		}
+
+		skins[i].Price = price
+		skins[i].Costs = make(map[string]int, len(cost))
+		for currencyID, amount := range cost {
+			skins[i].Costs[currencyID] = amount
+		}
+		changed++
+	}
+
+	if changed == 0 {
+		return 0, nil
+	}
+
+	db := s.db
+	db.Skins = skins
+	if err := s.saveToFile(db); err != nil {
+		return 0, err
+	}
+
+	s.db = db
+	return changed, nil
+}
+
+// sameCosts 比较两个价格表是否相同
+func sameCosts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for currencyID, amount := range a {
+		if other, exists := b[currencyID]; !exists || other != amount {
+			return false
+		}
+	}
+	return true
 }
 
 // NeedsUpdate 检查数据库是否需要更新（超过24小时未更新）
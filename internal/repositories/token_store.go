@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+const (
+	// TokensFileName 令牌撤销数据在数据目录下的文件名
+	TokensFileName = "tokens.json"
+)
+
+// ErrRefreshTokenNotFound 刷新令牌不存在
+var ErrRefreshTokenNotFound = errors.New("刷新令牌不存在")
+
+// tokenData 令牌存储的持久化结构
+type tokenData struct {
+	RefreshTokens     map[string]*models.RefreshToken `json:"refresh_tokens"`      // 令牌哈希 -> 刷新令牌
+	RevokedFamilies   map[string]int64                `json:"revoked_families"`    // 家族ID -> 撤销记录保留到的时间
+	RevokedTokens     map[string]int64                `json:"revoked_tokens"`      // 访问令牌jti -> 令牌过期时间
+	UserRevokedBefore map[string]int64                `json:"user_revoked_before"` // 用户ID -> 在此之前签发的令牌全部无效
+	UserRevokedUntil  map[string]int64                `json:"user_revoked_until"`  // 用户ID -> 撤销记录保留到的时间
+}
+
+// TokenStore 保存刷新令牌和令牌撤销列表
+// filePath为空时只保存在内存中
+type TokenStore struct {
+	data     tokenData
+	filePath string
+	mutex    sync.RWMutex
+}
+
+// NewTokenStore 创建令牌存储，并加载已有的数据文件
+func NewTokenStore(filePath string) (*TokenStore, error) {
+	store := &TokenStore{
+		data: tokenData{
+			RefreshTokens:     make(map[string]*models.RefreshToken),
+			RevokedFamilies:   make(map[string]int64),
+			RevokedTokens:     make(map[string]int64),
+			UserRevokedBefore: make(map[string]int64),
+			UserRevokedUntil:  make(map[string]int64),
+		},
+	}
+
+	if filePath == "" {
+		return store, nil
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+	store.filePath = absPath
+
+	raw, err := os.ReadFile(absPath)
+	if err != nil {
+		// 文件不存在不是错误
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("读取令牌文件失败: %w", err)
+	}
+
+	if err := json.Unmarshal(raw, &store.data); err != nil {
+		return nil, fmt.Errorf("解析令牌文件失败: %w", err)
+	}
+
+	// 旧文件中可能缺少某些字段
+	if store.data.RefreshTokens == nil {
+		store.data.RefreshTokens = make(map[string]*models.RefreshToken)
+	}
+	if store.data.RevokedFamilies == nil {
+		store.data.RevokedFamilies = make(map[string]int64)
+	}
+	if store.data.RevokedTokens == nil {
+		store.data.RevokedTokens = make(map[string]int64)
+	}
+	if store.data.UserRevokedBefore == nil {
+		store.data.UserRevokedBefore = make(map[string]int64)
+	}
+	if store.data.UserRevokedUntil == nil {
+		store.data.UserRevokedUntil = make(map[string]int64)
+	}
+
+	return store, nil
+}
+
+// saveLocked 清理过期记录并写回文件，调用方需持有写锁
+func (t *TokenStore) saveLocked() error {
+	now := time.Now().Unix()
+	for hash, token := range t.data.RefreshTokens {
+		if token.ExpiresAt <= now {
+			delete(t.data.RefreshTokens, hash)
+		}
+	}
+	for familyID, until := range t.data.RevokedFamilies {
+		if until <= now {
+			delete(t.data.RevokedFamilies, familyID)
+		}
+	}
+	for jti, expiresAt := range t.data.RevokedTokens {
+		if expiresAt <= now {
+			delete(t.data.RevokedTokens, jti)
+		}
+	}
+	// 旧文件中的用户撤销记录没有保留时间，继续保留
+	for userID, until := range t.data.UserRevokedUntil {
+		if until <= now {
+			delete(t.data.UserRevokedBefore, userID)
+			delete(t.data.UserRevokedUntil, userID)
+		}
+	}
+
+	if t.filePath == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.filePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	raw, err := json.MarshalIndent(t.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化令牌数据失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := t.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0600); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, t.filePath); err != nil {
+		return fmt.Errorf("替换令牌文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// SaveRefreshToken 保存新签发的刷新令牌
+func (t *TokenStore) SaveRefreshToken(token *models.RefreshToken) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	saved := *token
+	t.data.RefreshTokens[token.TokenHash] = &saved
+	return t.saveLocked()
+}
+
+// UseRefreshToken 将刷新令牌标记为已使用，返回标记前的记录
+// 调用方通过返回记录的Used字段判断令牌是否被重复使用
+func (t *TokenStore) UseRefreshToken(tokenHash string) (models.RefreshToken, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	token, exists := t.data.RefreshTokens[tokenHash]
+	if !exists || token.ExpiresAt <= time.Now().Unix() {
+		return models.RefreshToken{}, ErrRefreshTokenNotFound
+	}
+
+	previous := *token
+	if token.Used {
+		return previous, nil
+	}
+
+	token.Used = true
+	return previous, t.saveLocked()
+}
+
+// RevokeFamily 撤销一个令牌家族，该家族的访问令牌和刷新令牌全部失效
+func (t *TokenStore) RevokeFamily(familyID string, until time.Time) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.revokeFamilyLocked(familyID, until.Unix())
+	return t.saveLocked()
+}
+
+// revokeFamilyLocked 撤销令牌家族并删除其刷新令牌，调用方需持有写锁
+func (t *TokenStore) revokeFamilyLocked(familyID string, until int64) {
+	if until > t.data.RevokedFamilies[familyID] {
+		t.data.RevokedFamilies[familyID] = until
+	}
+	for hash, token := range t.data.RefreshTokens {
+		if token.FamilyID == familyID {
+			delete(t.data.RefreshTokens, hash)
+		}
+	}
+}
+
+// RevokeUser 撤销用户的所有令牌家族，并使在此之前签发的令牌全部失效
+// until之后该用户在at之前签发的令牌都已过期，撤销记录会被清理
+func (t *TokenStore) RevokeUser(userID string, at, until time.Time) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, token := range t.data.RefreshTokens {
+		if token.UserID == userID {
+			t.revokeFamilyLocked(token.FamilyID, until.Unix())
+		}
+	}
+	t.data.UserRevokedBefore[userID] = at.Unix()
+	if until.Unix() > t.data.UserRevokedUntil[userID] {
+		t.data.UserRevokedUntil[userID] = until.Unix()
+	}
+	return t.saveLocked()
+}
+
+// RevokeToken 将单个访问令牌加入撤销列表，记录保留到令牌过期
+func (t *TokenStore) RevokeToken(jti string, expiresAt time.Time) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.data.RevokedTokens[jti] = expiresAt.Unix()
+	return t.saveLocked()
+}
+
+// IsRevoked 检查访问令牌是否已被撤销
+func (t *TokenStore) IsRevoked(jti, familyID, userID string, issuedAt time.Time) bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if _, revoked := t.data.RevokedTokens[jti]; jti != "" && revoked {
+		return true
+	}
+	if _, revoked := t.data.RevokedFamilies[familyID]; familyID != "" && revoked {
+		return true
+	}
+	if before, exists := t.data.UserRevokedBefore[userID]; exists && issuedAt.Unix() < before {
+		return true
+	}
+	return false
+}
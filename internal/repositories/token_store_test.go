@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRevokeUserRecordsArePruned(t *testing.T) {
+	store, err := NewTokenStore(filepath.Join(t.TempDir(), TokensFileName))
+	if err != nil {
+		t.Fatalf("创建令牌存储失败: %v", err)
+	}
+
+	now := time.Now()
+	issuedAt := now.Add(-time.Minute)
+	if err := store.RevokeUser("active", now, now.Add(time.Hour)); err != nil {
+		t.Fatalf("撤销用户失败: %v", err)
+	}
+	if !store.IsRevoked("", "", "active", issuedAt) {
+		t.Fatal("撤销前签发的令牌应当失效")
+	}
+
+	// 保留时间已过的记录在下次保存时清理
+	if err := store.RevokeUser("expired", now, now.Add(-time.Second)); err != nil {
+		t.Fatalf("撤销用户失败: %v", err)
+	}
+	if _, exists := store.data.UserRevokedBefore["expired"]; exists {
+		t.Fatal("过期的用户撤销记录应当被清理")
+	}
+	if _, exists := store.data.UserRevokedUntil["expired"]; exists {
+		t.Fatal("过期的用户撤销保留时间应当被清理")
+	}
+	if !store.IsRevoked("", "", "active", issuedAt) {
+		t.Fatal("未过期的用户撤销记录不应被清理")
+	}
+}
+
+func TestRevokeUserKeepsLegacyRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), TokensFileName)
+	revokedAt := time.Now().Unix()
+	legacy := fmt.Sprintf(`{"refresh_tokens": {}, "revoked_families": {}, "revoked_tokens": {}, "user_revoked_before": {"legacy": %d}}`, revokedAt)
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatalf("写入令牌文件失败: %v", err)
+	}
+
+	store, err := NewTokenStore(path)
+	if err != nil {
+		t.Fatalf("加载旧的令牌文件失败: %v", err)
+	}
+
+	// 旧记录没有保留时间，保存时不会被清理
+	if err := store.RevokeToken("jti", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("保存令牌存储失败: %v", err)
+	}
+	if !store.IsRevoked("", "", "legacy", time.Unix(revokedAt-60, 0)) {
+		t.Fatal("旧文件中的用户撤销记录应当保留")
+	}
+}
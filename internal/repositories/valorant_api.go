@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,15 +21,9 @@ import (
 )
 
 const (
-	// API URLs
-	loginURL         = "https://auth.riotgames.com/api/v1/authorization"
-	loginUserPassURL = "https://auth.riotgames.com/api/v1/authorization"
-	entitlementsURL  = "https://entitlements.auth.riotgames.com/api/token/v1"
-	userInfoURL      = "https://auth.riotgames.com/userinfo"
-	storeURL         = "https://pd.%s.a.pvp.net/store/v3/storefront/%s"
-	walletURL        = "https://pd.%s.a.pvp.net/store/v1/wallet/%s"
-	contentURL       = "https://shared.%s.a.pvp.net/content-service/v3/content"
-	versionURL       = "https://valorant-api.com/v1/version"
+	// Cookie重新授权的查询参数，地址由RiotEndpoints构建
+	authorizeQuery      = "redirect_uri=https%3A%2F%2Fplayvalorant.com%2Fopt_in&client_id=play-valorant-web-prod&response_type=token%20id_token&scope=account%20openid&nonce=1"
+	authorizeCheckQuery = "redirect_uri=https%3A%2F%2Fplayvalorant.com%2Fopt_in&client_id=play-valorant-web-prod&response_type=token%20id_token&nonce=1"
 
 	// HTTP Headers
 	clientPlatform = "ew0KCSJwbGF0Zm9ybVR5cGUiOiAiUEMiLA0KCSJwbGF0Zm9ybU9TIjogIldpbmRvd3MiLA0KCSJwbGF0Zm9ybU9TVmVyc2lvbiI6ICIxMC4wLjE5MDQyLjEuMjU2LjY0Yml0IiwNCgkicGxhdGZvcm1DaGlwc2V0IjogIlVua25vd24iDQp9"
@@ -35,18 +31,40 @@ const (
 	// 默认的地区
 	defaultRegion = "ap"
 
+	// Riot访问令牌的默认有效期，认证响应中缺少expires_in时使用
+	defaultRiotTokenLifetime = time.Hour
+
 	// 认证方式
 	authTypeCookies  = 1
 	authTypeUserPass = 2
 )
 
 // ValorantAPI 处理与Valorant API的交互
+// 实例在所有用户之间共享，只保存与用户无关的部分（Transport、客户端版本）。
+// 区域、令牌等用户数据通过RiotAuth显式传入，登录流程使用各自独立的cookie jar。
 type ValorantAPI struct {
-	client            *http.Client
-	region            string
-	accessToken       string
-	entitlementsToken string
-	clientVersion     string
+	transport     http.RoundTripper
+	client        *http.Client // 不带cookie jar的共享客户端，用于携带令牌的请求
+	clientVersion string
+	endpoints     *RiotEndpoints
+	timeouts      RiotTimeouts
+	limiter       *rateLimiter // 按主机限流，所有用户共享
+}
+
+// RiotAuth 单个用户请求Riot接口所需的区域和令牌
+type RiotAuth struct {
+	Region           string
+	AccessToken      string
+	EntitlementToken string
+}
+
+// SessionAuth 从用户会话中提取请求Riot接口所需的区域和令牌
+func SessionAuth(session *models.UserSession) RiotAuth {
+	return RiotAuth{
+		Region:           session.Region,
+		AccessToken:      session.AccessToken,
+		EntitlementToken: session.Entitlement,
+	}
 }
 
 // 用于解析版本 API 响应的结构体
@@ -57,7 +75,7 @@ type versionResponse struct {
 }
 
 // fetchLatestClientVersion 从 valorant-api.com 获取最新的客户端版本
-func fetchLatestClientVersion(client *http.Client) (string, error) {
+func fetchLatestClientVersion(client *http.Client, versionURL string) (string, error) {
 	req, err := http.NewRequest(http.MethodGet, versionURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("创建版本请求失败: %w", err)
@@ -88,11 +106,12 @@ func fetchLatestClientVersion(client *http.Client) (string, error) {
 	return versionData.Data.RiotClientVersion, nil
 }
 
-// NewValorantAPI 创建一个新的ValorantAPI实例
-func NewValorantAPI() (*ValorantAPI, error) {
-	// 创建带cookie jar的HTTP客户端，以便能够维护会话
-	jar, err := cookiejar.New(nil)
-	if err != nil {
+// NewValorantAPI 创建一个新的ValorantAPI实例，endpoints为nil时使用Riot正式服务的地址
+func NewValorantAPI(endpoints *RiotEndpoints) (*ValorantAPI, error) {
+	if endpoints == nil {
+		endpoints = DefaultRiotEndpoints()
+	}
+	if err := endpoints.Validate(); err != nil {
 		return nil, err
 	}
 
@@ -130,19 +149,13 @@ func NewValorantAPI() (*ValorantAPI, error) {
 		},
 	}
 
-	client := &http.Client{
-		Jar:       jar,
-		Timeout:   60 * time.Second, // 增加超时时间到60秒
-		Transport: transport,
-	}
-
 	versionClient := &http.Client{
 		Timeout:   15 * time.Second,
 		Transport: transport,
 	}
 
 	fallbackClientVersion := "release-10.07-shipping-6-3399868"
-	fetchedClientVersion, err := fetchLatestClientVersion(versionClient)
+	fetchedClientVersion, err := fetchLatestClientVersion(versionClient, endpoints.VersionURL())
 	currentClientVersion := fallbackClientVersion
 
 	if err != nil {
@@ -151,22 +164,50 @@ func NewValorantAPI() (*ValorantAPI, error) {
 		currentClientVersion = fetchedClientVersion
 	}
 
-	api := &ValorantAPI{
-		client:        client,
-		region:        defaultRegion,
-		clientVersion: currentClientVersion,
+	return NewValorantAPIWithTransport(transport, currentClientVersion, endpoints), nil
+}
+
+// NewValorantAPIWithTransport 使用指定的Transport和客户端版本创建实例，不会请求版本信息
+func NewValorantAPIWithTransport(transport http.RoundTripper, clientVersion string, endpoints *RiotEndpoints) *ValorantAPI {
+	if endpoints == nil {
+		endpoints = DefaultRiotEndpoints()
+	}
+	return &ValorantAPI{
+		transport: transport,
+		// 超时由每次请求的context控制，见RiotTimeouts
+		client: &http.Client{
+			Transport: transport,
+		},
+		clientVersion: clientVersion,
+		endpoints:     endpoints,
+		limiter:       newRateLimiter(DefaultRiotRateLimit, DefaultRiotRateBurst),
 	}
+}
 
-	return api, nil
+// SetTimeouts 设置每种Riot请求的超时时间
+func (v *ValorantAPI) SetTimeouts(timeouts RiotTimeouts) {
+	v.timeouts = timeouts
 }
 
-// SetRegion 设置用户的地区
-func (v *ValorantAPI) SetRegion(region string) {
-	if region == "" {
-		v.region = defaultRegion
-		return
+// SetRateLimit 设置每个Riot主机每秒允许的请求数和突发请求数，rate为0时不限流
+func (v *ValorantAPI) SetRateLimit(rate float64, burst int) {
+	v.limiter = newRateLimiter(rate, burst)
+}
+
+// newRequest 创建带有该请求超时时间的HTTP请求，ctx取消时请求立即结束
+// 调用方读取完响应后需要调用返回的cancel
+func (v *ValorantAPI) newRequest(ctx context.Context, call, method, url string, body io.Reader) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.timeouts.forCall(call))
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		cancel()
+		return nil, nil, err
 	}
+	return req, cancel, nil
+}
 
+// NormalizeRegion 将用户区域转换为PD接口使用的分片代码
+func NormalizeRegion(region string) string {
 	// 转小写处理区域代码
 	region = strings.ToLower(region)
 
@@ -174,31 +215,29 @@ func (v *ValorantAPI) SetRegion(region string) {
 	switch region {
 	case "na", "latam", "br":
 		// latam和br使用na区域
-		v.region = "na"
+		return "na"
 	case "eu":
-		v.region = "eu"
+		return "eu"
 	case "ap", "kr":
 		// 有些API可能需要kr和ap区分，但这里我们确保使用正确的ap区域代码
-		v.region = region
+		return region
 	case "pbe":
-		v.region = "pbe"
+		return "pbe"
 	default:
-		// 对于其他输入，使用默认区域
-		v.region = defaultRegion
+		// 对于其他输入（包括空值），使用默认区域
+		return defaultRegion
 	}
-
-	fmt.Printf("区域设置已更新: 输入=%s, 最终区域=%s\n", region, v.region)
 }
 
 // GetPlayerRegion 获取玩家所在的区域
-func (v *ValorantAPI) GetPlayerRegion(accessToken, entitlementToken string) (string, error) {
+func (v *ValorantAPI) GetPlayerRegion(ctx context.Context, accessToken, entitlementToken string) (string, error) {
 	// 按照以下区域顺序尝试
 	regionsToTry := []string{"na", "eu", "ap", "kr", "latam", "br"}
 
 	fmt.Printf("开始检测玩家区域...\n")
 
 	// 获取用户ID (puuid)
-	userInfo, err := v.getUserInfo(accessToken)
+	userInfo, err := v.getUserInfo(ctx, accessToken)
 	if err != nil {
 		return "", fmt.Errorf("获取用户信息失败: %w", err)
 	}
@@ -210,68 +249,118 @@ func (v *ValorantAPI) GetPlayerRegion(accessToken, entitlementToken string) (str
 
 	// 尝试每个区域
 	for _, region := range regionsToTry {
-		// 临时设置区域
-		originalRegion := v.region
-		v.SetRegion(region)
-
-		fmt.Printf("尝试区域: %s (%s)\n", region, v.region)
+		shard := NormalizeRegion(region)
+		fmt.Printf("尝试区域: %s (%s)\n", region, shard)
 
 		// 尝试获取玩家对局历史
-		url := fmt.Sprintf("https://pd.%s.a.pvp.net/name-service/v2/players", v.region)
+		url := v.endpoints.NameServiceURL(shard)
 
-		req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer([]byte(`["`+puuid+`"]`)))
+		req, cancel, err := v.newRequest(ctx, RiotCallNameService, http.MethodPut, url, bytes.NewBuffer([]byte(`["`+puuid+`"]`)))
 		if err != nil {
-			v.region = originalRegion
 			continue
 		}
 
-		// 暂时保存令牌以便addCommonHeaders使用
-		prevAccessToken := v.accessToken
-		prevEntitlementToken := v.entitlementsToken
-
-		// 设置当前请求的令牌
-		v.accessToken = accessToken
-		v.entitlementsToken = entitlementToken
-
 		// 添加通用头信息
-		v.addCommonHeaders(req)
+		v.addCommonHeaders(req, accessToken, entitlementToken)
 
-		// 恢复原来的令牌
-		v.accessToken = prevAccessToken
-		v.entitlementsToken = prevEntitlementToken
-
-		resp, err := v.client.Do(req)
+		resp, err := v.send(v.client, RiotCallNameService, req)
 		if err != nil {
-			v.region = originalRegion
+			cancel()
+			// 请求已取消或被限流时不再尝试其他区域
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			if errors.Is(err, ErrRateLimited) {
+				return "", err
+			}
 			continue
 		}
-		defer resp.Body.Close()
+		resp.Body.Close()
+		cancel()
 
 		if resp.StatusCode == http.StatusOK {
 			fmt.Printf("找到玩家区域: %s\n", region)
 			return region, nil
 		}
-
-		// 恢复原始区域
-		v.region = originalRegion
 	}
 
 	return defaultRegion, fmt.Errorf("无法确定玩家区域，使用默认区域: %s", defaultRegion)
 }
 
+// PendingMFA 等待二次验证码的登录过程
+// 必须保留登录时使用的HTTP客户端，因为Riot通过cookie关联验证码与登录请求
+type PendingMFA struct {
+	Username    string
+	Multifactor models.ValorantMultifactorInfo
+	client      *http.Client
+}
+
 // Authenticate 使用用户名和密码进行认证
-func (v *ValorantAPI) Authenticate(username, password string) (*models.UserSession, error) {
+// 如果账号开启了二次验证，返回的session为nil，需要使用PendingMFA调用SubmitMFACode完成登录
+func (v *ValorantAPI) Authenticate(ctx context.Context, username, password string) (*models.UserSession, *PendingMFA, error) {
+	// 每次登录使用独立的cookie jar，避免不同用户的登录过程互相干扰
+	client, err := v.newAuthClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建认证客户端失败: %w", err)
+	}
+
 	// 第一步：获取认证cookie
-	if err := v.requestAuth(); err != nil {
-		return nil, fmt.Errorf("认证步骤1失败: %w", err)
+	if err := v.requestAuth(ctx, client); err != nil {
+		return nil, nil, fmt.Errorf("认证步骤1失败: %w", err)
 	}
 
 	// 第二步：使用用户名和密码登录
-	authResponse, err := v.requestLogin(username, password)
+	authResponse, err := v.requestLogin(ctx, client, username, password)
 	if err != nil {
-		return nil, fmt.Errorf("认证步骤2失败: %w", err)
+		return nil, nil, fmt.Errorf("认证步骤2失败: %w", err)
+	}
+
+	// 账号开启了二次验证，等待用户提交验证码
+	if authResponse.Type == "multifactor" {
+		pending := &PendingMFA{
+			Username:    username,
+			Multifactor: authResponse.Multifactor,
+			client:      client,
+		}
+		return nil, pending, nil
+	}
+
+	session, err := v.completeAuthentication(ctx, client, authResponse, username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return session, nil, nil
+}
+
+// SubmitMFACode 提交二次验证码并完成认证
+func (v *ValorantAPI) SubmitMFACode(ctx context.Context, pending *PendingMFA, code string, rememberDevice bool) (*models.UserSession, error) {
+	if pending == nil || pending.client == nil {
+		return nil, errors.New("无效的二次验证状态")
+	}
+
+	data := map[string]interface{}{
+		"type":           "multifactor",
+		"code":           code,
+		"rememberDevice": rememberDevice,
 	}
 
+	var resp models.ValorantAuthResponse
+	if err := v.makeRequestWithClient(ctx, pending.client, RiotCallAuthorization, http.MethodPut, v.endpoints.AuthorizationURL(), data, &resp); err != nil {
+		return nil, fmt.Errorf("提交验证码失败: %w", err)
+	}
+
+	// 验证码错误时Riot会再次返回multifactor类型
+	if resp.Type != "response" {
+		return nil, fmt.Errorf("%w: 验证码错误或已失效", ErrMFARequired)
+	}
+
+	return v.completeAuthentication(ctx, pending.client, &resp, pending.Username)
+}
+
+// completeAuthentication 使用登录成功的响应换取令牌并构建用户会话
+// 登录过程中Riot下发的ssid等Cookie会保存到会话中，用于令牌过期后重新认证
+func (v *ValorantAPI) completeAuthentication(ctx context.Context, client *http.Client, authResponse *models.ValorantAuthResponse, username string) (*models.UserSession, error) {
 	// 解析认证URI，获取访问令牌
 	accessToken, err := parseAuthURI(authResponse.Response.Parameters.URI)
 	if err != nil {
@@ -279,34 +368,36 @@ func (v *ValorantAPI) Authenticate(username, password string) (*models.UserSessi
 	}
 
 	// 获取授权令牌
-	entitlementToken, err := v.getEntitlementToken(accessToken)
+	entitlementToken, err := v.getEntitlementToken(ctx, accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("获取授权令牌失败: %w", err)
 	}
 
 	// 获取用户信息
-	userInfo, err := v.getUserInfo(accessToken)
+	userInfo, err := v.getUserInfo(ctx, accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户信息失败: %w", err)
 	}
 
 	// 获取用户区域
-	region, err := v.GetPlayerRegion(accessToken, entitlementToken)
+	region, err := v.GetPlayerRegion(ctx, accessToken, entitlementToken)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
 		fmt.Printf("警告: 获取用户区域失败: %v, 使用默认区域\n", err)
 		region = defaultRegion
-	} else {
-		// 设置API客户端区域
-		v.SetRegion(region)
 	}
 
 	// 创建用户会话
 	session := &models.UserSession{
-		UserID:      userInfo.Sub,
-		Username:    username,
-		AccessToken: accessToken,
-		Entitlement: entitlementToken,
-		Region:      region,
+		UserID:             userInfo.Sub,
+		Username:           username,
+		AccessToken:        accessToken,
+		Entitlement:        entitlementToken,
+		Region:             region,
+		Cookies:            collectJarCookies(client, v.endpoints.AuthorizationURL()),
+		RiotTokenExpiresAt: parseTokenExpiry(authResponse.Response.Parameters.URI),
 	}
 
 	// 设置Riot用户名和标签
@@ -322,8 +413,21 @@ func (v *ValorantAPI) Authenticate(username, password string) (*models.UserSessi
 	return session, nil
 }
 
+// newAuthClient 创建带独立cookie jar的HTTP客户端，与主客户端共享Transport
+func (v *ValorantAPI) newAuthClient() (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Jar:       jar,
+		Transport: v.transport,
+	}, nil
+}
+
 // requestAuth 初始化认证过程
-func (v *ValorantAPI) requestAuth() error {
+func (v *ValorantAPI) requestAuth(ctx context.Context, client *http.Client) error {
 	data := map[string]interface{}{
 		"client_id":     "play-valorant-web-prod",
 		"nonce":         "1",
@@ -332,11 +436,11 @@ func (v *ValorantAPI) requestAuth() error {
 		"scope":         "account openid",
 	}
 
-	return v.makeRequest(http.MethodPost, loginURL, data, nil)
+	return v.makeRequestWithClient(ctx, client, RiotCallAuthorization, http.MethodPost, v.endpoints.AuthorizationURL(), data, nil)
 }
 
 // requestLogin 使用用户凭证请求登录
-func (v *ValorantAPI) requestLogin(username, password string) (*models.ValorantAuthResponse, error) {
+func (v *ValorantAPI) requestLogin(ctx context.Context, client *http.Client, username, password string) (*models.ValorantAuthResponse, error) {
 	data := map[string]interface{}{
 		"type":     "auth",
 		"username": username,
@@ -344,46 +448,43 @@ func (v *ValorantAPI) requestLogin(username, password string) (*models.ValorantA
 	}
 
 	var resp models.ValorantAuthResponse
-	err := v.makeRequest(http.MethodPut, loginUserPassURL, data, &resp)
+	err := v.makeRequestWithClient(ctx, client, RiotCallAuthorization, http.MethodPut, v.endpoints.AuthorizationURL(), data, &resp)
 	if err != nil {
 		return nil, err
 	}
 
-	// 检查响应类型
-	if resp.Type != "response" {
-		return nil, errors.New("登录失败，请检查用户名和密码")
+	// 检查响应类型，multifactor表示需要二次验证
+	switch {
+	case resp.Type == "response" || resp.Type == "multifactor":
+		return &resp, nil
+	case strings.Contains(resp.Error, "captcha"):
+		return nil, ErrCaptchaRequired
+	case resp.Error == "rate_limited":
+		return nil, &RateLimitError{Host: urlHost(v.endpoints.AuthorizationURL())}
+	default:
+		return nil, ErrInvalidCredentials
 	}
-
-	return &resp, nil
 }
 
 // getEntitlementToken 获取授权令牌
-func (v *ValorantAPI) getEntitlementToken(accessToken string) (string, error) {
-	req, err := http.NewRequest(http.MethodPost, entitlementsURL, nil)
+func (v *ValorantAPI) getEntitlementToken(ctx context.Context, accessToken string) (string, error) {
+	req, cancel, err := v.newRequest(ctx, RiotCallEntitlements, http.MethodPost, v.endpoints.EntitlementsTokenURL(), nil)
 	if err != nil {
 		return "", err
 	}
-
-	// 暂时保存令牌以便addCommonHeaders使用
-	prevAccessToken := v.accessToken
-
-	// 设置当前请求的令牌
-	v.accessToken = accessToken
+	defer cancel()
 
 	// 添加通用头信息
-	v.addCommonHeaders(req)
-
-	// 恢复原来的令牌
-	v.accessToken = prevAccessToken
+	v.addCommonHeaders(req, accessToken, "")
 
-	resp, err := v.client.Do(req)
+	resp, err := v.send(v.client, RiotCallEntitlements, req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("获取授权令牌失败，状态码: %d", resp.StatusCode)
+		return "", fmt.Errorf("获取授权令牌失败: %w", riotStatusError(RiotCallEntitlements, resp.StatusCode, ""))
 	}
 
 	var entitlementResp models.ValorantEntitlementResponse
@@ -395,32 +496,24 @@ func (v *ValorantAPI) getEntitlementToken(accessToken string) (string, error) {
 }
 
 // getUserInfo 获取用户信息
-func (v *ValorantAPI) getUserInfo(accessToken string) (*models.ValorantUserInfoResponse, error) {
-	req, err := http.NewRequest(http.MethodGet, userInfoURL, nil)
+func (v *ValorantAPI) getUserInfo(ctx context.Context, accessToken string) (*models.ValorantUserInfoResponse, error) {
+	req, cancel, err := v.newRequest(ctx, RiotCallUserInfo, http.MethodGet, v.endpoints.UserInfoURL(), nil)
 	if err != nil {
 		return nil, err
 	}
-
-	// 暂时保存令牌以便addCommonHeaders使用
-	prevAccessToken := v.accessToken
-
-	// 设置当前请求的令牌
-	v.accessToken = accessToken
+	defer cancel()
 
 	// 添加通用头信息
-	v.addCommonHeaders(req)
+	v.addCommonHeaders(req, accessToken, "")
 
-	// 恢复原来的令牌
-	v.accessToken = prevAccessToken
-
-	resp, err := v.client.Do(req)
+	resp, err := v.send(v.client, RiotCallUserInfo, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("获取用户信息失败，状态码: %d", resp.StatusCode)
+		return nil, fmt.Errorf("获取用户信息失败: %w", riotStatusError(RiotCallUserInfo, resp.StatusCode, ""))
 	}
 
 	var userInfo models.ValorantUserInfoResponse
@@ -432,77 +525,41 @@ func (v *ValorantAPI) getUserInfo(accessToken string) (*models.ValorantUserInfoR
 }
 
 // GetStoreOffers 获取商店物品
-func (v *ValorantAPI) GetStoreOffers(userID, accessToken, entitlementToken string) (*models.ValorantStoreResponse, error) {
+func (v *ValorantAPI) GetStoreOffers(ctx context.Context, auth RiotAuth, userID string) (*models.ValorantStoreResponse, error) {
 	// 确保使用有效的区域设置
-	if v.region == "" {
-		v.region = defaultRegion
+	shard := NormalizeRegion(auth.Region)
+	if auth.Region == "" {
 		fmt.Printf("警告：未设置区域，将使用默认区域: %s\n", defaultRegion)
 	}
 
-	fmt.Printf("区域检查: 当前使用的区域是 %s\n", v.region)
-
 	// 构建URL
-	url := fmt.Sprintf(storeURL, v.region, userID)
-
-	// 打印详细日志
-	fmt.Printf("正在请求商店数据:\n")
-	fmt.Printf("- 完整URL: %s\n", url)
-	fmt.Printf("- 区域设置: %s\n", v.region)
-	fmt.Printf("- 用户ID: %s\n", userID)
-
-	fmt.Printf("- 请求方法: %s\n", http.MethodPost)
+	url := v.endpoints.StorefrontURL(shard, userID)
 
 	// 创建请求 - 使用POST方法并包含空请求体
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString("{}"))
+	req, cancel, err := v.newRequest(ctx, RiotCallStorefront, http.MethodPost, url, bytes.NewBufferString("{}"))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
+	defer cancel()
 
 	// 设置完整的请求头
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Riot-ClientPlatform", clientPlatform)
 	req.Header.Set("X-Riot-ClientVersion", v.clientVersion)
-	req.Header.Set("X-Riot-Entitlements-JWT", entitlementToken)
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	// 打印完整的请求头信息（用于调试）
-	fmt.Printf("- 请求头信息:\n")
-	for key, values := range req.Header {
-		// 跳过Authorization头以保护敏感信息
-		if key == "Authorization" || key == "X-Riot-Entitlements-JWT" {
-			fmt.Printf("  %s: ***敏感信息已隐藏***\n", key)
-		} else {
-			fmt.Printf("  %s: %s\n", key, values[0])
-		}
-	}
+	req.Header.Set("X-Riot-Entitlements-JWT", auth.EntitlementToken)
+	req.Header.Set("Authorization", "Bearer "+auth.AccessToken)
 
-	resp, err := v.retryHTTPRequest(req, 3) // 重试3次
+	resp, err := v.send(v.client, RiotCallStorefront, req)
 	if err != nil {
-		fmt.Printf("获取商店数据失败: %v\n", err)
 		return nil, fmt.Errorf("获取商店物品失败: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// 检查响应状态
+	// 检查响应状态，响应体只用于识别错误类型，不写入日志
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		bodyStr := string(bodyBytes)
-
-		fmt.Printf("请求失败:\n")
-		fmt.Printf("- 状态码: %d\n", resp.StatusCode)
-		fmt.Printf("- 响应体: %s\n", bodyStr)
-		fmt.Printf("- 响应头:\n")
-		for key, values := range resp.Header {
-			fmt.Printf("  %s: %s\n", key, values[0])
-		}
-
-		// 针对特定错误提供更具体的错误信息
-		if resp.StatusCode == 404 {
-			fmt.Printf("警告: 获取到404错误，这可能意味着API接口路径已更改或用户区域不正确\n")
-			fmt.Printf("尝试: 1. 确认用户所在区域 2. 验证API接口路径是否最新 3. 检查Riot客户端版本\n")
-		}
-
-		return nil, fmt.Errorf("获取商店数据失败，状态码: %d, 响应: %s", resp.StatusCode, bodyStr)
+		fmt.Printf("获取商店数据失败，区域: %s，状态码: %d\n", shard, resp.StatusCode)
+		return nil, fmt.Errorf("获取商店物品失败: %w", riotStatusError(RiotCallStorefront, resp.StatusCode, string(bodyBytes)))
 	}
 
 	// 解析响应
@@ -511,60 +568,39 @@ func (v *ValorantAPI) GetStoreOffers(userID, accessToken, entitlementToken strin
 		return nil, fmt.Errorf("解析商店数据失败: %w", err)
 	}
 
-	fmt.Printf("成功获取商店数据\n")
 	return &storeResp, nil
 }
 
 // GetWallet 获取用户钱包/余额
-func (v *ValorantAPI) GetWallet(userID, accessToken, entitlementToken string) (*models.ValorantWalletResponse, error) {
-	url := fmt.Sprintf(walletURL, v.region, userID)
-
-	fmt.Printf("正在请求钱包数据:\n")
-	fmt.Printf("- URL: %s\n", url)
-	fmt.Printf("- 区域: %s\n", v.region)
+func (v *ValorantAPI) GetWallet(ctx context.Context, auth RiotAuth, userID string) (*models.ValorantWalletResponse, error) {
+	shard := NormalizeRegion(auth.Region)
+	url := v.endpoints.WalletURL(shard, userID)
 
 	// 创建请求
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, cancel, err := v.newRequest(ctx, RiotCallWallet, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
-
-	// 暂时保存令牌以便addCommonHeaders使用
-	prevAccessToken := v.accessToken
-	prevEntitlementToken := v.entitlementsToken
-
-	// 设置当前请求的令牌
-	v.accessToken = accessToken
-	v.entitlementsToken = entitlementToken
+	defer cancel()
 
 	// 添加通用头信息
-	v.addCommonHeaders(req)
-
-	// 恢复原来的令牌
-	v.accessToken = prevAccessToken
-	v.entitlementsToken = prevEntitlementToken
+	v.addCommonHeaders(req, auth.AccessToken, auth.EntitlementToken)
 
 	// 添加特定于Riot客户端的头信息
 	req.Header.Add("X-Riot-ClientPlatform", clientPlatform)
 	req.Header.Add("X-Riot-ClientVersion", v.clientVersion)
 
-	resp, err := v.retryHTTPRequest(req, 3) // 重试3次
+	resp, err := v.send(v.client, RiotCallWallet, req)
 	if err != nil {
-		fmt.Printf("获取钱包数据失败: %v\n", err)
 		return nil, fmt.Errorf("获取钱包信息失败: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// 检查响应状态
+	// 检查响应状态，响应体只用于识别错误类型，不写入日志
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		bodyStr := string(bodyBytes)
-
-		fmt.Printf("请求失败:\n")
-		fmt.Printf("- 状态码: %d\n", resp.StatusCode)
-		fmt.Printf("- 响应体: %s\n", bodyStr)
-
-		return nil, fmt.Errorf("获取钱包数据失败，状态码: %d, 响应: %s", resp.StatusCode, bodyStr)
+		fmt.Printf("获取钱包数据失败，区域: %s，状态码: %d\n", shard, resp.StatusCode)
+		return nil, fmt.Errorf("获取钱包数据失败: %w", riotStatusError(RiotCallWallet, resp.StatusCode, string(bodyBytes)))
 	}
 
 	// 解析响应
@@ -573,16 +609,71 @@ func (v *ValorantAPI) GetWallet(userID, accessToken, entitlementToken string) (*
 		return nil, fmt.Errorf("解析钱包数据失败: %w", err)
 	}
 
-	fmt.Printf("成功获取钱包数据\n")
 	return &walletResp, nil
 }
 
+// GetOffers 获取商店中所有物品的价格表，价格表与用户无关但需要携带令牌请求
+func (v *ValorantAPI) GetOffers(ctx context.Context, auth RiotAuth) (*models.ValorantOffersResponse, error) {
+	url := v.endpoints.OffersURL(NormalizeRegion(auth.Region))
+
+	var offersResp models.ValorantOffersResponse
+	if err := v.makeAuthorizedRequest(ctx, RiotCallOffers, http.MethodGet, url, nil, &offersResp, auth); err != nil {
+		return nil, fmt.Errorf("获取商店价格失败: %w", err)
+	}
+
+	fmt.Printf("成功获取商店价格，共 %d 个物品\n", len(offersResp.Offers))
+	return &offersResp, nil
+}
+
+// GetEntitlements 获取用户拥有的某一类物品，itemTypeID见models中的ItemType常量
+func (v *ValorantAPI) GetEntitlements(ctx context.Context, auth RiotAuth, userID, itemTypeID string) (*models.ValorantEntitlementsResponse, error) {
+	url := v.endpoints.EntitlementItemsURL(NormalizeRegion(auth.Region), userID, itemTypeID)
+
+	var entitlementsResp models.ValorantEntitlementsResponse
+	if err := v.makeAuthorizedRequest(ctx, RiotCallOwnedItems, http.MethodGet, url, nil, &entitlementsResp, auth); err != nil {
+		return nil, fmt.Errorf("获取用户物品失败: %w", err)
+	}
+
+	return &entitlementsResp, nil
+}
+
+// GetPlayerLoadout 获取玩家当前的装备
+func (v *ValorantAPI) GetPlayerLoadout(ctx context.Context, auth RiotAuth, userID string) (*models.ValorantPlayerLoadout, error) {
+	url := v.endpoints.PlayerLoadoutURL(NormalizeRegion(auth.Region), userID)
+
+	var loadout models.ValorantPlayerLoadout
+	if err := v.makeAuthorizedRequest(ctx, RiotCallLoadout, http.MethodGet, url, nil, &loadout, auth); err != nil {
+		return nil, fmt.Errorf("获取玩家装备失败: %w", err)
+	}
+
+	return &loadout, nil
+}
+
+// SetPlayerLoadout 修改玩家的装备，返回修改后的装备
+func (v *ValorantAPI) SetPlayerLoadout(ctx context.Context, auth RiotAuth, userID string, loadout *models.ValorantPlayerLoadout) (*models.ValorantPlayerLoadout, error) {
+	url := v.endpoints.PlayerLoadoutURL(NormalizeRegion(auth.Region), userID)
+
+	// Riot不接受null的Attachments
+	for i := range loadout.Guns {
+		if loadout.Guns[i].Attachments == nil {
+			loadout.Guns[i].Attachments = []interface{}{}
+		}
+	}
+
+	var updated models.ValorantPlayerLoadout
+	if err := v.makeAuthorizedRequest(ctx, RiotCallLoadout, http.MethodPut, url, loadout, &updated, auth); err != nil {
+		return nil, fmt.Errorf("修改玩家装备失败: %w", err)
+	}
+
+	return &updated, nil
+}
+
 // GetContentInfo 获取游戏内容信息(包括皮肤等)
-func (v *ValorantAPI) GetContentInfo() (interface{}, error) {
-	url := fmt.Sprintf(contentURL, v.region)
+func (v *ValorantAPI) GetContentInfo(ctx context.Context, region string) (interface{}, error) {
+	url := v.endpoints.ContentURL(NormalizeRegion(region))
 	var contentResp interface{}
 
-	err := v.makeRequest(http.MethodGet, url, nil, &contentResp)
+	err := v.makeRequest(ctx, RiotCallContent, http.MethodGet, url, nil, &contentResp)
 	if err != nil {
 		return nil, fmt.Errorf("获取内容信息失败: %w", err)
 	}
@@ -591,7 +682,12 @@ func (v *ValorantAPI) GetContentInfo() (interface{}, error) {
 }
 
 // makeRequest 执行一个HTTP请求
-func (v *ValorantAPI) makeRequest(method, url string, data interface{}, result interface{}) error {
+func (v *ValorantAPI) makeRequest(ctx context.Context, call, method, url string, data interface{}, result interface{}) error {
+	return v.makeRequestWithClient(ctx, v.client, call, method, url, data, result)
+}
+
+// makeRequestWithClient 使用指定的HTTP客户端执行一个HTTP请求
+func (v *ValorantAPI) makeRequestWithClient(ctx context.Context, client *http.Client, call, method, url string, data interface{}, result interface{}) error {
 	var body io.Reader
 	if data != nil {
 		jsonData, err := json.Marshal(data)
@@ -601,16 +697,17 @@ func (v *ValorantAPI) makeRequest(method, url string, data interface{}, result i
 		body = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, cancel, err := v.newRequest(ctx, call, method, url, body)
 	if err != nil {
 		return err
 	}
+	defer cancel()
 
 	// 设置通用请求头
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("User-Agent", "RiotClient/"+v.clientVersion)
 
-	resp, err := v.client.Do(req)
+	resp, err := v.send(client, call, req)
 	if err != nil {
 		return err
 	}
@@ -619,7 +716,8 @@ func (v *ValorantAPI) makeRequest(method, url string, data interface{}, result i
 	// 检查响应状态
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
+		fmt.Printf("Riot请求 %s 失败，状态码: %d\n", call, resp.StatusCode)
+		return riotStatusError(call, resp.StatusCode, string(bodyBytes))
 	}
 
 	// 如果需要解析结果
@@ -633,7 +731,7 @@ func (v *ValorantAPI) makeRequest(method, url string, data interface{}, result i
 }
 
 // makeAuthorizedRequest 执行一个需要认证的HTTP请求
-func (v *ValorantAPI) makeAuthorizedRequest(method, url string, data, result interface{}, accessToken, entitlementToken string) error {
+func (v *ValorantAPI) makeAuthorizedRequest(ctx context.Context, call, method, url string, data, result interface{}, auth RiotAuth) error {
 	var body io.Reader
 	if data != nil {
 		jsonData, err := json.Marshal(data)
@@ -643,59 +741,34 @@ func (v *ValorantAPI) makeAuthorizedRequest(method, url string, data, result int
 		body = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, cancel, err := v.newRequest(ctx, call, method, url, body)
 	if err != nil {
 		return err
 	}
-
-	// 设置当前请求的令牌
-	v.accessToken = accessToken
-	v.entitlementsToken = entitlementToken
+	defer cancel()
 
 	// 添加通用头信息
-	v.addCommonHeaders(req)
-
-	fmt.Printf("发送HTTP请求:\n")
-	fmt.Printf("- 方法: %s\n", method)
-	fmt.Printf("- URL: %s\n", url)
-	fmt.Printf("- 请求头:\n")
-	for key, values := range req.Header {
-		// 跳过Authorization头以保护敏感信息
-		if key == "Authorization" || key == "X-Riot-Entitlements-JWT" {
-			fmt.Printf("  %s: ***敏感信息已隐藏***\n", key)
-		} else {
-			fmt.Printf("  %s: %s\n", key, values[0])
-		}
-	}
+	v.addCommonHeaders(req, auth.AccessToken, auth.EntitlementToken)
+	req.Header.Set("X-Riot-ClientPlatform", clientPlatform)
+	req.Header.Set("X-Riot-ClientVersion", v.clientVersion)
 
-	resp, err := v.client.Do(req)
+	resp, err := v.send(v.client, call, req)
 	if err != nil {
-		fmt.Printf("HTTP请求失败: %v\n", err)
 		return err
 	}
 	defer resp.Body.Close()
 
-	// 检查响应状态
+	// 检查响应状态，响应体只用于识别错误类型，不写入日志
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		bodyStr := string(bodyBytes)
-
-		fmt.Printf("请求失败:\n")
-		fmt.Printf("- 状态码: %d\n", resp.StatusCode)
-		fmt.Printf("- 响应体: %s\n", bodyStr)
-		fmt.Printf("- 响应头:\n")
-		for key, values := range resp.Header {
-			fmt.Printf("  %s: %s\n", key, values[0])
-		}
-
-		return fmt.Errorf("授权请求失败，状态码: %d, 响应: %s", resp.StatusCode, bodyStr)
+		fmt.Printf("Riot请求 %s 失败，状态码: %d\n", call, resp.StatusCode)
+		return fmt.Errorf("授权请求失败: %w", riotStatusError(call, resp.StatusCode, string(bodyBytes)))
 	}
 
 	// 如果需要解析结果
 	if result != nil {
 		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-			fmt.Printf("解析响应体失败: %v\n", err)
-			return err
+			return fmt.Errorf("解析%s响应失败: %w", call, err)
 		}
 	}
 
@@ -824,19 +897,24 @@ func ParseCookieString(cookieStr string) map[string]string {
 }
 
 // AuthenticateWithCookies 使用Cookie进行认证
-func (v *ValorantAPI) AuthenticateWithCookies(cookies map[string]string) (*models.UserSession, error) {
+func (v *ValorantAPI) AuthenticateWithCookies(ctx context.Context, cookies map[string]string) (*models.UserSession, error) {
 	// 尝试使用authorize端点进行认证
-	session, err := v.authenticateWithCookiesViaAuthorizeEndpoint(cookies)
+	session, err := v.authenticateWithCookiesViaAuthorizeEndpoint(ctx, cookies)
 	if err != nil {
+		// 请求已取消、被限流或Riot不可用时不再尝试备用方法，保留原始的错误类型
+		if ctx.Err() != nil || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUpstreamUnavailable) {
+			return nil, err
+		}
+
 		// 如果失败，尝试使用auth端点
-		session, err = v.authenticateWithCookiesViaAuthEndpoint(cookies)
+		session, err = v.authenticateWithCookiesViaAuthEndpoint(ctx, cookies)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// 获取用户信息并设置Riot用户名
-	userInfo, err := v.getUserInfo(session.AccessToken)
+	userInfo, err := v.getUserInfo(ctx, session.AccessToken)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户信息失败: %w", err)
 	}
@@ -851,7 +929,7 @@ func (v *ValorantAPI) AuthenticateWithCookies(cookies map[string]string) (*model
 }
 
 // 主要认证方法 - 通过authorize端点
-func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[string]string) (*models.UserSession, error) {
+func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(ctx context.Context, cookies map[string]string) (*models.UserSession, error) {
 	// 创建带有cookie jar的新HTTP客户端
 	jar, err := cookiejar.New(nil)
 	if err != nil {
@@ -859,18 +937,16 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[st
 	}
 
 	// 使用原始项目的策略 - 禁用重定向后处理
+	// 该客户端只用于本次认证，不与其他用户共享
 	client := &http.Client{
-		Jar:     jar,
-		Timeout: 30 * time.Second,
+		Jar:       jar,
+		Transport: v.transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// 禁止自动跟随重定向
 			return http.ErrUseLastResponse
 		},
 	}
 
-	// 使用这个带cookie的客户端替换当前的客户端
-	v.client = client
-
 	// 过滤保留有用的Cookie（但不再强制要求特定Cookie）
 	essentialCookies := FilterEssentialCookies(cookies)
 	if len(essentialCookies) == 0 {
@@ -878,12 +954,13 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[st
 	}
 
 	// 尝试方法一：直接使用cookie点击另一个端点
-	userInfoReq, err := http.NewRequest(http.MethodGet, userInfoURL, nil)
+	userInfoReq, cancelUserInfo, err := v.newRequest(ctx, RiotCallUserInfo, http.MethodGet, v.endpoints.UserInfoURL(), nil)
 	if err != nil {
 		return nil, err
 	}
+	defer cancelUserInfo()
 	setRiotRequestHeaders(userInfoReq, essentialCookies)
-	userInfoResp, err := v.client.Do(userInfoReq)
+	userInfoResp, err := v.send(client, RiotCallUserInfo, userInfoReq)
 
 	// 如果直接获取用户信息成功，说明cookie有效
 	if err == nil && userInfoResp.StatusCode == http.StatusOK {
@@ -891,17 +968,18 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[st
 		var userInfo models.ValorantUserInfoResponse
 		if err := json.NewDecoder(userInfoResp.Body).Decode(&userInfo); err == nil {
 			// 获取授权令牌
-			entitlementToken, err := v.getEntitlementToken(essentialCookies["ssid"])
+			entitlementToken, err := v.getEntitlementToken(ctx, essentialCookies["ssid"])
 			if err == nil {
 				// 创建用户会话
 				session := &models.UserSession{
-					UserID:       userInfo.Sub,
-					Username:     userInfo.Email,
-					AccessToken:  essentialCookies["ssid"], // 使用ssid作为token
-					Entitlement:  entitlementToken,
-					RiotUsername: userInfo.Name,
-					RiotTagline:  userInfo.Tag,
-					Cookies:      essentialCookies,
+					UserID:             userInfo.Sub,
+					Username:           userInfo.Email,
+					AccessToken:        essentialCookies["ssid"], // 使用ssid作为token
+					Entitlement:        entitlementToken,
+					RiotUsername:       userInfo.Name,
+					RiotTagline:        userInfo.Tag,
+					Cookies:            essentialCookies,
+					RiotTokenExpiresAt: time.Now().Add(defaultRiotTokenLifetime).Unix(),
 				}
 				return session, nil
 			}
@@ -910,22 +988,26 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[st
 	if userInfoResp != nil {
 		userInfoResp.Body.Close()
 	}
+	if errors.Is(err, ErrRateLimited) {
+		return nil, err
+	}
 
 	// 尝试方法二：通过authorize端点获取token
-	// 构建请求URL - 使用和原始项目完全相同的URL
-	authURL := "https://auth.riotgames.com/authorize?redirect_uri=https%3A%2F%2Fplayvalorant.com%2Fopt_in&client_id=play-valorant-web-prod&response_type=token%20id_token&scope=account%20openid&nonce=1"
+	// 构建请求URL - 使用和原始项目完全相同的查询参数
+	authURL := v.endpoints.AuthorizeURL(authorizeQuery)
 
 	// 创建请求
-	req, err := http.NewRequest(http.MethodGet, authURL, nil)
+	req, cancel, err := v.newRequest(ctx, RiotCallAuthorize, http.MethodGet, authURL, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer cancel()
 
 	// 添加所有必要的头部
 	setRiotRequestHeaders(req, essentialCookies)
 
 	// 发送请求
-	resp, err := v.client.Do(req)
+	resp, err := v.send(client, RiotCallAuthorize, req)
 	if err != nil {
 		return nil, err
 	}
@@ -934,7 +1016,8 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[st
 	// 检查状态码，应该是302或303（重定向）
 	if resp.StatusCode != http.StatusFound && resp.StatusCode != http.StatusSeeOther {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("认证请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
+		fmt.Printf("Cookie授权失败，状态码: %d\n", resp.StatusCode)
+		return nil, fmt.Errorf("认证请求失败: %w", riotStatusError(RiotCallAuthorize, resp.StatusCode, string(bodyBytes)))
 	}
 
 	// 获取Location头部
@@ -944,7 +1027,7 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[st
 	}
 
 	if strings.Contains(location, "/login") {
-		return nil, errors.New("Cookie无效或已过期")
+		return nil, fmt.Errorf("%w: Cookie已过期", ErrInvalidCredentials)
 	}
 
 	if !strings.Contains(location, "access_token=") {
@@ -957,48 +1040,56 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthorizeEndpoint(cookies map[st
 		return nil, fmt.Errorf("提取访问令牌失败: %w", err)
 	}
 
+	// Riot会在重新认证时轮换ssid等Cookie，使用最新的值覆盖
+	for name, value := range collectJarCookies(client, authURL) {
+		essentialCookies[name] = value
+	}
+
 	// 获取授权令牌
-	entitlementToken, err := v.getEntitlementToken(accessToken)
+	entitlementToken, err := v.getEntitlementToken(ctx, accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("获取授权令牌失败: %w", err)
 	}
 
 	// 获取用户信息
-	userInfo, err := v.getUserInfo(accessToken)
+	userInfo, err := v.getUserInfo(ctx, accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户信息失败: %w", err)
 	}
 
 	// 创建用户会话
 	session := &models.UserSession{
-		UserID:       userInfo.Sub,
-		Username:     userInfo.Email,
-		AccessToken:  accessToken,
-		Entitlement:  entitlementToken,
-		RiotUsername: userInfo.Name,
-		RiotTagline:  userInfo.Tag,
-		Cookies:      essentialCookies,
+		UserID:             userInfo.Sub,
+		Username:           userInfo.Email,
+		AccessToken:        accessToken,
+		Entitlement:        entitlementToken,
+		RiotUsername:       userInfo.Name,
+		RiotTagline:        userInfo.Tag,
+		Cookies:            essentialCookies,
+		RiotTokenExpiresAt: parseTokenExpiry(location),
 	}
 
 	return session, nil
 }
 
 // 备用认证方法 - 通过auth端点 (模仿原始项目)
-func (v *ValorantAPI) authenticateWithCookiesViaAuthEndpoint(cookies map[string]string) (*models.UserSession, error) {
+func (v *ValorantAPI) authenticateWithCookiesViaAuthEndpoint(ctx context.Context, cookies map[string]string) (*models.UserSession, error) {
 	// 创建带有cookie jar的新HTTP客户端
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, err
 	}
 
+	// 该客户端只用于本次认证，不与其他用户共享
+	// 不跟随重定向：Cookie失效时authorize会跳转到Riot的登录页，只需要保留第一次响应下发的Cookie
 	client := &http.Client{
-		Jar:     jar,
-		Timeout: 30 * time.Second,
+		Jar:       jar,
+		Transport: v.transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
 
-	// 使用这个带cookie的客户端替换当前的客户端
-	v.client = client
-
 	// 过滤保留有用的Cookie
 	essentialCookies := FilterEssentialCookies(cookies)
 	if len(essentialCookies) == 0 {
@@ -1006,21 +1097,22 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthEndpoint(cookies map[string]
 	}
 
 	// 定义API端点URL
-	userInfoURL := "https://auth.riotgames.com/userinfo"
+	userInfoURL := v.endpoints.UserInfoURL()
 
 	// 尝试原始项目的方法: 直接通过ssid获取信息
 	if ssid, ok := essentialCookies["ssid"]; ok {
 		// 尝试使用ssid直接请求用户信息
-		req, err := http.NewRequest(http.MethodGet, userInfoURL, nil)
+		req, cancel, err := v.newRequest(ctx, RiotCallUserInfo, http.MethodGet, userInfoURL, nil)
 		if err != nil {
 			return nil, err
 		}
+		defer cancel()
 
 		// 使用ssid作为Bearer令牌
 		req.Header.Add("Authorization", "Bearer "+ssid)
 		req.Header.Add("Content-Type", "application/json")
 
-		resp, err := v.client.Do(req)
+		resp, err := v.send(client, RiotCallUserInfo, req)
 		if err != nil {
 			return nil, err
 		}
@@ -1034,20 +1126,21 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthEndpoint(cookies map[string]
 			}
 
 			// 尝试获取entitlement令牌
-			entitlementToken, err := v.getEntitlementToken(ssid)
+			entitlementToken, err := v.getEntitlementToken(ctx, ssid)
 			if err != nil {
 				return nil, fmt.Errorf("获取授权令牌失败: %w", err)
 			}
 
 			// 创建会话
 			session := &models.UserSession{
-				UserID:       userInfo.Sub,
-				Username:     userInfo.Email,
-				AccessToken:  ssid,
-				Entitlement:  entitlementToken,
-				RiotUsername: userInfo.Name,
-				RiotTagline:  userInfo.Tag,
-				Cookies:      essentialCookies,
+				UserID:             userInfo.Sub,
+				Username:           userInfo.Email,
+				AccessToken:        ssid,
+				Entitlement:        entitlementToken,
+				RiotUsername:       userInfo.Name,
+				RiotTagline:        userInfo.Tag,
+				Cookies:            essentialCookies,
+				RiotTokenExpiresAt: time.Now().Add(defaultRiotTokenLifetime).Unix(),
 			}
 
 			return session, nil
@@ -1055,30 +1148,32 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthEndpoint(cookies map[string]
 	}
 
 	// 第一步：尝试获取当前的cookie状态
-	authCheckURL := "https://auth.riotgames.com/authorize?redirect_uri=https%3A%2F%2Fplayvalorant.com%2Fopt_in&client_id=play-valorant-web-prod&response_type=token%20id_token&nonce=1"
-	req, err := http.NewRequest(http.MethodGet, authCheckURL, nil)
+	authCheckURL := v.endpoints.AuthorizeURL(authorizeCheckQuery)
+	req, cancel, err := v.newRequest(ctx, RiotCallAuthorize, http.MethodGet, authCheckURL, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer cancel()
 
 	// 添加Cookie和请求头
 	setRiotRequestHeaders(req, essentialCookies)
 
 	// 发送请求
-	resp, err := v.client.Do(req)
+	resp, err := v.send(client, RiotCallAuthorize, req)
 	if err != nil {
 		return nil, err
 	}
 	resp.Body.Close()
 
 	// 第二步：尝试获取认证状态
-	req, err = http.NewRequest(http.MethodGet, userInfoURL, nil)
+	req, cancelUserInfo, err := v.newRequest(ctx, RiotCallUserInfo, http.MethodGet, userInfoURL, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer cancelUserInfo()
 
 	// 尝试从Response中获取Cookie并添加到请求
-	for _, cookie := range v.client.Jar.Cookies(req.URL) {
+	for _, cookie := range client.Jar.Cookies(req.URL) {
 		essentialCookies[cookie.Name] = cookie.Value
 	}
 
@@ -1086,72 +1181,85 @@ func (v *ValorantAPI) authenticateWithCookiesViaAuthEndpoint(cookies map[string]
 	setRiotRequestHeaders(req, essentialCookies)
 
 	// 发送请求
-	resp, err = v.client.Do(req)
+	resp, err = v.send(client, RiotCallUserInfo, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	// 如果能够获取用户信息，说明Cookie有效
-	if resp.StatusCode == http.StatusOK {
-		var userInfo models.ValorantUserInfoResponse
-		if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-			return nil, fmt.Errorf("解析用户信息失败: %w", err)
-		}
+	// 获取不到用户信息时按状态码判断是Cookie失效还是Riot不可用
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("备用认证方法失败: %w", cookieStatusError(RiotCallUserInfo, resp.StatusCode))
+	}
 
-		// 尝试获取authorization token
-		req, err = http.NewRequest(http.MethodPost, "https://auth.riotgames.com/api/token/v1", bytes.NewBuffer([]byte("{}")))
-		if err != nil {
-			return nil, err
-		}
+	var userInfo models.ValorantUserInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
+		return nil, fmt.Errorf("解析用户信息失败: %w", err)
+	}
 
-		setRiotRequestHeaders(req, essentialCookies)
+	// 尝试获取authorization token
+	req, cancelToken, err := v.newRequest(ctx, RiotCallAuthorize, http.MethodPost, v.endpoints.AuthTokenURL(), bytes.NewBuffer([]byte("{}")))
+	if err != nil {
+		return nil, err
+	}
+	defer cancelToken()
 
-		resp, err = v.client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		resp.Body.Close()
+	setRiotRequestHeaders(req, essentialCookies)
 
-		// 尝试获取entitlements token
-		req, err = http.NewRequest(http.MethodPost, "https://entitlements.auth.riotgames.com/api/token/v1", bytes.NewBuffer([]byte("{}")))
-		if err != nil {
-			return nil, err
-		}
+	resp, err = v.send(client, RiotCallAuthorize, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
 
-		setRiotRequestHeaders(req, essentialCookies)
+	// 尝试获取entitlements token
+	req, cancelEntitlements, err := v.newRequest(ctx, RiotCallEntitlements, http.MethodPost, v.endpoints.EntitlementsTokenURL(), bytes.NewBuffer([]byte("{}")))
+	if err != nil {
+		return nil, err
+	}
+	defer cancelEntitlements()
 
-		resp, err = v.client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
+	setRiotRequestHeaders(req, essentialCookies)
 
-		var entitlementResp struct {
-			EntitlementToken string `json:"entitlements_token"`
-		}
+	resp, err = v.send(client, RiotCallEntitlements, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-		if resp.StatusCode == http.StatusOK {
-			if err := json.NewDecoder(resp.Body).Decode(&entitlementResp); err != nil {
-				return nil, fmt.Errorf("解析entitlement令牌失败: %w", err)
-			}
+	var entitlementResp struct {
+		EntitlementToken string `json:"entitlements_token"`
+	}
 
-			// 创建临时会话
-			session := &models.UserSession{
-				UserID:       userInfo.Sub,
-				Username:     userInfo.Email,
-				AccessToken:  getTokenValue(essentialCookies), // 优先使用ssid
-				Entitlement:  entitlementResp.EntitlementToken,
-				RiotUsername: userInfo.Name,
-				RiotTagline:  userInfo.Tag,
-				Cookies:      essentialCookies,
-			}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("备用认证方法失败: %w", cookieStatusError(RiotCallEntitlements, resp.StatusCode))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entitlementResp); err != nil {
+		return nil, fmt.Errorf("解析entitlement令牌失败: %w", err)
+	}
 
-			return session, nil
-		}
+	// 创建临时会话
+	session := &models.UserSession{
+		UserID:             userInfo.Sub,
+		Username:           userInfo.Email,
+		AccessToken:        getTokenValue(essentialCookies), // 优先使用ssid
+		Entitlement:        entitlementResp.EntitlementToken,
+		RiotUsername:       userInfo.Name,
+		RiotTagline:        userInfo.Tag,
+		Cookies:            essentialCookies,
+		RiotTokenExpiresAt: time.Now().Add(defaultRiotTokenLifetime).Unix(),
 	}
 
-	return nil, errors.New("备用认证方法失败")
+	return session, nil
+}
+
+// cookieStatusError 根据Cookie认证请求的失败状态码构建错误
+// 401和403表示Cookie已失效，其他状态码按riotStatusError分类，Riot的5xx不会被当作Cookie失效
+func cookieStatusError(call string, statusCode int) error {
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: Cookie已失效", ErrInvalidCredentials)
+	}
+	return riotStatusError(call, statusCode, "")
 }
 
 // getTokenValue 从cookie中获取合适的token值
@@ -1202,135 +1310,50 @@ func parseAccessTokenFromURI(uri string) (string, error) {
 	return accessToken, nil
 }
 
-// StringifyCookies 将cookie map转换为字符串
-func StringifyCookies(cookies map[string]string) string {
-	parts := make([]string, 0, len(cookies))
-	for name, value := range cookies {
-		parts = append(parts, fmt.Sprintf("%s=%s", name, value))
-	}
-	return strings.Join(parts, "; ")
-}
+// parseTokenExpiry 从授权URI的expires_in参数计算令牌过期时间（Unix时间戳）
+func parseTokenExpiry(uri string) int64 {
+	lifetime := defaultRiotTokenLifetime
 
-// retryHTTPRequest 执行HTTP请求，带有指数退避重试
-func (v *ValorantAPI) retryHTTPRequest(req *http.Request, maxRetries int) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	var attempt int
-
-	// 基础等待时间（毫秒）
-	baseWaitMS := 500
-
-	for attempt = 0; attempt <= maxRetries; attempt++ {
-		// 除了第一次尝试外，记录重试次数
-		if attempt > 0 {
-			waitTime := time.Duration(baseWaitMS*1<<uint(attempt-1)) * time.Millisecond
-			if waitTime > 10*time.Second {
-				waitTime = 10 * time.Second // 最大等待10秒
+	if u, err := url.Parse(uri); err == nil {
+		if values, err := url.ParseQuery(u.Fragment); err == nil {
+			if seconds, err := strconv.Atoi(values.Get("expires_in")); err == nil && seconds > 0 {
+				lifetime = time.Duration(seconds) * time.Second
 			}
-			fmt.Printf("第%d次重试请求 %s %s，等待%v后重试...\n",
-				attempt, req.Method, req.URL.String(), waitTime)
-			time.Sleep(waitTime)
-
-			// 重新创建请求，避免使用已关闭的请求
-			newReq := req.Clone(req.Context())
-			// 复制原始请求头
-			newReq.Header = req.Header.Clone()
-			req = newReq
-		}
-
-		resp, err = v.client.Do(req)
-
-		// 如果没有错误，直接返回响应
-		if err == nil {
-			if attempt > 0 {
-				fmt.Printf("在第%d次尝试后成功执行请求\n", attempt)
-			}
-			return resp, nil
-		}
-
-		// 检查错误类型，决定是否继续重试
-		if attempt == maxRetries {
-			fmt.Printf("达到最大重试次数(%d)，放弃请求\n", maxRetries)
-			break
-		}
-
-		// 判断错误类型，只对网络相关错误进行重试
-		if isNetworkError(err) {
-			fmt.Printf("检测到网络错误: %v, 将重试...\n", err)
-			continue
-		} else {
-			// 其他错误不重试
-			fmt.Printf("收到非网络错误，放弃重试: %v\n", err)
-			break
 		}
 	}
 
-	return nil, fmt.Errorf("请求失败，经过%d次尝试: %w", attempt, err)
+	return time.Now().Add(lifetime).Unix()
 }
 
-// isNetworkError 检查是否为网络连接相关错误
-func isNetworkError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	// 检查常见的网络错误
-	if _, ok := err.(net.Error); ok {
-		// 网络错误通常都需要重试
-		return true
-	}
-
-	// 检查URL错误，比如DNS查询失败等
-	if urlErr, ok := err.(*url.Error); ok {
-		// URL错误通常是由底层错误引起的，递归检查
-		return isNetworkError(urlErr.Err)
-	}
-
-	// 检查HTTP客户端错误
-	if strings.Contains(err.Error(), "Client.Timeout") {
-		return true
-	}
-
-	// 检查标准错误字符串
-	errMsg := err.Error()
-
-	// 连接相关错误
-	if strings.Contains(errMsg, "connection") && (strings.Contains(errMsg, "reset") ||
-		strings.Contains(errMsg, "closed") ||
-		strings.Contains(errMsg, "refused") ||
-		strings.Contains(errMsg, "broken")) {
-		return true
+// collectJarCookies 从客户端的cookie jar中取出指定URL对应的Cookie
+func collectJarCookies(client *http.Client, rawURL string) map[string]string {
+	cookies := make(map[string]string)
+	if client == nil || client.Jar == nil {
+		return cookies
 	}
 
-	// EOF相关错误
-	if strings.Contains(errMsg, "EOF") ||
-		strings.Contains(errMsg, "unexpected EOF") ||
-		strings.Contains(errMsg, "read: connection closed") ||
-		strings.Contains(errMsg, "body closed") {
-		return true
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return cookies
 	}
 
-	// 超时相关错误
-	if strings.Contains(errMsg, "timeout") ||
-		strings.Contains(errMsg, "deadline exceeded") {
-		return true
+	for _, cookie := range client.Jar.Cookies(u) {
+		cookies[cookie.Name] = cookie.Value
 	}
+	return cookies
+}
 
-	// 网络繁忙或服务器错误
-	if strings.Contains(errMsg, "server closed idle connection") ||
-		strings.Contains(errMsg, "no such host") ||
-		strings.Contains(errMsg, "TLS handshake timeout") ||
-		strings.Contains(errMsg, "503") || // 服务不可用
-		strings.Contains(errMsg, "504") { // 网关超时
-		return true
+// StringifyCookies 将cookie map转换为字符串
+func StringifyCookies(cookies map[string]string) string {
+	parts := make([]string, 0, len(cookies))
+	for name, value := range cookies {
+		parts = append(parts, fmt.Sprintf("%s=%s", name, value))
 	}
-
-	// 默认情况下，未知错误不视为网络错误
-	return false
+	return strings.Join(parts, "; ")
 }
 
-// addCommonHeaders 添加HTTP请求所需的通用头信息
-func (v *ValorantAPI) addCommonHeaders(req *http.Request) {
+// addCommonHeaders 添加HTTP请求所需的通用头信息，令牌为空时不设置对应的头
+func (v *ValorantAPI) addCommonHeaders(req *http.Request, accessToken, entitlementToken string) {
 	// 设置通用请求头
 	req.Header.Set("User-Agent", "RiotClient/43.0.1.4195386.4190634 rso-auth (Windows;10;;Professional, x64)")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
@@ -1343,10 +1366,10 @@ func (v *ValorantAPI) addCommonHeaders(req *http.Request) {
 	req.Header.Set("Cookie", "dummy=value")
 
 	// 特定头信息设置
-	if v.entitlementsToken != "" {
-		req.Header.Set("X-Riot-Entitlements-JWT", v.entitlementsToken)
+	if entitlementToken != "" {
+		req.Header.Set("X-Riot-Entitlements-JWT", entitlementToken)
 	}
-	if v.accessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+v.accessToken)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
 	}
 }
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+const (
+	// WebhooksFileName Webhook和推送记录在数据目录下的文件名
+	WebhooksFileName = "webhooks.json"
+
+	// maxWebhookDeliveries 每个Webhook最多保留的推送记录数
+	maxWebhookDeliveries = 50
+)
+
+// ErrWebhookNotFound Webhook不存在
+var ErrWebhookNotFound = errors.New("Webhook不存在")
+
+// webhookData Webhook存储的持久化结构
+type webhookData struct {
+	Webhooks   map[string]*models.Webhook           `json:"webhooks"`   // Webhook ID -> Webhook
+	Deliveries map[string][]*models.WebhookDelivery `json:"deliveries"` // Webhook ID -> 推送记录，按时间从旧到新
+}
+
+// WebhookStore 保存用户注册的Webhook和推送记录
+// 所有数据保存在内存中，每次修改后整体写回文件
+type WebhookStore struct {
+	data     webhookData
+	filePath string
+	mutex    sync.RWMutex
+}
+
+// NewWebhookStore 创建Webhook存储，并加载已有的数据文件
+func NewWebhookStore(filePath string) (*WebhookStore, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+
+	store := &WebhookStore{
+		data: webhookData{
+			Webhooks:   make(map[string]*models.Webhook),
+			Deliveries: make(map[string][]*models.WebhookDelivery),
+		},
+		filePath: absPath,
+	}
+
+	raw, err := os.ReadFile(absPath)
+	if err != nil {
+		// 文件不存在不是错误
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("读取Webhook文件失败: %w", err)
+	}
+
+	if err := json.Unmarshal(raw, &store.data); err != nil {
+		return nil, fmt.Errorf("解析Webhook文件失败: %w", err)
+	}
+
+	// 旧文件中可能缺少某些字段
+	if store.data.Webhooks == nil {
+		store.data.Webhooks = make(map[string]*models.Webhook)
+	}
+	if store.data.Deliveries == nil {
+		store.data.Deliveries = make(map[string][]*models.WebhookDelivery)
+	}
+
+	return store, nil
+}
+
+// saveLocked 将所有数据写回文件，调用方需持有写锁
+func (w *WebhookStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(w.filePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	raw, err := json.MarshalIndent(w.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化Webhook数据失败: %w", err)
+	}
+
+	// 文件中包含签名密钥，只允许当前用户读取
+	tmpPath := w.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0600); err != nil {
+		return fmt.Errorf("写入Webhook文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, w.filePath); err != nil {
+		return fmt.Errorf("替换Webhook文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// cloneWebhook 复制Webhook，避免调用方与存储共享切片
+func cloneWebhook(webhook *models.Webhook) *models.Webhook {
+	clone := *webhook
+	clone.Events = append([]string(nil), webhook.Events...)
+	return &clone
+}
+
+// Get 获取Webhook，不存在时返回ErrWebhookNotFound
+func (w *WebhookStore) Get(id string) (*models.Webhook, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	webhook, exists := w.data.Webhooks[id]
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+	return cloneWebhook(webhook), nil
+}
+
+// Put 保存或覆盖Webhook
+func (w *WebhookStore) Put(webhook *models.Webhook) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.data.Webhooks[webhook.ID] = cloneWebhook(webhook)
+	return w.saveLocked()
+}
+
+// Delete 删除Webhook及其推送记录
+func (w *WebhookStore) Delete(id string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, exists := w.data.Webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+	delete(w.data.Webhooks, id)
+	delete(w.data.Deliveries, id)
+	return w.saveLocked()
+}
+
+// ListByUser 列出用户的所有Webhook，按创建时间排列
+func (w *WebhookStore) ListByUser(userID string) []*models.Webhook {
+	return w.list(func(webhook *models.Webhook) bool {
+		return webhook.UserID == userID
+	})
+}
+
+// ListByEvent 列出订阅了指定事件的所有Webhook
+func (w *WebhookStore) ListByEvent(event string) []*models.Webhook {
+	return w.list(func(webhook *models.Webhook) bool {
+		return slices.Contains(webhook.Events, event)
+	})
+}
+
+// list 按条件列出Webhook
+func (w *WebhookStore) list(match func(webhook *models.Webhook) bool) []*models.Webhook {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	webhooks := make([]*models.Webhook, 0)
+	for _, webhook := range w.data.Webhooks {
+		if match(webhook) {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	slices.SortFunc(webhooks, func(a, b *models.Webhook) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	return webhooks
+}
+
+// SaveDelivery 保存或更新推送记录，只保留最近的记录
+func (w *WebhookStore) SaveDelivery(delivery *models.WebhookDelivery) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// Webhook已被删除时不再记录
+	if _, exists := w.data.Webhooks[delivery.WebhookID]; !exists {
+		return nil
+	}
+
+	clone := *delivery
+	deliveries := w.data.Deliveries[delivery.WebhookID]
+	index := slices.IndexFunc(deliveries, func(d *models.WebhookDelivery) bool {
+		return d.ID == delivery.ID
+	})
+	if index >= 0 {
+		deliveries[index] = &clone
+	} else {
+		deliveries = append(deliveries, &clone)
+	}
+	if len(deliveries) > maxWebhookDeliveries {
+		deliveries = deliveries[len(deliveries)-maxWebhookDeliveries:]
+	}
+	w.data.Deliveries[delivery.WebhookID] = deliveries
+
+	return w.saveLocked()
+}
+
+// ListDeliveries 列出Webhook的推送记录，按时间从新到旧排列
+func (w *WebhookStore) ListDeliveries(webhookID string) []models.WebhookDelivery {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	deliveries := w.data.Deliveries[webhookID]
+	result := make([]models.WebhookDelivery, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		result = append(result, *deliveries[i])
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+const (
+	// WishlistsFileName 愿望单在数据目录下的文件名
+	WishlistsFileName = "wishlists.json"
+)
+
+// WishlistStore 保存用户的皮肤愿望单和匹配记录
+// 所有愿望单保存在内存中，每次修改后整体写回文件
+type WishlistStore struct {
+	wishlists map[string]*models.Wishlist
+	filePath  string
+	mutex     sync.RWMutex
+}
+
+// NewWishlistStore 创建愿望单存储，并加载已有的数据文件
+func NewWishlistStore(filePath string) (*WishlistStore, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+
+	store := &WishlistStore{
+		wishlists: make(map[string]*models.Wishlist),
+		filePath:  absPath,
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		// 文件不存在不是错误
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("读取愿望单文件失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.wishlists); err != nil {
+		return nil, fmt.Errorf("解析愿望单文件失败: %w", err)
+	}
+
+	return store, nil
+}
+
+// cloneWishlist 复制愿望单，避免调用方与存储共享切片
+func cloneWishlist(wishlist *models.Wishlist) *models.Wishlist {
+	clone := *wishlist
+	clone.SkinIDs = append([]string(nil), wishlist.SkinIDs...)
+	clone.Matches = append([]models.WishlistMatch(nil), wishlist.Matches...)
+	return &clone
+}
+
+// saveLocked 将所有愿望单写回文件，调用方需持有写锁
+func (w *WishlistStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(w.filePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(w.wishlists, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化愿望单失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := w.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入愿望单文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, w.filePath); err != nil {
+		return fmt.Errorf("替换愿望单文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// Get 获取用户的愿望单，不存在时返回false
+func (w *WishlistStore) Get(userID string) (*models.Wishlist, bool) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	wishlist, exists := w.wishlists[userID]
+	if !exists {
+		return nil, false
+	}
+	return cloneWishlist(wishlist), true
+}
+
+// Update 在写锁内修改用户的愿望单并写回文件，愿望单不存在时传入空愿望单
+// fn返回错误时不保存任何修改
+func (w *WishlistStore) Update(userID string, fn func(wishlist *models.Wishlist) error) (*models.Wishlist, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	wishlist := &models.Wishlist{UserID: userID}
+	if existing, exists := w.wishlists[userID]; exists {
+		wishlist = cloneWishlist(existing)
+	}
+
+	if err := fn(wishlist); err != nil {
+		return nil, err
+	}
+
+	previous, existed := w.wishlists[userID]
+	w.wishlists[userID] = wishlist
+	if err := w.saveLocked(); err != nil {
+		// 写入失败时恢复内存中的数据
+		if existed {
+			w.wishlists[userID] = previous
+		} else {
+			delete(w.wishlists, userID)
+		}
+		return nil, err
+	}
+
+	return cloneWishlist(wishlist), nil
+}
+
+// List 列出所有愿望单
+func (w *WishlistStore) List() []*models.Wishlist {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	wishlists := make([]*models.Wishlist, 0, len(w.wishlists))
+	for _, wishlist := range w.wishlists {
+		wishlists = append(wishlists, cloneWishlist(wishlist))
+	}
+	return wishlists
+}
@@ -1,7 +1,13 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/emper0r/val-store/server/internal/config"
@@ -10,73 +16,320 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// SessionCache 定义会话缓存接口
-type SessionCache interface {
-	CacheUserSession(userID string, session *models.UserSession)
+const (
+	// mfaChallengeTimeout 二次验证挑战的有效期
+	mfaChallengeTimeout = 5 * time.Minute
+	// mfaMaxAttempts 每个二次验证挑战允许提交错误验证码的次数，用完后需要重新登录
+	mfaMaxAttempts = 5
+)
+
+var (
+	// ErrInvalidRefreshToken 刷新令牌无效、已过期或已被撤销
+	ErrInvalidRefreshToken = errors.New("刷新令牌无效或已过期，请重新登录")
+	// ErrRefreshTokenReused 刷新令牌被重复使用，整个令牌家族已被撤销
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，为安全起见已注销该登录，请重新登录")
+	// ErrMFAChallengeNotFound 二次验证挑战不存在或已过期，需要重新登录
+	ErrMFAChallengeNotFound = errors.New("二次验证不存在或已过期，请重新登录")
+	// ErrMFATooManyAttempts 验证码错误次数过多，挑战已被删除，需要重新登录
+	ErrMFATooManyAttempts = errors.New("验证码错误次数过多，请重新登录")
+	// ErrInvalidCookies 无法从请求中解析出Cookie
+	ErrInvalidCookies = errors.New("无法解析Cookie字符串，请确保格式正确")
+)
+
+// pendingMFAChallenge 服务端保存的待完成二次验证登录
+// 登录过程的cookie jar只能由一个请求使用，同一挑战的提交通过mutex依次执行
+type pendingMFAChallenge struct {
+	mutex     sync.Mutex
+	pending   *repositories.PendingMFA
+	expiresAt time.Time
+	attempts  int // 已提交的错误验证码次数
 }
 
 // AuthService 处理认证相关的业务逻辑
 type AuthService struct {
 	valorantAPI  *repositories.ValorantAPI
+	tokenStore   *repositories.TokenStore
 	jwtSecret    string
-	tokenExpiry  time.Duration
-	sessionCache SessionCache // 使用接口替代具体类型
+	tokenExpiry  time.Duration   // 刷新令牌和会话的有效期
+	accessExpiry time.Duration   // JWT访问令牌的有效期，过期后使用刷新令牌换取新令牌
+	sessions     *SessionService // 保存Riot会话，为空时不保存
+
+	mfaMutex      sync.Mutex
+	mfaChallenges map[string]*pendingMFAChallenge // 挑战ID -> 待完成的登录
 }
 
 // NewAuthService 创建新的认证服务
-func NewAuthService(valorantAPI *repositories.ValorantAPI) *AuthService {
-	// 从环境变量获取JWT密钥，默认为一个随机字符串（仅用于开发环境）
-	jwtSecret := config.GetEnv("JWT_SECRET", "val-store-secret-key-development-only")
-	// JWT令牌有效期，默认24小时
-	tokenExpiry := 24 * time.Hour
-
+func NewAuthService(cfg *config.Config, valorantAPI *repositories.ValorantAPI, tokenStore *repositories.TokenStore) *AuthService {
 	return &AuthService{
-		valorantAPI: valorantAPI,
-		jwtSecret:   jwtSecret,
-		tokenExpiry: tokenExpiry,
+		valorantAPI:   valorantAPI,
+		tokenStore:    tokenStore,
+		jwtSecret:     cfg.JWTSecret,
+		tokenExpiry:   cfg.JWTExpiration(),
+		accessExpiry:  cfg.AccessTokenExpiration(),
+		mfaChallenges: make(map[string]*pendingMFAChallenge),
 	}
 }
 
-// SetSessionCache 设置会话缓存
-func (s *AuthService) SetSessionCache(cache SessionCache) {
-	s.sessionCache = cache
+// SetSessionService 设置保存Riot会话的会话服务
+func (s *AuthService) SetSessionService(sessions *SessionService) {
+	s.sessions = sessions
 }
 
 // Login 处理用户登录，返回JWT令牌
-func (s *AuthService) Login(username, password string) (*models.UserTokensResponse, error) {
+// 如果账号开启了二次验证，返回挑战信息，客户端需要调用SubmitMFACode完成登录
+func (s *AuthService) Login(ctx context.Context, username, password string) (*models.UserTokensResponse, *models.MFAChallengeResponse, error) {
 	// 调用Valorant API进行认证
-	session, err := s.valorantAPI.Authenticate(username, password)
+	session, pending, err := s.valorantAPI.Authenticate(ctx, username, password)
 	if err != nil {
-		return nil, fmt.Errorf("认证失败: %w", err)
+		return nil, nil, fmt.Errorf("认证失败: %w", err)
+	}
+
+	// 需要二次验证，保存登录状态并返回挑战信息
+	if pending != nil {
+		challenge, err := s.createMFAChallenge(pending)
+		if err != nil {
+			return nil, nil, fmt.Errorf("创建二次验证失败: %w", err)
+		}
+		return nil, challenge, nil
 	}
 
+	response, err := s.completeLogin(session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, nil, nil
+}
+
+// SubmitMFACode 提交二次验证码，完成登录并返回JWT令牌
+// 验证码错误mfaMaxAttempts次后删除挑战，防止在有效期内穷举验证码
+func (s *AuthService) SubmitMFACode(ctx context.Context, challengeID, code string, rememberDevice bool) (*models.UserTokensResponse, error) {
+	challenge, exists := s.getMFAChallenge(challengeID)
+	if !exists {
+		return nil, ErrMFAChallengeNotFound
+	}
+
+	challenge.mutex.Lock()
+	defer challenge.mutex.Unlock()
+
+	// 等待期间其他请求可能已经完成了登录或用完了尝试次数
+	if current, exists := s.getMFAChallenge(challengeID); !exists || current != challenge {
+		return nil, ErrMFAChallengeNotFound
+	}
+
+	session, err := s.valorantAPI.SubmitMFACode(ctx, challenge.pending, code, rememberDevice)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrMFARequired) {
+			// 其他错误不消耗尝试次数，允许用户在有效期内重试
+			return nil, fmt.Errorf("二次验证失败: %w", err)
+		}
+
+		challenge.attempts++
+		if challenge.attempts >= mfaMaxAttempts {
+			s.deleteMFAChallenge(challengeID)
+			return nil, ErrMFATooManyAttempts
+		}
+		return nil, fmt.Errorf("二次验证失败（还可以尝试%d次）: %w", mfaMaxAttempts-challenge.attempts, err)
+	}
+
+	s.deleteMFAChallenge(challengeID)
+	return s.completeLogin(session)
+}
+
+// getMFAChallenge 获取未过期的二次验证挑战
+func (s *AuthService) getMFAChallenge(challengeID string) (*pendingMFAChallenge, bool) {
+	s.mfaMutex.Lock()
+	defer s.mfaMutex.Unlock()
+
+	s.cleanupExpiredMFAChallenges()
+	challenge, exists := s.mfaChallenges[challengeID]
+	return challenge, exists
+}
+
+// deleteMFAChallenge 删除二次验证挑战
+func (s *AuthService) deleteMFAChallenge(challengeID string) {
+	s.mfaMutex.Lock()
+	defer s.mfaMutex.Unlock()
+
+	delete(s.mfaChallenges, challengeID)
+}
+
+// createMFAChallenge 保存待完成的登录并生成挑战信息
+func (s *AuthService) createMFAChallenge(pending *repositories.PendingMFA) (*models.MFAChallengeResponse, error) {
+	challengeID, err := generateRandomID(16)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(mfaChallengeTimeout)
+
+	s.mfaMutex.Lock()
+	s.cleanupExpiredMFAChallenges()
+	s.mfaChallenges[challengeID] = &pendingMFAChallenge{
+		pending:   pending,
+		expiresAt: expiresAt,
+	}
+	s.mfaMutex.Unlock()
+
+	return &models.MFAChallengeResponse{
+		MFARequired: true,
+		ChallengeID: challengeID,
+		Email:       pending.Multifactor.Email,
+		Method:      pending.Multifactor.Method,
+		CodeLength:  pending.Multifactor.MultiFactorCodeLength,
+		ExpiresAt:   expiresAt.Unix(),
+	}, nil
+}
+
+// cleanupExpiredMFAChallenges 清理过期的二次验证挑战，调用方需持有mfaMutex
+func (s *AuthService) cleanupExpiredMFAChallenges() {
+	now := time.Now()
+	for id, challenge := range s.mfaChallenges {
+		if now.After(challenge.expiresAt) {
+			delete(s.mfaChallenges, id)
+		}
+	}
+}
+
+// completeLogin 保存认证成功的会话并生成登录响应
+func (s *AuthService) completeLogin(session *models.UserSession) (*models.UserTokensResponse, error) {
 	// 如果未设置区域，确保默认为AP
 	if session.Region == "" {
 		session.Region = models.RegionAP
-		// 设置默认区域
-		s.valorantAPI.SetRegion(models.RegionAP)
 	}
 
-	// 如果设置了会话缓存，缓存会话
-	if s.sessionCache != nil {
-		s.sessionCache.CacheUserSession(session.UserID, session)
+	// 会话与刷新令牌同时过期
+	session.ExpiresAt = time.Now().Add(s.tokenExpiry).Unix()
+
+	// 如果设置了会话服务，保存会话
+	if s.sessions != nil {
+		if err := s.sessions.SaveSession(session); err != nil {
+			return nil, fmt.Errorf("保存会话失败: %w", err)
+		}
 	}
 
-	// 生成JWT令牌
-	token, err := s.generateJWT(session)
+	// 每次登录创建一个新的令牌家族
+	familyID, err := generateRandomID(16)
 	if err != nil {
 		return nil, fmt.Errorf("生成令牌失败: %w", err)
 	}
 
+	return s.issueTokens(session, familyID)
+}
+
+// RefreshTokens 使用刷新令牌换取新的访问令牌和刷新令牌
+// 刷新令牌只能使用一次，重复使用说明令牌可能已泄露，会撤销整个令牌家族
+func (s *AuthService) RefreshTokens(refreshToken string) (*models.UserTokensResponse, error) {
+	record, err := s.tokenStore.UseRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if record.Used {
+		fmt.Printf("检测到用户 %s 的刷新令牌被重复使用，撤销令牌家族 %s\n", record.UserID, record.FamilyID)
+		if err := s.tokenStore.RevokeFamily(record.FamilyID, time.Now().Add(s.tokenExpiry)); err != nil {
+			return nil, fmt.Errorf("撤销令牌失败: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	// 没有会话服务时只根据刷新令牌中的用户信息签发新令牌
+	if s.sessions == nil {
+		return s.issueTokens(&models.UserSession{UserID: record.UserID, RiotUsername: record.Username}, record.FamilyID)
+	}
+
+	// 延长会话有效期，与新的刷新令牌同时过期
+	session, err := s.sessions.ExtendSession(record.UserID, time.Now().Add(s.tokenExpiry))
+	if errors.Is(err, ErrSessionNotFound) {
+		// Riot会话已不存在时无法继续使用，需要重新登录
+		if err := s.tokenStore.RevokeFamily(record.FamilyID, time.Now().Add(s.tokenExpiry)); err != nil {
+			fmt.Printf("撤销令牌家族 %s 失败: %v\n", record.FamilyID, err)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(session, record.FamilyID)
+}
+
+// Logout 注销当前登录：撤销当前访问令牌及其所属令牌家族
+func (s *AuthService) Logout(claims *models.JWTClaims) error {
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.tokenStore.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+			return fmt.Errorf("撤销令牌失败: %w", err)
+		}
+	}
+
+	if claims.FamilyID != "" {
+		if err := s.tokenStore.RevokeFamily(claims.FamilyID, time.Now().Add(s.tokenExpiry)); err != nil {
+			return fmt.Errorf("撤销令牌失败: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// LogoutAll 注销用户在所有设备上的登录，并删除保存的Riot会话
+func (s *AuthService) LogoutAll(userID string) error {
+	now := time.Now()
+	if err := s.tokenStore.RevokeUser(userID, now, now.Add(s.tokenExpiry)); err != nil {
+		return fmt.Errorf("撤销令牌失败: %w", err)
+	}
+
+	if s.sessions != nil {
+		if err := s.sessions.DeleteSession(userID); err != nil {
+			return fmt.Errorf("删除会话失败: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// IsTokenRevoked 检查访问令牌是否已被撤销
+func (s *AuthService) IsTokenRevoked(claims *models.JWTClaims) bool {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return s.tokenStore.IsRevoked(claims.ID, claims.FamilyID, claims.UserID, issuedAt)
+}
+
+// issueTokens 为会话签发新的访问令牌和刷新令牌
+func (s *AuthService) issueTokens(session *models.UserSession, familyID string) (*models.UserTokensResponse, error) {
 	// 构建格式化的用户名
 	formattedUsername := session.RiotUsername
 	if session.RiotTagline != "" {
 		formattedUsername = fmt.Sprintf("%s#%s", session.RiotUsername, session.RiotTagline)
 	}
 
+	// 生成JWT令牌
+	token, expiresAt, err := s.generateJWT(session.UserID, formattedUsername, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("生成令牌失败: %w", err)
+	}
+
+	// 生成刷新令牌，服务端只保存哈希值
+	refreshToken, err := generateRandomID(32)
+	if err != nil {
+		return nil, fmt.Errorf("生成刷新令牌失败: %w", err)
+	}
+	err = s.tokenStore.SaveRefreshToken(&models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    session.UserID,
+		Username:  formattedUsername,
+		ExpiresAt: time.Now().Add(s.tokenExpiry).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存刷新令牌失败: %w", err)
+	}
+
 	// 构建响应
 	response := &models.UserTokensResponse{
-		Token: token,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt.Unix(),
 		User: struct {
 			Username string `json:"username"`
 			UserID   string `json:"user_id"`
@@ -90,17 +343,17 @@ func (s *AuthService) Login(username, password string) (*models.UserTokensRespon
 }
 
 // LoginWithCookies 使用Cookie进行登录，返回JWT令牌
-func (s *AuthService) LoginWithCookies(cookieStr string, region string) (*models.UserTokensResponse, error) {
+func (s *AuthService) LoginWithCookies(ctx context.Context, cookieStr string, region string) (*models.UserTokensResponse, error) {
 	// 使用增强版的Cookie解析
 	cookies := repositories.EnhancedParseCookieString(cookieStr)
 
 	// 如果没有解析出任何Cookie，返回错误
 	if len(cookies) == 0 {
-		return nil, fmt.Errorf("无法解析Cookie字符串，请确保格式正确")
+		return nil, ErrInvalidCookies
 	}
 
 	// 调用优化后的认证方法
-	session, err := s.valorantAPI.AuthenticateWithCookies(cookies)
+	session, err := s.valorantAPI.AuthenticateWithCookies(ctx, cookies)
 	if err != nil {
 		return nil, fmt.Errorf("Cookie认证失败: %w", err)
 	}
@@ -108,62 +361,35 @@ func (s *AuthService) LoginWithCookies(cookieStr string, region string) (*models
 	// 直接使用用户提供的区域，不再进行复杂验证
 	if region != "" {
 		session.Region = region
-		s.valorantAPI.SetRegion(region)
 		fmt.Printf("使用用户指定的区域: %s\n", region)
 	} else {
 		// 如果未提供区域，使用默认值AP
 		session.Region = models.RegionAP
-		s.valorantAPI.SetRegion(models.RegionAP)
 		fmt.Printf("未提供区域参数，使用默认区域: %s\n", models.RegionAP)
 	}
 
-	// 生成JWT令牌
-	token, err := s.generateJWT(session)
-	if err != nil {
-		return nil, fmt.Errorf("生成JWT失败: %w", err)
-	}
-
-	// 如果设置了会话缓存，则缓存会话
-	if s.sessionCache != nil {
-		s.sessionCache.CacheUserSession(session.UserID, session)
-	}
-
-	// 构建格式化的用户名
-	formattedUsername := session.RiotUsername
-	if session.RiotTagline != "" {
-		formattedUsername = fmt.Sprintf("%s#%s", session.RiotUsername, session.RiotTagline)
-	}
-
-	// 构建响应
-	response := &models.UserTokensResponse{
-		Token: token,
-		User: struct {
-			Username string `json:"username"`
-			UserID   string `json:"user_id"`
-		}{
-			Username: formattedUsername,
-			UserID:   session.UserID,
-		},
-	}
-
-	return response, nil
+	return s.completeLogin(session)
 }
 
-// generateJWT 生成JWT令牌
-func (s *AuthService) generateJWT(session *models.UserSession) (string, error) {
-	// 构建格式化的用户名
-	formattedUsername := session.RiotUsername
-	if session.RiotTagline != "" {
-		formattedUsername = fmt.Sprintf("%s#%s", session.RiotUsername, session.RiotTagline)
+// generateJWT 生成JWT访问令牌，返回令牌和过期时间
+func (s *AuthService) generateJWT(userID, username, familyID string) (string, time.Time, error) {
+	jti, err := generateRandomID(16)
+	if err != nil {
+		return "", time.Time{}, err
 	}
 
+	now := time.Now()
+	expiresAt := now.Add(s.accessExpiry)
+
 	// 设置JWT声明
 	claims := models.JWTClaims{
-		UserID:   session.UserID,
-		Username: formattedUsername,
+		UserID:   userID,
+		Username: username,
+		FamilyID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -173,10 +399,10 @@ func (s *AuthService) generateJWT(session *models.UserSession) (string, error) {
 	// 使用密钥签名令牌
 	tokenString, err := token.SignedString([]byte(s.jwtSecret))
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
 }
 
 // ValidateToken 验证JWT令牌的有效性并返回声明
@@ -206,3 +432,18 @@ func (s *AuthService) ValidateToken(tokenString string) (*models.JWTClaims, erro
 func (s *AuthService) GetJWTSecret() string {
 	return s.jwtSecret
 }
+
+// generateRandomID 生成指定字节数的随机十六进制字符串
+func generateRandomID(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken 计算刷新令牌的SHA-256哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/config"
+	"github.com/emper0r/val-store/server/internal/mockriot"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// testMFACode 测试账号mfa使用的二次验证码
+const testMFACode = "123456"
+
+// mfaFixture 创建需要二次验证的账号mfa/mfa
+func mfaFixture() *mockriot.Fixture {
+	return &mockriot.Fixture{
+		Accounts: []mockriot.Account{{
+			Username: "mfa",
+			Password: "mfa",
+			PUUID:    "puuid-mfa",
+			Email:    "mfa@example.com",
+			Region:   "ap",
+			MFACode:  testMFACode,
+		}},
+	}
+}
+
+func TestSubmitMFACodeRetriesWrongCode(t *testing.T) {
+	_, api := newMockRiot(t, mfaFixture())
+	authService, _ := newTestAuthService(t, api)
+
+	tokens, challenge, err := authService.Login(t.Context(), "mfa", "mfa")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if tokens != nil || challenge == nil {
+		t.Fatalf("需要二次验证的账号应当返回挑战，得到 tokens=%v challenge=%v", tokens, challenge)
+	}
+
+	if _, err := authService.SubmitMFACode(t.Context(), challenge.ChallengeID, "000000", false); !errors.Is(err, repositories.ErrMFARequired) {
+		t.Fatalf("错误的验证码应当返回ErrMFARequired，得到 %v", err)
+	}
+
+	tokens, err = authService.SubmitMFACode(t.Context(), challenge.ChallengeID, testMFACode, false)
+	if err != nil {
+		t.Fatalf("正确的验证码应当完成登录: %v", err)
+	}
+	if tokens.Token == "" || tokens.User.UserID != "puuid-mfa" {
+		t.Fatalf("登录响应不正确: %+v", tokens)
+	}
+
+	// 挑战只能完成一次
+	if _, err := authService.SubmitMFACode(t.Context(), challenge.ChallengeID, testMFACode, false); !errors.Is(err, ErrMFAChallengeNotFound) {
+		t.Fatalf("已完成的挑战应当返回ErrMFAChallengeNotFound，得到 %v", err)
+	}
+}
+
+func TestSubmitMFACodeLimitsAttempts(t *testing.T) {
+	_, api := newMockRiot(t, mfaFixture())
+	authService, _ := newTestAuthService(t, api)
+
+	_, challenge, err := authService.Login(t.Context(), "mfa", "mfa")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+
+	for i := 1; i < mfaMaxAttempts; i++ {
+		if _, err := authService.SubmitMFACode(t.Context(), challenge.ChallengeID, "000000", false); !errors.Is(err, repositories.ErrMFARequired) {
+			t.Fatalf("第%d次错误的验证码应当返回ErrMFARequired，得到 %v", i, err)
+		}
+	}
+	if _, err := authService.SubmitMFACode(t.Context(), challenge.ChallengeID, "000000", false); !errors.Is(err, ErrMFATooManyAttempts) {
+		t.Fatalf("用完尝试次数后应当返回ErrMFATooManyAttempts，得到 %v", err)
+	}
+
+	// 挑战已删除，正确的验证码也无法完成登录
+	if _, err := authService.SubmitMFACode(t.Context(), challenge.ChallengeID, testMFACode, false); !errors.Is(err, ErrMFAChallengeNotFound) {
+		t.Fatalf("删除的挑战应当返回ErrMFAChallengeNotFound，得到 %v", err)
+	}
+}
+
+func TestSubmitMFACodeConcurrentSubmits(t *testing.T) {
+	_, api := newMockRiot(t, mfaFixture())
+	authService, _ := newTestAuthService(t, api)
+
+	_, challenge, err := authService.Login(t.Context(), "mfa", "mfa")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+
+	// 同一挑战的并发提交依次执行，只有一次能完成登录
+	const submits = 8
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		successes int
+	)
+	for i := 0; i < submits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := authService.SubmitMFACode(t.Context(), challenge.ChallengeID, testMFACode, false); err == nil {
+				mutex.Lock()
+				successes++
+				mutex.Unlock()
+			} else if !errors.Is(err, ErrMFAChallengeNotFound) {
+				t.Errorf("并发提交返回了非预期的错误: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if successes != 1 {
+		t.Fatalf("并发提交同一挑战应当只成功一次，实际成功%d次", successes)
+	}
+}
+
+func TestRefreshTokensRotatesAndDetectsReuse(t *testing.T) {
+	_, api := newMockRiot(t, testFixture(1))
+	tokenStore, err := repositories.NewTokenStore("")
+	if err != nil {
+		t.Fatalf("创建令牌存储失败: %v", err)
+	}
+	cfg := config.Default()
+	cfg.JWTAccessExpiration = "5m"
+	authService := NewAuthService(cfg, api, tokenStore)
+	authService.SetSessionService(NewSessionService(api, repositories.NewMemorySessionStore()))
+
+	first, _, err := authService.Login(t.Context(), "player0", "pass0")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if remaining := time.Until(time.Unix(first.ExpiresAt, 0)); remaining > 5*time.Minute || remaining < 4*time.Minute {
+		t.Fatalf("访问令牌有效期应当为配置的5分钟，剩余 %v", remaining)
+	}
+
+	// 每次刷新都签发新的刷新令牌
+	second, err := authService.RefreshTokens(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("刷新令牌失败: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.Token == first.Token {
+		t.Fatal("刷新后应当返回新的令牌对")
+	}
+	third, err := authService.RefreshTokens(second.RefreshToken)
+	if err != nil {
+		t.Fatalf("使用轮换后的刷新令牌失败: %v", err)
+	}
+
+	// 重复使用已轮换的刷新令牌会撤销整个令牌家族
+	if _, err := authService.RefreshTokens(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("重复使用刷新令牌应当返回ErrRefreshTokenReused，得到 %v", err)
+	}
+	if _, err := authService.RefreshTokens(third.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("令牌家族撤销后最新的刷新令牌也应当失效，得到 %v", err)
+	}
+	claims, err := authService.ValidateToken(third.Token)
+	if err != nil {
+		t.Fatalf("解析访问令牌失败: %v", err)
+	}
+	if !authService.IsTokenRevoked(claims) {
+		t.Fatal("令牌家族撤销后访问令牌应当失效")
+	}
+
+	// 其他登录不受影响
+	other, _, err := authService.Login(t.Context(), "player0", "pass0")
+	if err != nil {
+		t.Fatalf("重新登录失败: %v", err)
+	}
+	if _, err := authService.RefreshTokens(other.RefreshToken); err != nil {
+		t.Fatalf("新登录的刷新令牌应当可用: %v", err)
+	}
+}
+
+func TestRefreshTokensWithoutSessionService(t *testing.T) {
+	_, api := newMockRiot(t, testFixture(1))
+	tokenStore, err := repositories.NewTokenStore("")
+	if err != nil {
+		t.Fatalf("创建令牌存储失败: %v", err)
+	}
+	authService := NewAuthService(config.Default(), api, tokenStore)
+
+	first, _, err := authService.Login(t.Context(), "player0", "pass0")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	second, err := authService.RefreshTokens(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("没有会话服务时刷新令牌失败: %v", err)
+	}
+	if second.User != first.User {
+		t.Fatalf("刷新后的用户信息应当不变，得到 %+v，期望 %+v", second.User, first.User)
+	}
+}
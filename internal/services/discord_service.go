@@ -0,0 +1,350 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+const (
+	// discordAPIBaseURL Discord API地址，用于发送斜杠命令的后续消息
+	discordAPIBaseURL = "https://discord.com/api/v10"
+	// discordEmbedColor 嵌入消息的颜色
+	discordEmbedColor = 0xFF4655
+)
+
+// ErrDiscordUserIDInvalid Discord用户ID格式无效
+var ErrDiscordUserIDInvalid = errors.New("无效的Discord用户ID")
+
+// discordUserIDPattern Discord用户ID为17到20位数字
+var discordUserIDPattern = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// DiscordService 处理Discord斜杠命令，并管理Discord账号与val-store用户的绑定
+type DiscordService struct {
+	publicKey       ed25519.PublicKey // 为空时不启用Discord交互
+	linkStore       *repositories.LinkStore
+	sessionService  *SessionService
+	shopService     *ShopService
+	userService     *UserService
+	wishlistService *WishlistService
+	client          *http.Client
+	apiBaseURL      string
+}
+
+// NewDiscordService 创建新的Discord服务，publicKey为Discord应用公钥的十六进制，为空时不启用交互
+func NewDiscordService(publicKey string, linkStore *repositories.LinkStore, sessionService *SessionService, shopService *ShopService, userService *UserService, wishlistService *WishlistService) (*DiscordService, error) {
+	service := &DiscordService{
+		linkStore:       linkStore,
+		sessionService:  sessionService,
+		shopService:     shopService,
+		userService:     userService,
+		wishlistService: wishlistService,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		apiBaseURL:      discordAPIBaseURL,
+	}
+
+	if publicKey != "" {
+		key, err := hex.DecodeString(publicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("Discord公钥必须是64位十六进制的Ed25519公钥")
+		}
+		service.publicKey = key
+	}
+
+	return service, nil
+}
+
+// Enabled 是否配置了Discord应用公钥
+func (s *DiscordService) Enabled() bool {
+	return len(s.publicKey) > 0
+}
+
+// VerifySignature 验证Discord请求的Ed25519签名，签名内容为时间戳加请求体
+func (s *DiscordService) VerifySignature(signature, timestamp string, body []byte) bool {
+	if !s.Enabled() || timestamp == "" {
+		return false
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+
+	message := make([]byte, 0, len(timestamp)+len(body))
+	message = append(message, timestamp...)
+	message = append(message, body...)
+	return ed25519.Verify(s.publicKey, message, sig)
+}
+
+// LinkAccount 将Discord用户绑定到val-store用户，已绑定到其他用户时返回repositories.ErrLinkConflict
+func (s *DiscordService) LinkAccount(userID, discordUserID string) error {
+	if !discordUserIDPattern.MatchString(discordUserID) {
+		return ErrDiscordUserIDInvalid
+	}
+	return s.linkStore.Link(repositories.LinkPlatformDiscord, discordUserID, userID)
+}
+
+// UnlinkAccount 解除用户绑定的所有Discord账号
+func (s *DiscordService) UnlinkAccount(userID string) (int, error) {
+	return s.linkStore.UnlinkUser(repositories.LinkPlatformDiscord, userID)
+}
+
+// GetLinkedAccounts 获取用户绑定的Discord用户ID
+func (s *DiscordService) GetLinkedAccounts(userID string) []string {
+	return s.linkStore.ListByUser(repositories.LinkPlatformDiscord, userID)
+}
+
+// HandleInteraction 处理Discord交互
+// 需要请求Riot的命令先返回延迟响应，在后台获取数据后更新消息，避免超过Discord的3秒限制
+func (s *DiscordService) HandleInteraction(ctx context.Context, interaction *models.DiscordInteraction) *models.DiscordInteractionResponse {
+	if interaction.Type == models.DiscordInteractionPing {
+		return &models.DiscordInteractionResponse{Type: models.DiscordResponsePong}
+	}
+	if interaction.Type != models.DiscordInteractionCommand {
+		return discordReply(&models.DiscordMessage{Content: "不支持的交互类型"})
+	}
+
+	discordUserID := ""
+	if interaction.Member != nil {
+		discordUserID = interaction.Member.User.ID
+	} else if interaction.User != nil {
+		discordUserID = interaction.User.ID
+	}
+
+	userID, linked := s.linkStore.GetUserID(repositories.LinkPlatformDiscord, discordUserID)
+	if !linked {
+		return discordReply(&models.DiscordMessage{
+			Content: fmt.Sprintf("你的Discord账号还没有绑定val-store，请登录后调用 `POST /api/integrations/discord/link` 绑定Discord用户ID `%s`", discordUserID),
+		})
+	}
+
+	switch interaction.Data.Name {
+	case "wishlist":
+		return discordReply(s.wishlistMessage(userID))
+	case "shop", "nightmarket", "wallet":
+		// 后台任务在交互请求返回后继续执行，不随请求取消
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			s.sendFollowUp(ctx, interaction, s.commandMessage(ctx, userID, interaction.Data.Name))
+		}()
+		return &models.DiscordInteractionResponse{
+			Type: models.DiscordResponseDeferredMessage,
+			Data: &models.DiscordMessage{Flags: models.DiscordMessageFlagEphemeral},
+		}
+	default:
+		return discordReply(&models.DiscordMessage{Content: "未知命令: /" + interaction.Data.Name})
+	}
+}
+
+// discordReply 立即回复只有命令使用者可见的消息
+func discordReply(message *models.DiscordMessage) *models.DiscordInteractionResponse {
+	message.Flags = models.DiscordMessageFlagEphemeral
+	return &models.DiscordInteractionResponse{
+		Type: models.DiscordResponseChannelMessage,
+		Data: message,
+	}
+}
+
+// commandMessage 使用用户的Riot会话执行需要请求Riot的命令
+func (s *DiscordService) commandMessage(ctx context.Context, userID, command string) *models.DiscordMessage {
+	var message *models.DiscordMessage
+	err := s.sessionService.WithSession(ctx, userID, func(session *models.UserSession) error {
+		switch command {
+		case "shop":
+			shop, err := s.shopService.GetShop(ctx, session, false)
+			if err != nil {
+				return err
+			}
+			message = shopMessage(shop)
+		case "nightmarket":
+			nightMarket, err := s.shopService.GetNightMarket(ctx, session, false)
+			if err != nil {
+				return err
+			}
+			message = nightMarketMessage(nightMarket)
+		case "wallet":
+			wallet, err := s.userService.GetUserWallet(ctx, session)
+			if err != nil {
+				return err
+			}
+			message = walletMessage(wallet)
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		return message
+	case errors.Is(err, ErrSessionNotFound), errors.Is(err, ErrSessionReauthFailed):
+		return &models.DiscordMessage{Content: "Riot会话已过期，请重新登录val-store"}
+	case errors.Is(err, ErrNightMarketNotActive):
+		return &models.DiscordMessage{Content: "当前没有开放的夜市"}
+	case errors.Is(err, repositories.ErrRateLimited):
+		return &models.DiscordMessage{Content: "Riot请求过于频繁，请稍后重试"}
+	default:
+		fmt.Printf("处理Discord命令 /%s 失败: %v\n", command, err)
+		return &models.DiscordMessage{Content: "获取数据失败，请稍后重试"}
+	}
+}
+
+// sendFollowUp 用命令结果替换延迟响应的消息
+func (s *DiscordService) sendFollowUp(ctx context.Context, interaction *models.DiscordInteraction, message *models.DiscordMessage) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		fmt.Printf("序列化Discord消息失败: %v\n", err)
+		return
+	}
+
+	url := fmt.Sprintf("%s/webhooks/%s/%s/messages/@original", s.apiBaseURL, interaction.ApplicationID, interaction.Token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("创建Discord请求失败: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		fmt.Printf("发送Discord消息失败: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		fmt.Printf("发送Discord消息失败，状态码: %d, 响应: %s\n", resp.StatusCode, string(respBody))
+	}
+}
+
+// shopMessage 将每日商店和精选套装转换为嵌入消息
+func shopMessage(shop *models.ShopResponse) *models.DiscordMessage {
+	embeds := make([]models.DiscordEmbed, 0, models.DiscordMaxEmbeds)
+	for _, item := range shop.DailyOffers {
+		embeds = append(embeds, skinEmbed(item, fmt.Sprintf("%d VP", item.FinalPrice)))
+	}
+	for _, bundle := range shop.FeaturedBundles {
+		if len(embeds) >= models.DiscordMaxEmbeds {
+			break
+		}
+		embed := models.DiscordEmbed{
+			Title:       "精选套装: " + bundle.Name,
+			Description: fmt.Sprintf("%d VP，%s结束", bundle.DiscountedPrice, discordTimestamp(bundle.ExpiresAt)),
+			Color:       discordEmbedColor,
+		}
+		if bundle.PromoImageURL != "" {
+			embed.Image = &models.DiscordEmbedImage{URL: bundle.PromoImageURL}
+		}
+		embeds = append(embeds, embed)
+	}
+
+	return &models.DiscordMessage{
+		Content: fmt.Sprintf("每日商店，%s刷新", discordTimestamp(shop.ExpiresAt)),
+		Embeds:  embeds,
+	}
+}
+
+// nightMarketMessage 将夜市转换为嵌入消息
+func nightMarketMessage(nightMarket *models.NightMarketResponse) *models.DiscordMessage {
+	embeds := make([]models.DiscordEmbed, 0, len(nightMarket.Offers))
+	for _, item := range nightMarket.Offers {
+		if len(embeds) >= models.DiscordMaxEmbeds {
+			break
+		}
+		price := fmt.Sprintf("~~%d~~ %d VP（-%d%%）", item.BasePrice, item.FinalPrice, item.DiscountPercent)
+		if !item.Seen {
+			price += "，未翻开"
+		}
+		embeds = append(embeds, skinEmbed(item.ShopItem, price))
+	}
+
+	return &models.DiscordMessage{
+		Content: fmt.Sprintf("夜市，%s结束", discordTimestamp(nightMarket.ExpiresAt)),
+		Embeds:  embeds,
+	}
+}
+
+// skinEmbed 将商店中的皮肤转换为嵌入消息
+func skinEmbed(item models.ShopItem, price string) models.DiscordEmbed {
+	if item.Owned {
+		price += "（已拥有）"
+	}
+	embed := models.DiscordEmbed{
+		Title:       item.Skin.Name,
+		Description: price,
+		Color:       discordEmbedColor,
+	}
+	if item.Skin.IconURL != "" {
+		embed.Thumbnail = &models.DiscordEmbedImage{URL: item.Skin.IconURL}
+	}
+	if item.Skin.TierName != "" {
+		embed.Footer = &models.DiscordEmbedFooter{Text: item.Skin.TierName}
+	}
+	return embed
+}
+
+// walletMessage 将钱包余额转换为嵌入消息
+func walletMessage(wallet *models.WalletResponse) *models.DiscordMessage {
+	return &models.DiscordMessage{
+		Embeds: []models.DiscordEmbed{{
+			Title: "钱包",
+			Color: discordEmbedColor,
+			Fields: []models.DiscordEmbedField{
+				{Name: "VP", Value: fmt.Sprint(wallet.ValorantPoints), Inline: true},
+				{Name: "辐能点", Value: fmt.Sprint(wallet.RadianitePoints), Inline: true},
+				{Name: "王国信用点", Value: fmt.Sprint(wallet.KingdomCredits), Inline: true},
+			},
+		}},
+	}
+}
+
+// wishlistMessage 将愿望单和最近的匹配记录转换为嵌入消息
+func (s *DiscordService) wishlistMessage(userID string) *models.DiscordMessage {
+	wishlist := s.wishlistService.GetWishlist(userID)
+	if len(wishlist.Skins) == 0 {
+		return &models.DiscordMessage{Content: "愿望单是空的"}
+	}
+
+	names := make([]string, 0, len(wishlist.Skins))
+	for _, skin := range wishlist.Skins {
+		names = append(names, "• "+skin.Name)
+	}
+	embed := models.DiscordEmbed{
+		Title:       "愿望单",
+		Description: strings.Join(names, "\n"),
+		Color:       discordEmbedColor,
+	}
+
+	matches := s.wishlistService.GetMatches(userID)
+	if len(matches) > 5 {
+		matches = matches[:5]
+	}
+	if len(matches) > 0 {
+		lines := make([]string, 0, len(matches))
+		for _, match := range matches {
+			lines = append(lines, fmt.Sprintf("%s（%s，%s）", match.SkinName, match.Source, match.Date))
+		}
+		embed.Fields = append(embed.Fields, models.DiscordEmbedField{
+			Name:  "最近出现",
+			Value: strings.Join(lines, "\n"),
+		})
+	}
+
+	return &models.DiscordMessage{Embeds: []models.DiscordEmbed{embed}}
+}
+
+// discordTimestamp 返回Discord的相对时间格式，客户端会按本地时间显示
+func discordTimestamp(unix int64) string {
+	return fmt.Sprintf("<t:%d:R>", unix)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// InventoryService 查询用户拥有的物品，并使用本地皮肤数据库补充物品信息
+type InventoryService struct {
+	valorantAPI  *repositories.ValorantAPI
+	skinDatabase *repositories.SkinDatabase
+}
+
+// NewInventoryService 创建新的库存服务
+func NewInventoryService(valorantAPI *repositories.ValorantAPI, skinDatabase *repositories.SkinDatabase) *InventoryService {
+	return &InventoryService{
+		valorantAPI:  valorantAPI,
+		skinDatabase: skinDatabase,
+	}
+}
+
+// GetEntitlements 获取用户拥有的某一类物品
+func (s *InventoryService) GetEntitlements(ctx context.Context, session *models.UserSession, itemTypeID string) ([]models.Entitlement, error) {
+	entitlements, err := s.valorantAPI.GetEntitlements(ctx, repositories.SessionAuth(session), session.UserID, itemTypeID)
+	if err != nil {
+		return nil, err
+	}
+	return entitlements.Entitlements, nil
+}
+
+// OwnedItemIDs 获取用户拥有的某一类物品的ID集合（小写）
+func (s *InventoryService) OwnedItemIDs(ctx context.Context, session *models.UserSession, itemTypeID string) (map[string]bool, error) {
+	entitlements, err := s.GetEntitlements(ctx, session, itemTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[string]bool, len(entitlements))
+	for _, entitlement := range entitlements {
+		owned[strings.ToLower(entitlement.ItemID)] = true
+	}
+	return owned, nil
+}
+
+// GetInventory 获取用户拥有的皮肤、枪挂、喷漆、玩家卡面和称号
+func (s *InventoryService) GetInventory(ctx context.Context, session *models.UserSession) (*models.InventoryResponse, error) {
+	ownedLevels, err := s.OwnedItemIDs(ctx, session, models.ItemTypeSkinLevel)
+	if err != nil {
+		return nil, fmt.Errorf("获取皮肤等级失败: %w", err)
+	}
+	ownedChromas, err := s.OwnedItemIDs(ctx, session, models.ItemTypeSkinChroma)
+	if err != nil {
+		return nil, fmt.Errorf("获取皮肤颜色变体失败: %w", err)
+	}
+
+	inventory := &models.InventoryResponse{
+		Skins: s.buildOwnedSkins(ownedLevels, ownedChromas),
+	}
+
+	cosmeticTypes := []struct {
+		itemTypeID   string
+		cosmeticType string
+		target       *[]models.Cosmetic
+	}{
+		{models.ItemTypeBuddy, models.CosmeticBuddy, &inventory.Buddies},
+		{models.ItemTypeSpray, models.CosmeticSpray, &inventory.Sprays},
+		{models.ItemTypePlayerCard, models.CosmeticPlayerCard, &inventory.PlayerCards},
+		{models.ItemTypePlayerTitle, models.CosmeticPlayerTitle, &inventory.PlayerTitles},
+	}
+	for _, cosmeticType := range cosmeticTypes {
+		owned, err := s.OwnedItemIDs(ctx, session, cosmeticType.itemTypeID)
+		if err != nil {
+			return nil, fmt.Errorf("获取%s失败: %w", cosmeticType.cosmeticType, err)
+		}
+		*cosmeticType.target = s.buildOwnedCosmetics(owned, cosmeticType.cosmeticType)
+	}
+
+	return inventory, nil
+}
+
+// buildOwnedSkins 将拥有的皮肤等级和颜色变体按皮肤分组
+func (s *InventoryService) buildOwnedSkins(ownedLevels, ownedChromas map[string]bool) []models.OwnedSkin {
+	skins := make(map[string]*models.OwnedSkin)
+
+	ownedSkin := func(itemID string) (*models.OwnedSkin, models.Skin) {
+		skin, found := s.skinDatabase.GetSkinByID(itemID)
+		if !found {
+			// 如果皮肤未找到，使用占位符
+			skin = models.Skin{
+				UUID:       itemID,
+				Name:       "未知皮肤",
+				TierName:   "未知",
+				WeaponName: "未知武器",
+			}
+		}
+		entry, exists := skins[skin.UUID]
+		if !exists {
+			entry = &models.OwnedSkin{
+				Skin:    skin,
+				Levels:  []models.SkinLevel{},
+				Chromas: []models.SkinChroma{},
+			}
+			skins[skin.UUID] = entry
+		}
+		return entry, skin
+	}
+
+	for levelID := range ownedLevels {
+		entry, skin := ownedSkin(levelID)
+		for _, level := range skin.Levels {
+			if strings.EqualFold(level.UUID, levelID) {
+				entry.Levels = append(entry.Levels, level)
+			}
+		}
+	}
+
+	for chromaID := range ownedChromas {
+		entry, skin := ownedSkin(chromaID)
+		for _, chroma := range skin.Chromas {
+			if strings.EqualFold(chroma.UUID, chromaID) {
+				entry.Chromas = append(entry.Chromas, chroma)
+			}
+		}
+	}
+
+	result := make([]models.OwnedSkin, 0, len(skins))
+	for _, entry := range skins {
+		sort.Slice(entry.Levels, func(i, j int) bool { return entry.Levels[i].Name < entry.Levels[j].Name })
+		sort.Slice(entry.Chromas, func(i, j int) bool { return entry.Chromas[i].Name < entry.Chromas[j].Name })
+		result = append(result, *entry)
+	}
+
+	// 按武器名和皮肤名排序
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Skin.WeaponName == result[j].Skin.WeaponName {
+			return result[i].Skin.Name < result[j].Skin.Name
+		}
+		return result[i].Skin.WeaponName < result[j].Skin.WeaponName
+	})
+
+	return result
+}
+
+// buildOwnedCosmetics 将拥有的饰品ID转换为饰品信息，同一饰品的多个等级只保留一个
+func (s *InventoryService) buildOwnedCosmetics(owned map[string]bool, cosmeticType string) []models.Cosmetic {
+	seen := make(map[string]bool, len(owned))
+	cosmetics := make([]models.Cosmetic, 0, len(owned))
+
+	for itemID := range owned {
+		cosmetic, found := s.skinDatabase.GetCosmeticByID(itemID)
+		if !found {
+			cosmetic = models.Cosmetic{
+				UUID: itemID,
+				Type: cosmeticType,
+				Name: "未知物品",
+			}
+		}
+		if seen[cosmetic.UUID] {
+			continue
+		}
+		seen[cosmetic.UUID] = true
+		cosmetics = append(cosmetics, cosmetic)
+	}
+
+	sort.Slice(cosmetics, func(i, j int) bool { return cosmetics[i].Name < cosmetics[j].Name })
+	return cosmetics
+}
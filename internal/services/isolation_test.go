@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+// TestConcurrentUsersDoNotShareRiotState 多个用户并发登录、获取商店和重新认证时，
+// 每个用户的请求只能使用自己的区域和令牌；模拟服务会拒绝使用其他玩家令牌或分片的请求
+func TestConcurrentUsersDoNotShareRiotState(t *testing.T) {
+	const users = 8
+	const rounds = 3
+
+	_, api := newMockRiot(t, testFixture(users))
+	authService, sessionStore := newTestAuthService(t, api)
+	sessionService := NewSessionService(api, sessionStore)
+	shopService := newTestShopService(t, api, sessionStore)
+
+	var wg sync.WaitGroup
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			puuid := fmt.Sprintf("puuid-%d", i)
+			daily := fmt.Sprintf("skin-%d", i)
+
+			tokens, _, err := authService.Login(t.Context(), fmt.Sprintf("player%d", i), fmt.Sprintf("pass%d", i))
+			if err != nil {
+				t.Errorf("用户 %d 登录失败: %v", i, err)
+				return
+			}
+			if tokens.User.UserID != puuid {
+				t.Errorf("用户 %d 登录后得到了 %s 的身份", i, tokens.User.UserID)
+				return
+			}
+
+			for round := 0; round < rounds; round++ {
+				// 每轮都让Riot令牌过期，使获取商店前先用Cookie重新认证
+				expireRiotToken(t, sessionStore, puuid)
+
+				err := sessionService.WithSession(t.Context(), puuid, func(session *models.UserSession) error {
+					if session.UserID != puuid {
+						return fmt.Errorf("获取到了 %s 的会话", session.UserID)
+					}
+					shop, err := shopService.GetShop(t.Context(), session, true)
+					if err != nil {
+						return err
+					}
+					if len(shop.DailyOffers) != 1 || shop.DailyOffers[0].Skin.UUID != daily || shop.DailyOffers[0].ItemType != models.ShopItemSkin {
+						return fmt.Errorf("每日商店应当是 %s，得到 %+v", daily, shop.DailyOffers)
+					}
+					return nil
+				})
+				if err != nil {
+					t.Errorf("用户 %d 第%d轮获取商店失败: %v", i, round+1, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
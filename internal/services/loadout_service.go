@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+var (
+	// ErrLoadoutInvalid 装备修改请求中包含无效的物品或栏位
+	ErrLoadoutInvalid = errors.New("无效的装备")
+	// ErrItemNotOwned 用户没有拥有要装备的物品
+	ErrItemNotOwned = errors.New("未拥有该物品")
+)
+
+// LoadoutService 查询和修改玩家的装备
+type LoadoutService struct {
+	valorantAPI      *repositories.ValorantAPI
+	skinDatabase     *repositories.SkinDatabase
+	inventoryService *InventoryService
+}
+
+// NewLoadoutService 创建新的装备服务
+func NewLoadoutService(valorantAPI *repositories.ValorantAPI, skinDatabase *repositories.SkinDatabase, inventoryService *InventoryService) *LoadoutService {
+	return &LoadoutService{
+		valorantAPI:      valorantAPI,
+		skinDatabase:     skinDatabase,
+		inventoryService: inventoryService,
+	}
+}
+
+// GetLoadout 获取玩家当前的装备，并使用本地皮肤数据库补充物品信息
+func (s *LoadoutService) GetLoadout(ctx context.Context, session *models.UserSession) (*models.LoadoutResponse, error) {
+	loadout, err := s.valorantAPI.GetPlayerLoadout(ctx, repositories.SessionAuth(session), session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolveLoadout(loadout), nil
+}
+
+// UpdateLoadout 修改玩家的装备
+// 校验所有新装备的物品都存在于皮肤数据库且用户已拥有，校验通过后才提交给Riot
+func (s *LoadoutService) UpdateLoadout(ctx context.Context, session *models.UserSession, req *models.LoadoutUpdateRequest) (*models.LoadoutResponse, error) {
+	auth := repositories.SessionAuth(session)
+
+	loadout, err := s.valorantAPI.GetPlayerLoadout(ctx, auth, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := newOwnedItems(ctx, s.inventoryService, s.skinDatabase, session)
+
+	for _, update := range req.Guns {
+		if err := s.applyGunUpdate(loadout, update, owned); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, update := range req.Sprays {
+		if err := s.applySprayUpdate(loadout, update, owned); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.PlayerCardID != "" && !strings.EqualFold(req.PlayerCardID, loadout.Identity.PlayerCardID) {
+		card, err := s.ownedCosmetic(req.PlayerCardID, models.CosmeticPlayerCard, models.ItemTypePlayerCard, owned)
+		if err != nil {
+			return nil, err
+		}
+		loadout.Identity.PlayerCardID = card.UUID
+	}
+
+	if req.PlayerTitleID != "" && !strings.EqualFold(req.PlayerTitleID, loadout.Identity.PlayerTitleID) {
+		title, err := s.ownedCosmetic(req.PlayerTitleID, models.CosmeticPlayerTitle, models.ItemTypePlayerTitle, owned)
+		if err != nil {
+			return nil, err
+		}
+		loadout.Identity.PlayerTitleID = title.UUID
+	}
+
+	updated, err := s.valorantAPI.SetPlayerLoadout(ctx, auth, session.UserID, loadout)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("用户 %s 的装备已更新\n", session.UserID)
+	return s.resolveLoadout(updated), nil
+}
+
+// applyGunUpdate 修改单把武器的皮肤、颜色变体和枪挂
+func (s *LoadoutService) applyGunUpdate(loadout *models.ValorantPlayerLoadout, update models.LoadoutGunUpdate, owned *ownedItems) error {
+	gun := findGun(loadout, update.WeaponID)
+	if gun == nil {
+		return fmt.Errorf("%w: 未知的武器 %s", ErrLoadoutInvalid, update.WeaponID)
+	}
+
+	// 更换皮肤
+	if update.SkinLevelID != "" && !strings.EqualFold(update.SkinLevelID, gun.SkinLevelID) {
+		skin, found := s.skinDatabase.GetSkinByID(update.SkinLevelID)
+		if !found || !hasSkinLevel(skin, update.SkinLevelID) {
+			return fmt.Errorf("%w: 未知的皮肤等级 %s", ErrLoadoutInvalid, update.SkinLevelID)
+		}
+		if !strings.EqualFold(skin.WeaponID, gun.ID) {
+			return fmt.Errorf("%w: 皮肤 %s 不属于该武器", ErrLoadoutInvalid, skin.Name)
+		}
+
+		// 默认皮肤不在用户的物品列表中
+		if !skin.Default {
+			ownedLevels, err := owned.ids(models.ItemTypeSkinLevel)
+			if err != nil {
+				return err
+			}
+			if !ownedLevels[strings.ToLower(update.SkinLevelID)] {
+				return fmt.Errorf("%w: %s", ErrItemNotOwned, skin.Name)
+			}
+		}
+
+		gun.SkinID = skin.UUID
+		gun.SkinLevelID = strings.ToLower(update.SkinLevelID)
+		gun.ChromaID = ""
+		if len(skin.Chromas) > 0 {
+			gun.ChromaID = skin.Chromas[0].UUID
+		}
+	}
+
+	// 更换颜色变体
+	if update.ChromaID != "" && !strings.EqualFold(update.ChromaID, gun.ChromaID) {
+		skin, _ := s.skinDatabase.GetSkinByID(gun.SkinLevelID)
+		index := chromaIndex(skin, update.ChromaID)
+		if index < 0 {
+			return fmt.Errorf("%w: 颜色变体 %s 不属于当前皮肤", ErrLoadoutInvalid, update.ChromaID)
+		}
+
+		// 第一个颜色变体随皮肤一起获得
+		if index > 0 {
+			ownedChromas, err := owned.ids(models.ItemTypeSkinChroma)
+			if err != nil {
+				return err
+			}
+			if !ownedChromas[strings.ToLower(update.ChromaID)] {
+				return fmt.Errorf("%w: %s", ErrItemNotOwned, skin.Chromas[index].Name)
+			}
+		}
+
+		gun.ChromaID = strings.ToLower(update.ChromaID)
+	}
+
+	// 更换或卸下枪挂
+	if update.BuddyLevelID != nil && !strings.EqualFold(*update.BuddyLevelID, gun.CharmLevelID) {
+		if *update.BuddyLevelID == "" {
+			gun.CharmID = ""
+			gun.CharmLevelID = ""
+			gun.CharmInstanceID = ""
+			return nil
+		}
+
+		buddy, found := s.skinDatabase.GetCosmeticByID(*update.BuddyLevelID)
+		if !found || buddy.Type != models.CosmeticBuddy {
+			return fmt.Errorf("%w: 未知的枪挂 %s", ErrLoadoutInvalid, *update.BuddyLevelID)
+		}
+
+		// 每个枪挂实例只能挂在一把武器上
+		instanceID, err := owned.freeBuddyInstance(loadout, *update.BuddyLevelID)
+		if err != nil {
+			return err
+		}
+		if instanceID == "" {
+			return fmt.Errorf("%w: %s（或所有实例都已装备在其他武器上）", ErrItemNotOwned, buddy.Name)
+		}
+
+		gun.CharmID = buddy.UUID
+		gun.CharmLevelID = strings.ToLower(*update.BuddyLevelID)
+		gun.CharmInstanceID = instanceID
+	}
+
+	return nil
+}
+
+// applySprayUpdate 修改喷漆栏位
+func (s *LoadoutService) applySprayUpdate(loadout *models.ValorantPlayerLoadout, update models.LoadoutSprayUpdate, owned *ownedItems) error {
+	var slot *models.LoadoutSpray
+	for i := range loadout.Sprays {
+		if strings.EqualFold(loadout.Sprays[i].EquipSlotID, update.SlotID) {
+			slot = &loadout.Sprays[i]
+			break
+		}
+	}
+	if slot == nil {
+		return fmt.Errorf("%w: 未知的喷漆栏位 %s", ErrLoadoutInvalid, update.SlotID)
+	}
+	if strings.EqualFold(slot.SprayID, update.SprayID) {
+		return nil
+	}
+
+	// 已装备在其他栏位的喷漆视为已拥有（包括默认喷漆）
+	equipped := false
+	for _, spray := range loadout.Sprays {
+		if strings.EqualFold(spray.SprayID, update.SprayID) {
+			equipped = true
+		}
+	}
+
+	spray, found := s.skinDatabase.GetCosmeticByID(update.SprayID)
+	if !found || spray.Type != models.CosmeticSpray {
+		return fmt.Errorf("%w: 未知的喷漆 %s", ErrLoadoutInvalid, update.SprayID)
+	}
+	if !equipped {
+		if _, err := s.ownedCosmetic(update.SprayID, models.CosmeticSpray, models.ItemTypeSpray, owned); err != nil {
+			return err
+		}
+	}
+
+	slot.SprayID = spray.UUID
+	slot.SprayLevelID = nil
+	return nil
+}
+
+// ownedCosmetic 校验饰品存在且用户已拥有
+func (s *LoadoutService) ownedCosmetic(id, cosmeticType, itemTypeID string, owned *ownedItems) (models.Cosmetic, error) {
+	cosmetic, found := s.skinDatabase.GetCosmeticByID(id)
+	if !found || cosmetic.Type != cosmeticType {
+		return models.Cosmetic{}, fmt.Errorf("%w: 未知的%s %s", ErrLoadoutInvalid, cosmeticType, id)
+	}
+
+	ownedIDs, err := owned.ids(itemTypeID)
+	if err != nil {
+		return models.Cosmetic{}, err
+	}
+	if !ownedIDs[strings.ToLower(cosmetic.UUID)] {
+		return models.Cosmetic{}, fmt.Errorf("%w: %s", ErrItemNotOwned, cosmetic.Name)
+	}
+
+	return cosmetic, nil
+}
+
+// resolveLoadout 将Riot返回的装备转换为客户端响应
+func (s *LoadoutService) resolveLoadout(loadout *models.ValorantPlayerLoadout) *models.LoadoutResponse {
+	response := &models.LoadoutResponse{
+		Guns:        make([]models.EquippedGun, 0, len(loadout.Guns)),
+		Sprays:      make([]models.EquippedSpray, 0, len(loadout.Sprays)),
+		PlayerCard:  s.lookupCosmetic(loadout.Identity.PlayerCardID, models.CosmeticPlayerCard),
+		PlayerTitle: s.lookupCosmetic(loadout.Identity.PlayerTitleID, models.CosmeticPlayerTitle),
+		Incognito:   loadout.Incognito,
+	}
+
+	for _, gun := range loadout.Guns {
+		equipped := models.EquippedGun{
+			WeaponID:  gun.ID,
+			SkinLevel: models.SkinLevel{UUID: gun.SkinLevelID},
+			Chroma:    models.SkinChroma{UUID: gun.ChromaID},
+		}
+
+		skin, found := s.skinDatabase.GetSkinByID(gun.SkinLevelID)
+		if !found {
+			// 如果皮肤未找到，使用占位符
+			skin = models.Skin{
+				UUID:       gun.SkinID,
+				Name:       "未知皮肤",
+				TierName:   "未知",
+				WeaponID:   gun.ID,
+				WeaponName: "未知武器",
+			}
+		}
+		equipped.Skin = skin
+		equipped.WeaponName = skin.WeaponName
+
+		for _, level := range skin.Levels {
+			if strings.EqualFold(level.UUID, gun.SkinLevelID) {
+				equipped.SkinLevel = level
+			}
+		}
+		if index := chromaIndex(skin, gun.ChromaID); index >= 0 {
+			equipped.Chroma = skin.Chromas[index]
+		}
+
+		if gun.CharmLevelID != "" {
+			buddy := s.lookupCosmetic(gun.CharmLevelID, models.CosmeticBuddy)
+			equipped.Buddy = &buddy
+		}
+
+		response.Guns = append(response.Guns, equipped)
+	}
+
+	for _, spray := range loadout.Sprays {
+		response.Sprays = append(response.Sprays, models.EquippedSpray{
+			SlotID: spray.EquipSlotID,
+			Spray:  s.lookupCosmetic(spray.SprayID, models.CosmeticSpray),
+		})
+	}
+
+	return response
+}
+
+// lookupCosmetic 从皮肤数据库获取饰品信息，未找到时返回占位符
+func (s *LoadoutService) lookupCosmetic(id, cosmeticType string) models.Cosmetic {
+	if cosmetic, found := s.skinDatabase.GetCosmeticByID(id); found {
+		return cosmetic
+	}
+	return models.Cosmetic{
+		UUID: id,
+		Type: cosmeticType,
+		Name: "未知物品",
+	}
+}
+
+// findGun 在装备中查找指定武器
+func findGun(loadout *models.ValorantPlayerLoadout, weaponID string) *models.LoadoutGun {
+	for i := range loadout.Guns {
+		if strings.EqualFold(loadout.Guns[i].ID, weaponID) {
+			return &loadout.Guns[i]
+		}
+	}
+	return nil
+}
+
+// hasSkinLevel 判断等级是否属于该皮肤
+func hasSkinLevel(skin models.Skin, levelID string) bool {
+	for _, level := range skin.Levels {
+		if strings.EqualFold(level.UUID, levelID) {
+			return true
+		}
+	}
+	return false
+}
+
+// chromaIndex 返回颜色变体在皮肤中的位置，不属于该皮肤时返回-1
+func chromaIndex(skin models.Skin, chromaID string) int {
+	for i, chroma := range skin.Chromas {
+		if strings.EqualFold(chroma.UUID, chromaID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ownedItems 按需获取并缓存用户拥有的物品，一次修改请求中每类物品只请求一次
+// 只在单个请求内使用，因此保存该请求的ctx
+type ownedItems struct {
+	ctx              context.Context
+	inventoryService *InventoryService
+	skinDatabase     *repositories.SkinDatabase
+	session          *models.UserSession
+
+	byType  map[string]map[string]bool
+	buddies []models.Entitlement
+}
+
+// newOwnedItems 创建用户物品缓存
+func newOwnedItems(ctx context.Context, inventoryService *InventoryService, skinDatabase *repositories.SkinDatabase, session *models.UserSession) *ownedItems {
+	return &ownedItems{
+		ctx:              ctx,
+		inventoryService: inventoryService,
+		skinDatabase:     skinDatabase,
+		session:          session,
+		byType:           make(map[string]map[string]bool),
+	}
+}
+
+// ids 获取用户拥有的某一类物品ID，饰品同时包含饰品本身和其等级的UUID
+func (o *ownedItems) ids(itemTypeID string) (map[string]bool, error) {
+	if ids, cached := o.byType[itemTypeID]; cached {
+		return ids, nil
+	}
+
+	ids, err := o.inventoryService.OwnedItemIDs(o.ctx, o.session, itemTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	for id := range ids {
+		if cosmetic, found := o.skinDatabase.GetCosmeticByID(id); found {
+			ids[strings.ToLower(cosmetic.UUID)] = true
+		}
+	}
+
+	o.byType[itemTypeID] = ids
+	return ids, nil
+}
+
+// freeBuddyInstance 返回一个未装备在其他武器上的枪挂实例ID，没有可用实例时返回空字符串
+func (o *ownedItems) freeBuddyInstance(loadout *models.ValorantPlayerLoadout, buddyLevelID string) (string, error) {
+	if o.buddies == nil {
+		entitlements, err := o.inventoryService.GetEntitlements(o.ctx, o.session, models.ItemTypeBuddy)
+		if err != nil {
+			return "", err
+		}
+		o.buddies = entitlements
+	}
+
+	used := make(map[string]bool, len(loadout.Guns))
+	for _, gun := range loadout.Guns {
+		if gun.CharmInstanceID != "" {
+			used[strings.ToLower(gun.CharmInstanceID)] = true
+		}
+	}
+
+	for _, entitlement := range o.buddies {
+		if strings.EqualFold(entitlement.ItemID, buddyLevelID) && !used[strings.ToLower(entitlement.InstanceID)] {
+			return entitlement.InstanceID, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emper0r/val-store/server/internal/config"
+	"github.com/emper0r/val-store/server/internal/mockriot"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// testFixture 创建count个不需要二次验证的账号player<i>/pass<i>，每个账号的每日商店中有一个不同的皮肤
+func testFixture(count int) *mockriot.Fixture {
+	fixture := &mockriot.Fixture{Offers: map[string]int{}}
+	for i := 0; i < count; i++ {
+		daily := fmt.Sprintf("skin-%d", i)
+		fixture.Offers[daily] = 1775
+		fixture.Accounts = append(fixture.Accounts, mockriot.Account{
+			Username: fmt.Sprintf("player%d", i),
+			Password: fmt.Sprintf("pass%d", i),
+			PUUID:    fmt.Sprintf("puuid-%d", i),
+			GameName: fmt.Sprintf("Player%d", i),
+			TagLine:  "TEST",
+			Region:   "eu",
+			Wallet:   mockriot.Wallet{VP: 1000 + i},
+			Shop:     mockriot.Shop{Daily: []string{daily}},
+		})
+	}
+	return fixture
+}
+
+// newMockRiot 启动模拟Riot服务，返回模拟服务和指向它的ValorantAPI
+func newMockRiot(t *testing.T, fixture *mockriot.Fixture) (*mockriot.Server, *repositories.ValorantAPI) {
+	t.Helper()
+
+	mock, err := mockriot.New(fixture)
+	if err != nil {
+		t.Fatalf("创建模拟服务失败: %v", err)
+	}
+	return mock, newMockRiotAPI(t, mock)
+}
+
+// newMockRiotAPI 启动handler并返回指向它的ValorantAPI，handler可以包装模拟服务以记录请求
+func newMockRiotAPI(t *testing.T, handler http.Handler) *repositories.ValorantAPI {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	api := repositories.NewValorantAPIWithTransport(server.Client().Transport, "release-mock", mockriot.Endpoints(server.URL))
+	api.SetRateLimit(0, 1)
+	return api
+}
+
+// newTestAuthService 创建使用内存会话存储的认证服务
+func newTestAuthService(t *testing.T, api *repositories.ValorantAPI) (*AuthService, repositories.SessionStore) {
+	t.Helper()
+
+	tokenStore, err := repositories.NewTokenStore("")
+	if err != nil {
+		t.Fatalf("创建令牌存储失败: %v", err)
+	}
+	sessionStore := repositories.NewMemorySessionStore()
+
+	authService := NewAuthService(config.Default(), api, tokenStore)
+	authService.SetSessionService(NewSessionService(api, sessionStore))
+	return authService, sessionStore
+}
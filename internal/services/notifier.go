@@ -0,0 +1,16 @@
+package services
+
+// EventNotifier 接收用户相关的事件，用于推送通知
+type EventNotifier interface {
+	Notify(userID, event string, data interface{})
+}
+
+// Notifiers 将事件依次转发给多个接收者
+type Notifiers []EventNotifier
+
+// Notify 将事件转发给所有接收者
+func (n Notifiers) Notify(userID, event string, data interface{}) {
+	for _, notifier := range n {
+		notifier.Notify(userID, event, data)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// PriceService 缓存商店价格表，并将价格合并到皮肤数据库
+// 价格表对所有用户相同，但Riot要求携带令牌请求，因此在用户请求商店时按需刷新
+type PriceService struct {
+	valorantAPI     *repositories.ValorantAPI
+	skinDatabase    *repositories.SkinDatabase
+	refreshInterval time.Duration
+
+	mutex     sync.RWMutex
+	costs     map[string]map[string]int // 报价ID或物品ID -> 货币ID -> 价格
+	fetchedAt time.Time
+
+	refreshMutex sync.Mutex // 避免并发请求重复获取价格表
+}
+
+// NewPriceService 创建新的价格服务
+func NewPriceService(valorantAPI *repositories.ValorantAPI, skinDatabase *repositories.SkinDatabase, refreshInterval time.Duration) *PriceService {
+	return &PriceService{
+		valorantAPI:     valorantAPI,
+		skinDatabase:    skinDatabase,
+		refreshInterval: refreshInterval,
+		costs:           make(map[string]map[string]int),
+	}
+}
+
+// stale 判断价格表是否需要刷新
+func (s *PriceService) stale() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.fetchedAt.IsZero() || time.Since(s.fetchedAt) > s.refreshInterval
+}
+
+// EnsureFresh 价格表超过刷新间隔时使用用户的令牌重新获取
+func (s *PriceService) EnsureFresh(ctx context.Context, auth repositories.RiotAuth) error {
+	if !s.stale() {
+		return nil
+	}
+
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+
+	// 等待锁期间其他请求可能已经完成了刷新
+	if !s.stale() {
+		return nil
+	}
+
+	return s.Refresh(ctx, auth)
+}
+
+// Refresh 立即获取价格表并合并到皮肤数据库
+func (s *PriceService) Refresh(ctx context.Context, auth repositories.RiotAuth) error {
+	offersData, err := s.valorantAPI.GetOffers(ctx, auth)
+	if err != nil {
+		return err
+	}
+
+	costs := make(map[string]map[string]int, len(offersData.Offers))
+	for _, offer := range offersData.Offers {
+		if len(offer.Cost) == 0 {
+			continue
+		}
+		// 保留所有货币的价格，包括未知的货币
+		cost := make(map[string]int, len(offer.Cost))
+		for currencyID, amount := range offer.Cost {
+			cost[strings.ToLower(currencyID)] = amount
+		}
+
+		costs[strings.ToLower(offer.OfferID)] = cost
+		// 单个物品的报价，同时以物品ID索引（皮肤的报价ID与皮肤等级ID相同）
+		if len(offer.Rewards) == 1 {
+			costs[strings.ToLower(offer.Rewards[0].ItemID)] = cost
+		}
+	}
+
+	s.mutex.Lock()
+	s.costs = costs
+	s.fetchedAt = time.Now()
+	s.mutex.Unlock()
+
+	changed, err := s.skinDatabase.UpdatePrices(costs)
+	if err != nil {
+		return fmt.Errorf("保存皮肤价格失败: %w", err)
+	}
+
+	fmt.Printf("商店价格已更新，共 %d 个报价，%d 个皮肤价格发生变化\n", len(offersData.Offers), changed)
+	return nil
+}
+
+// GetCost 获取报价或物品的价格，键是货币ID
+func (s *PriceService) GetCost(id string) (map[string]int, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cost, found := s.costs[strings.ToLower(id)]
+	if !found {
+		return nil, false
+	}
+
+	copied := make(map[string]int, len(cost))
+	for currencyID, amount := range cost {
+		copied[currencyID] = amount
+	}
+	return copied, true
+}
+
+// ApplyPrice 使用缓存的价格表填充皮肤价格
+func (s *PriceService) ApplyPrice(skin *models.Skin, id string) {
+	cost, found := s.GetCost(id)
+	if !found {
+		return
+	}
+	skin.Price = cost[models.CurrencyValorantPoints]
+	skin.Costs = cost
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// riotTokenRefreshMargin Riot访问令牌剩余有效期小于该值时提前重新认证
+const riotTokenRefreshMargin = 5 * time.Minute
+
+var (
+	// ErrSessionNotFound 会话不存在，用户需要重新登录
+	ErrSessionNotFound = errors.New("用户会话不存在，请重新登录")
+	// ErrSessionReauthFailed 使用保存的Cookie重新认证失败，用户需要重新登录
+	ErrSessionReauthFailed = errors.New("Riot会话已失效，请重新登录")
+)
+
+// SessionService 管理用户的Riot会话，在访问令牌过期时使用保存的Cookie自动重新认证
+type SessionService struct {
+	valorantAPI  *repositories.ValorantAPI
+	sessionStore repositories.SessionStore
+	notifier     EventNotifier // 会话失效的通知，可以为空
+
+	locksMutex sync.Mutex
+	userLocks  map[string]*sync.Mutex // 每个用户一把锁，避免并发请求重复认证
+}
+
+// NewSessionService 创建新的会话服务
+func NewSessionService(valorantAPI *repositories.ValorantAPI, sessionStore repositories.SessionStore) *SessionService {
+	return &SessionService{
+		valorantAPI:  valorantAPI,
+		sessionStore: sessionStore,
+		userLocks:    make(map[string]*sync.Mutex),
+	}
+}
+
+// SetNotifier 设置会话事件的通知接收者
+func (s *SessionService) SetNotifier(notifier EventNotifier) {
+	s.notifier = notifier
+}
+
+// userLock 获取指定用户的锁
+func (s *SessionService) userLock(userID string) *sync.Mutex {
+	s.locksMutex.Lock()
+	defer s.locksMutex.Unlock()
+
+	lock, exists := s.userLocks[userID]
+	if !exists {
+		lock = &sync.Mutex{}
+		s.userLocks[userID] = lock
+	}
+	return lock
+}
+
+// GetSession 获取用户会话，Riot访问令牌即将过期时自动重新认证
+func (s *SessionService) GetSession(ctx context.Context, userID string) (*models.UserSession, error) {
+	session, exists := s.sessionStore.Get(userID)
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	if !needsRefresh(session) {
+		return session, nil
+	}
+
+	lock := s.userLock(userID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// 等待锁期间其他请求可能已经完成了重新认证
+	session, exists = s.sessionStore.Get(userID)
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	if !needsRefresh(session) {
+		return session, nil
+	}
+
+	return s.reauthenticate(ctx, session)
+}
+
+// SaveSession 保存新登录的会话，覆盖用户原有的会话
+func (s *SessionService) SaveSession(session *models.UserSession) error {
+	lock := s.userLock(session.UserID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return s.sessionStore.Put(session.UserID, session)
+}
+
+// ExtendSession 把会话的过期时间延长到expiresAt，其他字段保持不变
+// 与重新认证使用同一把锁，避免用旧的令牌和Cookie覆盖重新认证的结果
+func (s *SessionService) ExtendSession(userID string, expiresAt time.Time) (*models.UserSession, error) {
+	lock := s.userLock(userID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	session, exists := s.sessionStore.Get(userID)
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	session.ExpiresAt = expiresAt.Unix()
+	if err := s.sessionStore.Put(userID, session); err != nil {
+		return nil, fmt.Errorf("保存会话失败: %w", err)
+	}
+	return session, nil
+}
+
+// DeleteSession 删除用户会话，等待正在进行的重新认证完成，避免会话被重新写回
+func (s *SessionService) DeleteSession(userID string) error {
+	lock := s.userLock(userID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return s.sessionStore.Delete(userID)
+}
+
+// WithSession 使用用户会话执行Riot请求，fn中的请求应当使用同一个ctx
+// 如果Riot返回令牌失效，重新认证后重试一次
+func (s *SessionService) WithSession(ctx context.Context, userID string, fn func(session *models.UserSession) error) error {
+	session, err := s.GetSession(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = fn(session)
+	if !errors.Is(err, repositories.ErrRiotTokenExpired) {
+		return err
+	}
+
+	fmt.Printf("用户 %s 的Riot令牌被拒绝，尝试重新认证\n", userID)
+	session, err = s.Refresh(ctx, userID, session.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	return fn(session)
+}
+
+// Refresh 强制重新认证用户会话
+// staleToken为调用方认为已失效的令牌，如果会话中的令牌已被其他请求更新则直接返回最新会话
+func (s *SessionService) Refresh(ctx context.Context, userID, staleToken string) (*models.UserSession, error) {
+	lock := s.userLock(userID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	session, exists := s.sessionStore.Get(userID)
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	if session.AccessToken != staleToken {
+		return session, nil
+	}
+
+	return s.reauthenticate(ctx, session)
+}
+
+// reauthenticate 使用保存的Cookie重新执行认证流程，并将新令牌和轮换后的Cookie写回会话
+// 调用方需持有该用户的锁
+func (s *SessionService) reauthenticate(ctx context.Context, session *models.UserSession) (*models.UserSession, error) {
+	if len(session.Cookies) == 0 {
+		s.dropSession(session.UserID)
+		return nil, ErrSessionReauthFailed
+	}
+
+	fresh, err := s.valorantAPI.AuthenticateWithCookies(ctx, session.Cookies)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("重新认证已取消: %w", ctx.Err())
+	}
+	// 只有Riot拒绝了Cookie才删除会话；Riot不可用、超时或被限流时保留会话，等待下次请求重试
+	if err != nil && !errors.Is(err, repositories.ErrInvalidCredentials) {
+		fmt.Printf("用户 %s 重新认证失败，保留会话: %v\n", session.UserID, err)
+		return nil, fmt.Errorf("重新认证失败: %w", err)
+	}
+	if err != nil {
+		fmt.Printf("用户 %s 的Cookie已失效: %v\n", session.UserID, err)
+		s.dropSession(session.UserID)
+		return nil, fmt.Errorf("%w: %v", ErrSessionReauthFailed, err)
+	}
+
+	// 只更新令牌相关字段，保留用户选择的区域和会话过期时间
+	session.AccessToken = fresh.AccessToken
+	session.Entitlement = fresh.Entitlement
+	session.RiotTokenExpiresAt = fresh.RiotTokenExpiresAt
+	if len(fresh.Cookies) > 0 {
+		session.Cookies = fresh.Cookies
+	}
+
+	if err := s.sessionStore.Put(session.UserID, session); err != nil {
+		return nil, fmt.Errorf("保存会话失败: %w", err)
+	}
+
+	fmt.Printf("用户 %s 的Riot令牌已刷新\n", session.UserID)
+	return session, nil
+}
+
+// dropSession 删除无法继续使用的会话，并通知用户需要重新登录
+func (s *SessionService) dropSession(userID string) {
+	if err := s.sessionStore.Delete(userID); err != nil {
+		fmt.Printf("删除用户 %s 的会话失败: %v\n", userID, err)
+	}
+
+	if s.notifier != nil {
+		s.notifier.Notify(userID, models.WebhookEventSessionExpired, map[string]string{
+			"reason": ErrSessionReauthFailed.Error(),
+		})
+	}
+}
+
+// needsRefresh 判断Riot访问令牌是否即将过期
+func needsRefresh(session *models.UserSession) bool {
+	if session.RiotTokenExpiresAt == 0 {
+		return false
+	}
+	return time.Now().Add(riotTokenRefreshMargin).Unix() >= session.RiotTokenExpiresAt
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/mockriot"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// expireRiotToken 使会话中的Riot令牌立即需要重新认证
+func expireRiotToken(t *testing.T, store repositories.SessionStore, userID string) {
+	t.Helper()
+
+	session, exists := store.Get(userID)
+	if !exists {
+		t.Fatalf("用户 %s 的会话不存在", userID)
+	}
+	session.RiotTokenExpiresAt = time.Now().Unix()
+	if err := store.Put(userID, session); err != nil {
+		t.Fatalf("保存会话失败: %v", err)
+	}
+}
+
+func TestReauthenticateKeepsSessionWhenRiotUnavailable(t *testing.T) {
+	mock, api := newMockRiot(t, testFixture(1))
+	authService, sessionStore := newTestAuthService(t, api)
+	sessionService := NewSessionService(api, sessionStore)
+
+	tokens, _, err := authService.Login(t.Context(), "player0", "pass0")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	userID := tokens.User.UserID
+	expireRiotToken(t, sessionStore, userID)
+
+	if err := mock.AddFault(mockriot.Fault{Endpoint: mockriot.EndpointAuthorize, Status: http.StatusServiceUnavailable}); err != nil {
+		t.Fatalf("注入错误失败: %v", err)
+	}
+	if _, err := sessionService.GetSession(t.Context(), userID); !errors.Is(err, repositories.ErrUpstreamUnavailable) {
+		t.Fatalf("Riot不可用时应当返回ErrUpstreamUnavailable，得到 %v", err)
+	}
+	if _, exists := sessionStore.Get(userID); !exists {
+		t.Fatal("Riot不可用时不应删除会话")
+	}
+
+	// Riot恢复后使用保存的Cookie重新认证
+	mock.ClearFaults()
+	session, err := sessionService.GetSession(t.Context(), userID)
+	if err != nil {
+		t.Fatalf("Riot恢复后重新认证失败: %v", err)
+	}
+	if needsRefresh(session) {
+		t.Fatal("重新认证后Riot令牌应当已更新")
+	}
+}
+
+func TestReauthenticateDropsSessionWhenCookiesRejected(t *testing.T) {
+	_, api := newMockRiot(t, testFixture(1))
+	authService, sessionStore := newTestAuthService(t, api)
+	sessionService := NewSessionService(api, sessionStore)
+
+	tokens, _, err := authService.Login(t.Context(), "player0", "pass0")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	userID := tokens.User.UserID
+
+	session, _ := sessionStore.Get(userID)
+	session.Cookies = map[string]string{"ssid": "revoked"}
+	session.RiotTokenExpiresAt = time.Now().Unix()
+	if err := sessionStore.Put(userID, session); err != nil {
+		t.Fatalf("保存会话失败: %v", err)
+	}
+
+	if _, err := sessionService.GetSession(t.Context(), userID); !errors.Is(err, ErrSessionReauthFailed) {
+		t.Fatalf("Cookie失效时应当返回ErrSessionReauthFailed，得到 %v", err)
+	}
+	if _, exists := sessionStore.Get(userID); exists {
+		t.Fatal("Cookie失效时应当删除会话")
+	}
+}
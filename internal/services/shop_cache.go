@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+// shopCacheEntry 缓存的商店数据
+type shopCacheEntry struct {
+	shop      *models.ShopResponse
+	fetchedAt time.Time
+	expiresAt time.Time
+}
+
+// shopCacheLock 缓存键的锁，refs为持有和等待该锁的请求数，为0时从locks中删除
+type shopCacheLock struct {
+	sem  chan struct{} // 容量为1的信号量
+	refs int
+}
+
+// shopCache 按用户和区域缓存商店数据，过期时间为最早的商店刷新时间
+type shopCache struct {
+	mutex   sync.Mutex
+	entries map[string]*shopCacheEntry
+	locks   map[string]*shopCacheLock // 每个缓存键一把锁，合并并发请求
+}
+
+// newShopCache 创建商店缓存
+func newShopCache() *shopCache {
+	return &shopCache{
+		entries: make(map[string]*shopCacheEntry),
+		locks:   make(map[string]*shopCacheLock),
+	}
+}
+
+// get 获取未过期的商店数据
+func (c *shopCache) get(key string) (*models.ShopResponse, bool) {
+	shop, _, found := c.getWithTime(key)
+	return shop, found
+}
+
+// getWithTime 获取未过期的商店数据及其获取时间
+func (c *shopCache) getWithTime(key string) (*models.ShopResponse, time.Time, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists || !time.Now().Before(entry.expiresAt) {
+		return nil, time.Time{}, false
+	}
+	return entry.shop, entry.fetchedAt, true
+}
+
+// put 缓存商店数据，同时清理已过期的数据
+func (c *shopCache) put(key string, shop *models.ShopResponse, expiresAt time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = &shopCacheEntry{
+		shop:      shop,
+		fetchedAt: now,
+		expiresAt: expiresAt,
+	}
+}
+
+// acquire 获取缓存键对应的锁，ctx取消时放弃等待并返回ctx的错误
+// 获取成功后调用方需调用返回的release释放锁
+func (c *shopCache) acquire(ctx context.Context, key string) (func(), error) {
+	c.mutex.Lock()
+	lock, exists := c.locks[key]
+	if !exists {
+		lock = &shopCacheLock{sem: make(chan struct{}, 1)}
+		c.locks[key] = lock
+	}
+	lock.refs++
+	c.mutex.Unlock()
+
+	select {
+	case lock.sem <- struct{}{}:
+		return func() {
+			<-lock.sem
+			c.unref(key, lock)
+		}, nil
+	case <-ctx.Done():
+		c.unref(key, lock)
+		return nil, ctx.Err()
+	}
+}
+
+// unref 减少锁的引用数，没有请求持有或等待时删除该锁
+func (c *shopCache) unref(key string, lock *shopCacheLock) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(c.locks, key)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+func TestShopCacheExpires(t *testing.T) {
+	cache := newShopCache()
+	shop := &models.ShopResponse{}
+
+	cache.put("expired", shop, time.Now().Add(-time.Second))
+	if _, found := cache.get("expired"); found {
+		t.Fatal("过期的商店数据不应返回")
+	}
+
+	cache.put("fresh", shop, time.Now().Add(time.Hour))
+	cached, found := cache.get("fresh")
+	if !found || cached != shop {
+		t.Fatal("未过期的商店数据应当返回")
+	}
+
+	// 写入新数据时清理过期数据
+	if _, exists := cache.entries["expired"]; exists {
+		t.Fatal("过期的商店数据应当被清理")
+	}
+}
+
+func TestShopCacheAcquireHonoursContext(t *testing.T) {
+	cache := newShopCache()
+
+	release, err := cache.acquire(t.Context(), "key")
+	if err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+
+	// 锁被占用时等待到ctx超时
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := cache.acquire(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("等待锁超时应当返回context.DeadlineExceeded，得到 %v", err)
+	}
+
+	// 其他缓存键不受影响
+	releaseOther, err := cache.acquire(t.Context(), "other")
+	if err != nil {
+		t.Fatalf("获取其他缓存键的锁失败: %v", err)
+	}
+	releaseOther()
+
+	release()
+	release, err = cache.acquire(t.Context(), "key")
+	if err != nil {
+		t.Fatalf("释放后应当可以再次获取锁: %v", err)
+	}
+	release()
+
+	// 没有请求持有或等待的锁会被删除
+	if len(cache.locks) != 0 {
+		t.Fatalf("所有锁释放后应当被删除，剩余%d个", len(cache.locks))
+	}
+}
+
+func TestShopCacheAcquireConcurrent(t *testing.T) {
+	cache := newShopCache()
+
+	const workers = 16
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		holders int
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := cache.acquire(t.Context(), "key")
+			if err != nil {
+				t.Errorf("获取锁失败: %v", err)
+				return
+			}
+			mutex.Lock()
+			holders++
+			if holders > 1 {
+				t.Error("同一缓存键的锁同时被多个请求持有")
+			}
+			mutex.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mutex.Lock()
+			holders--
+			mutex.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if len(cache.locks) != 0 {
+		t.Fatalf("所有锁释放后应当被删除，剩余%d个", len(cache.locks))
+	}
+}
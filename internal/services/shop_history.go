@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+const (
+	// ShopHistoryDateLayout 商店历史使用的日期格式
+	ShopHistoryDateLayout = "2006-01-02"
+
+	defaultHistoryPageSize = 20
+	maxHistoryPageSize     = 100
+)
+
+// ErrInvalidHistoryQuery 商店历史查询参数无效
+var ErrInvalidHistoryQuery = errors.New("无效的商店历史查询参数")
+
+// rotationDate 根据每日商店的刷新时间计算这一轮商店开始的日期（UTC）
+// 刷新时间由剩余秒数推算，会有几秒误差，取整到小时后再往前推一天
+func rotationDate(expiresAt int64) string {
+	return time.Unix(expiresAt, 0).UTC().Round(time.Hour).Add(-24 * time.Hour).Format(ShopHistoryDateLayout)
+}
+
+// recordSnapshot 保存商店快照，同一轮每日商店只保留一份
+// 出现新一轮每日商店或新的夜市时通知订阅者
+func (s *ShopService) recordSnapshot(userID string, shop *models.ShopResponse) error {
+	if s.historyStore == nil || shop.ExpiresAt == 0 {
+		return nil
+	}
+
+	latest, err := s.historyStore.Latest(userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	snapshot := models.ShopSnapshot{
+		Date:            rotationDate(shop.ExpiresAt),
+		RecordedAt:      now,
+		UpdatedAt:       now,
+		ExpiresAt:       shop.ExpiresAt,
+		DailyOffers:     shop.DailyOffers,
+		FeaturedBundles: shop.FeaturedBundles,
+		BonusOffers:     shop.BonusOffers,
+	}
+	if err := s.historyStore.Save(userID, snapshot); err != nil {
+		return err
+	}
+
+	if s.notifier == nil {
+		return nil
+	}
+	if latest == nil || latest.Date < snapshot.Date {
+		s.notifier.Notify(userID, models.WebhookEventShopRotated, snapshot)
+	}
+	if len(shop.BonusOffers) > 0 && (latest == nil || !sameNightMarket(latest.BonusOffers, shop.BonusOffers)) {
+		s.notifier.Notify(userID, models.WebhookEventNightMarketOpened, models.NightMarketResponse{
+			Offers:    shop.BonusOffers,
+			ExpiresAt: shop.BonusExpiresAt,
+		})
+	}
+	return nil
+}
+
+// sameNightMarket 判断两次获取的夜市是否为同一期，每期夜市的报价ID都不同
+func sameNightMarket(previous, current []models.NightMarketItem) bool {
+	return len(previous) > 0 && len(current) > 0 && previous[0].BonusOfferID == current[0].BonusOfferID
+}
+
+// GetShopHistory 分页获取账号的商店历史，from和to为YYYY-MM-DD格式的日期（包含），为空表示不限制
+func (s *ShopService) GetShopHistory(userID, from, to string, page, pageSize int) (*models.ShopHistoryResponse, error) {
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(ShopHistoryDateLayout, date); err != nil {
+			return nil, fmt.Errorf("%w: 日期格式应为YYYY-MM-DD: %s", ErrInvalidHistoryQuery, date)
+		}
+	}
+	if from != "" && to != "" && from > to {
+		return nil, fmt.Errorf("%w: 开始日期不能晚于结束日期", ErrInvalidHistoryQuery)
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultHistoryPageSize
+	}
+	if pageSize > maxHistoryPageSize {
+		pageSize = maxHistoryPageSize
+	}
+
+	snapshots, err := s.historyStore.List(userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("获取商店历史失败: %w", err)
+	}
+
+	response := &models.ShopHistoryResponse{
+		Snapshots: []models.ShopSnapshot{},
+		Total:     len(snapshots),
+		Page:      page,
+		PageSize:  pageSize,
+	}
+
+	start := (page - 1) * pageSize
+	if start < len(snapshots) {
+		end := min(start+pageSize, len(snapshots))
+		response.Snapshots = snapshots[start:end]
+	}
+
+	return response, nil
+}
+
+// GetShopSnapshot 获取账号在指定日期的商店快照，不存在时返回repositories.ErrShopSnapshotNotFound
+func (s *ShopService) GetShopSnapshot(userID, date string) (*models.ShopSnapshot, error) {
+	if _, err := time.Parse(ShopHistoryDateLayout, date); err != nil {
+		return nil, fmt.Errorf("%w: 日期格式应为YYYY-MM-DD: %s", ErrInvalidHistoryQuery, date)
+	}
+
+	return s.historyStore.Get(userID, date)
+}
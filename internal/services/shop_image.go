@@ -0,0 +1,524 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // 部分套装图片是JPEG
+	"image/png"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// 商店图片的布局和主题
+const (
+	ShopImageLayoutGrid = "grid" // 每行两个物品
+	ShopImageLayoutList = "list" // 每行一个物品
+
+	ShopImageThemeDark  = "dark"
+	ShopImageThemeLight = "light"
+)
+
+// ErrInvalidImageOptions 商店图片的参数无效
+var ErrInvalidImageOptions = errors.New("无效的图片参数")
+
+// 商店图片的尺寸
+const (
+	shopImageWidth   = 960
+	shopImagePadding = 24
+	shopImageGap     = 16
+	shopImageHeader  = 60 // 分区标题的高度
+	shopGridCardH    = 230
+	shopListCardH    = 120
+	shopBundleCardH  = 260
+)
+
+// tierColors 皮肤等级的颜色，键是等级UUID
+var tierColors = map[string]color.RGBA{
+	"12683d76-48d7-84a3-4e09-6985794f0445": {0x5a, 0x9f, 0xe2, 0xff}, // Select
+	"0cebb8be-46d7-c12a-d306-e9907bfc5a25": {0x00, 0x95, 0x87, 0xff}, // Deluxe
+	"60bca009-4182-7998-dee7-b8a2558dc369": {0xd1, 0x54, 0x8d, 0xff}, // Premium
+	"e046854e-406c-37f4-6607-19a9ba8426fc": {0xf5, 0x95, 0x5b, 0xff}, // Exclusive
+	"411e4a55-4e59-7757-41f0-86a53f101bb5": {0xfa, 0xd6, 0x63, 0xff}, // Ultra
+}
+
+// shopImageTheme 商店图片的配色
+type shopImageTheme struct {
+	background color.RGBA
+	card       color.RGBA
+	text       color.RGBA
+	muted      color.RGBA
+	accent     color.RGBA
+}
+
+var shopImageThemes = map[string]shopImageTheme{
+	ShopImageThemeDark: {
+		background: color.RGBA{0x0f, 0x19, 0x23, 0xff},
+		card:       color.RGBA{0x1f, 0x2a, 0x35, 0xff},
+		text:       color.RGBA{0xec, 0xe8, 0xe1, 0xff},
+		muted:      color.RGBA{0x8b, 0x97, 0x8f, 0xff},
+		accent:     color.RGBA{0xff, 0x46, 0x55, 0xff},
+	},
+	ShopImageThemeLight: {
+		background: color.RGBA{0xec, 0xe8, 0xe1, 0xff},
+		card:       color.RGBA{0xff, 0xff, 0xff, 0xff},
+		text:       color.RGBA{0x0f, 0x19, 0x23, 0xff},
+		muted:      color.RGBA{0x5b, 0x66, 0x70, 0xff},
+		accent:     color.RGBA{0xff, 0x46, 0x55, 0xff},
+	},
+}
+
+// ShopImageOptions 商店图片的参数，空值使用默认值
+type ShopImageOptions struct {
+	Layout             string // grid或list，默认grid
+	Theme              string // dark或light，默认dark
+	IncludeBundles     bool   // 是否包括精选套装
+	IncludeNightMarket bool   // 是否包括夜市（夜市开放时）
+}
+
+// shopImageCard 图片中的一个物品
+type shopImageCard struct {
+	name     string
+	tier     string
+	price    string
+	oldPrice string // 夜市原价
+	iconURL  string
+	accent   color.RGBA
+	owned    bool
+	bundle   bool // 套装占满整行
+}
+
+// shopImageSection 图片中的一个分区，如每日商店、夜市
+type shopImageSection struct {
+	title    string
+	subtitle string
+	cards    []shopImageCard
+}
+
+// shopImageFaces 一次绘制使用的字体，opentype的字体不能并发使用
+type shopImageFaces struct {
+	title font.Face
+	name  font.Face
+	small font.Face
+}
+
+// ShopImageService 将商店绘制为PNG图片
+type ShopImageService struct {
+	iconCache *repositories.IconCache
+	regular   *opentype.Font
+	bold      *opentype.Font
+}
+
+// NewShopImageService 创建商店图片服务
+// fontFile为空时使用内置的Go字体，内置字体不包含中文，皮肤名称为中文时需要指定字体文件
+func NewShopImageService(iconCache *repositories.IconCache, fontFile string) (*ShopImageService, error) {
+	service := &ShopImageService{iconCache: iconCache}
+
+	if fontFile == "" {
+		regular, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			return nil, fmt.Errorf("解析内置字体失败: %w", err)
+		}
+		bold, err := opentype.Parse(gobold.TTF)
+		if err != nil {
+			return nil, fmt.Errorf("解析内置字体失败: %w", err)
+		}
+		service.regular, service.bold = regular, bold
+		return service, nil
+	}
+
+	data, err := os.ReadFile(fontFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取字体文件失败: %w", err)
+	}
+	custom, err := opentype.Parse(data)
+	if err != nil {
+		// 字体集合（.ttc）使用第一个字体
+		collection, collectionErr := opentype.ParseCollection(data)
+		if collectionErr != nil {
+			return nil, fmt.Errorf("解析字体文件失败: %w", err)
+		}
+		if custom, err = collection.Font(0); err != nil {
+			return nil, fmt.Errorf("解析字体文件失败: %w", err)
+		}
+	}
+	service.regular, service.bold = custom, custom
+	return service, nil
+}
+
+// RenderShop 将每日商店绘制为PNG图片，包括皮肤图标、名称、等级颜色、价格和剩余时间
+func (s *ShopImageService) RenderShop(ctx context.Context, shop *models.ShopResponse, opts ShopImageOptions) ([]byte, error) {
+	if opts.Layout == "" {
+		opts.Layout = ShopImageLayoutGrid
+	}
+	if opts.Theme == "" {
+		opts.Theme = ShopImageThemeDark
+	}
+	if opts.Layout != ShopImageLayoutGrid && opts.Layout != ShopImageLayoutList {
+		return nil, fmt.Errorf("%w: 布局只能是grid或list", ErrInvalidImageOptions)
+	}
+	theme, exists := shopImageThemes[opts.Theme]
+	if !exists {
+		return nil, fmt.Errorf("%w: 主题只能是dark或light", ErrInvalidImageOptions)
+	}
+
+	sections := buildShopImageSections(shop, opts)
+	icons := s.loadIcons(ctx, sections)
+
+	faces, err := s.newFaces()
+	if err != nil {
+		return nil, err
+	}
+	defer faces.close()
+
+	canvas := image.NewRGBA(image.Rect(0, 0, shopImageWidth, shopImageHeight(sections, opts.Layout)))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(theme.background), image.Point{}, draw.Src)
+
+	y := shopImagePadding
+	for _, section := range sections {
+		drawText(canvas, faces.title, theme.accent, shopImagePadding, y+36, section.title)
+		drawTextRight(canvas, faces.small, theme.muted, shopImageWidth-shopImagePadding, y+36, section.subtitle)
+		y += shopImageHeader
+
+		column := 0
+		for _, card := range section.cards {
+			// 套装单独作为一个分区，占满整行
+			if card.bundle || opts.Layout == ShopImageLayoutList {
+				height := shopListCardH
+				if card.bundle {
+					height = shopBundleCardH
+				}
+				rect := image.Rect(shopImagePadding, y, shopImageWidth-shopImagePadding, y+height)
+				if card.bundle {
+					drawGridCard(canvas, rect, card, icons[card.iconURL], theme, faces)
+				} else {
+					drawListCard(canvas, rect, card, icons[card.iconURL], theme, faces)
+				}
+				y += height + shopImageGap
+				continue
+			}
+
+			width := (shopImageWidth - 2*shopImagePadding - shopImageGap) / 2
+			x := shopImagePadding + column*(width+shopImageGap)
+			drawGridCard(canvas, image.Rect(x, y, x+width, y+shopGridCardH), card, icons[card.iconURL], theme, faces)
+			column++
+			if column == 2 {
+				y += shopGridCardH + shopImageGap
+				column = 0
+			}
+		}
+		if column > 0 {
+			y += shopGridCardH + shopImageGap
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("编码图片失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// buildShopImageSections 将商店数据转换为图片中的分区
+func buildShopImageSections(shop *models.ShopResponse, opts ShopImageOptions) []shopImageSection {
+	daily := shopImageSection{
+		title:    "DAILY SHOP",
+		subtitle: "Resets in " + formatImageRemaining(shop.ExpiresAt),
+	}
+	for _, item := range shop.DailyOffers {
+		daily.cards = append(daily.cards, skinImageCard(item, fmt.Sprintf("%d VP", item.FinalPrice)))
+	}
+	sections := []shopImageSection{daily}
+
+	if opts.IncludeBundles {
+		for _, bundle := range shop.FeaturedBundles {
+			iconURL := bundle.IconURL
+			if iconURL == "" {
+				iconURL = bundle.PromoImageURL
+			}
+			sections = append(sections, shopImageSection{
+				title:    "FEATURED BUNDLE",
+				subtitle: "Ends in " + formatImageRemaining(bundle.ExpiresAt),
+				cards: []shopImageCard{{
+					name:    bundle.Name,
+					tier:    fmt.Sprintf("%d items", len(bundle.Items)),
+					price:   fmt.Sprintf("%d VP", bundle.DiscountedPrice),
+					iconURL: iconURL,
+					bundle:  true,
+				}},
+			})
+		}
+	}
+
+	if opts.IncludeNightMarket && len(shop.BonusOffers) > 0 {
+		nightMarket := shopImageSection{
+			title:    "NIGHT MARKET",
+			subtitle: "Ends in " + formatImageRemaining(shop.BonusExpiresAt),
+		}
+		for _, item := range shop.BonusOffers {
+			card := skinImageCard(item.ShopItem, fmt.Sprintf("%d VP -%d%%", item.FinalPrice, item.DiscountPercent))
+			card.oldPrice = fmt.Sprintf("%d", item.BasePrice)
+			nightMarket.cards = append(nightMarket.cards, card)
+		}
+		sections = append(sections, nightMarket)
+	}
+
+	return sections
+}
+
+// skinImageCard 将商店物品转换为图片中的物品
+func skinImageCard(item models.ShopItem, price string) shopImageCard {
+	accent, exists := tierColors[item.Skin.TierUUID]
+	if !exists {
+		accent = color.RGBA{0x76, 0x80, 0x79, 0xff}
+	}
+	return shopImageCard{
+		name:    item.Skin.Name,
+		tier:    item.Skin.TierName,
+		price:   price,
+		iconURL: item.Skin.IconURL,
+		accent:  accent,
+		owned:   item.Owned,
+	}
+}
+
+// shopImageHeight 计算图片的高度
+func shopImageHeight(sections []shopImageSection, layout string) int {
+	height := shopImagePadding * 2
+	for _, section := range sections {
+		height += shopImageHeader
+		gridItems := 0
+		for _, card := range section.cards {
+			switch {
+			case card.bundle:
+				height += shopBundleCardH + shopImageGap
+			case layout == ShopImageLayoutList:
+				height += shopListCardH + shopImageGap
+			default:
+				gridItems++
+			}
+		}
+		height += (gridItems + 1) / 2 * (shopGridCardH + shopImageGap)
+	}
+	return height - shopImageGap
+}
+
+// loadIcons 并发获取所有图标，获取失败的图标不绘制
+func (s *ShopImageService) loadIcons(ctx context.Context, sections []shopImageSection) map[string]image.Image {
+	icons := make(map[string]image.Image)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, section := range sections {
+		for _, card := range section.cards {
+			if card.iconURL == "" {
+				continue
+			}
+			mutex.Lock()
+			_, started := icons[card.iconURL]
+			icons[card.iconURL] = nil
+			mutex.Unlock()
+			if started {
+				continue
+			}
+
+			wg.Add(1)
+			go func(iconURL string) {
+				defer wg.Done()
+				data, err := s.iconCache.Get(ctx, iconURL)
+				if err != nil {
+					fmt.Printf("获取图标失败: %v\n", err)
+					return
+				}
+				icon, _, err := image.Decode(bytes.NewReader(data))
+				if err != nil {
+					fmt.Printf("解码图标 %s 失败: %v\n", iconURL, err)
+					return
+				}
+				mutex.Lock()
+				icons[iconURL] = icon
+				mutex.Unlock()
+			}(card.iconURL)
+		}
+	}
+
+	wg.Wait()
+	return icons
+}
+
+// newFaces 创建本次绘制使用的字体
+func (s *ShopImageService) newFaces() (*shopImageFaces, error) {
+	newFace := func(f *opentype.Font, size float64) (font.Face, error) {
+		return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	}
+
+	title, err := newFace(s.bold, 28)
+	if err != nil {
+		return nil, fmt.Errorf("创建字体失败: %w", err)
+	}
+	name, err := newFace(s.bold, 20)
+	if err != nil {
+		title.Close()
+		return nil, fmt.Errorf("创建字体失败: %w", err)
+	}
+	small, err := newFace(s.regular, 16)
+	if err != nil {
+		title.Close()
+		name.Close()
+		return nil, fmt.Errorf("创建字体失败: %w", err)
+	}
+	return &shopImageFaces{title: title, name: name, small: small}, nil
+}
+
+// close 释放字体
+func (f *shopImageFaces) close() {
+	f.title.Close()
+	f.name.Close()
+	f.small.Close()
+}
+
+// drawGridCard 绘制网格布局的物品：图标在上，名称、等级和价格在下
+func drawGridCard(dst *image.RGBA, rect image.Rectangle, card shopImageCard, icon image.Image, theme shopImageTheme, faces *shopImageFaces) {
+	drawCardBackground(dst, rect, card, theme)
+
+	iconRect := image.Rect(rect.Min.X+24, rect.Min.Y+16, rect.Max.X-24, rect.Max.Y-72)
+	drawIcon(dst, iconRect, icon)
+
+	priceWidth := textWidth(faces.name, card.price)
+	drawText(dst, faces.name, theme.text, rect.Min.X+20, rect.Max.Y-42, fitText(faces.name, card.name, rect.Dx()-40))
+	drawText(dst, faces.small, theme.muted, rect.Min.X+20, rect.Max.Y-16, fitText(faces.small, card.tier, rect.Dx()-priceWidth-60))
+	drawPrice(dst, rect.Max.X-16, rect.Max.Y-16, card, theme, faces)
+	if card.owned {
+		drawTextRight(dst, faces.small, theme.muted, rect.Max.X-16, rect.Min.Y+26, "OWNED")
+	}
+}
+
+// drawListCard 绘制列表布局的物品：图标在左，名称和等级在中间，价格在右
+func drawListCard(dst *image.RGBA, rect image.Rectangle, card shopImageCard, icon image.Image, theme shopImageTheme, faces *shopImageFaces) {
+	drawCardBackground(dst, rect, card, theme)
+
+	iconRect := image.Rect(rect.Min.X+24, rect.Min.Y+12, rect.Min.X+264, rect.Max.Y-12)
+	drawIcon(dst, iconRect, icon)
+
+	textX := rect.Min.X + 288
+	priceWidth := textWidth(faces.name, card.price)
+	maxWidth := rect.Max.X - textX - priceWidth - 40
+	drawText(dst, faces.name, theme.text, textX, rect.Min.Y+52, fitText(faces.name, card.name, maxWidth))
+	tier := card.tier
+	if card.owned && tier != "" {
+		tier += " · OWNED"
+	} else if card.owned {
+		tier = "OWNED"
+	}
+	drawText(dst, faces.small, theme.muted, textX, rect.Min.Y+80, fitText(faces.small, tier, maxWidth))
+	drawPrice(dst, rect.Max.X-20, rect.Min.Y+rect.Dy()/2+8, card, theme, faces)
+}
+
+// drawCardBackground 绘制物品背景和左侧的等级颜色条
+func drawCardBackground(dst *image.RGBA, rect image.Rectangle, card shopImageCard, theme shopImageTheme) {
+	draw.Draw(dst, rect, image.NewUniform(theme.card), image.Point{}, draw.Src)
+	accent := card.accent
+	if card.bundle {
+		accent = theme.accent
+	}
+	stripe := image.Rect(rect.Min.X, rect.Min.Y, rect.Min.X+6, rect.Max.Y)
+	draw.Draw(dst, stripe, image.NewUniform(accent), image.Point{}, draw.Src)
+}
+
+// drawPrice 右对齐绘制价格，夜市物品在价格上方绘制原价
+func drawPrice(dst *image.RGBA, right, baseline int, card shopImageCard, theme shopImageTheme, faces *shopImageFaces) {
+	drawTextRight(dst, faces.name, theme.text, right, baseline, card.price)
+	if card.oldPrice == "" {
+		return
+	}
+
+	width := textWidth(faces.small, card.oldPrice)
+	oldBaseline := baseline - 26
+	drawTextRight(dst, faces.small, theme.muted, right, oldBaseline, card.oldPrice)
+	// 删除线
+	line := image.Rect(right-width, oldBaseline-6, right, oldBaseline-4)
+	draw.Draw(dst, line, image.NewUniform(theme.muted), image.Point{}, draw.Over)
+}
+
+// drawIcon 保持比例缩放图标并居中绘制
+func drawIcon(dst *image.RGBA, rect image.Rectangle, icon image.Image) {
+	if icon == nil || rect.Empty() {
+		return
+	}
+
+	bounds := icon.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return
+	}
+	scale := min(float64(rect.Dx())/float64(bounds.Dx()), float64(rect.Dy())/float64(bounds.Dy()))
+	width := int(float64(bounds.Dx()) * scale)
+	height := int(float64(bounds.Dy()) * scale)
+	x := rect.Min.X + (rect.Dx()-width)/2
+	y := rect.Min.Y + (rect.Dy()-height)/2
+
+	xdraw.CatmullRom.Scale(dst, image.Rect(x, y, x+width, y+height), icon, bounds, draw.Over, nil)
+}
+
+// drawText 从基线位置绘制文字
+func drawText(dst *image.RGBA, face font.Face, col color.Color, x, baseline int, text string) {
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(col),
+		Face: face,
+		Dot:  fixed.P(x, baseline),
+	}
+	drawer.DrawString(text)
+}
+
+// drawTextRight 右对齐绘制文字
+func drawTextRight(dst *image.RGBA, face font.Face, col color.Color, right, baseline int, text string) {
+	drawText(dst, face, col, right-textWidth(face, text), baseline, text)
+}
+
+// textWidth 计算文字宽度（像素）
+func textWidth(face font.Face, text string) int {
+	return font.MeasureString(face, text).Ceil()
+}
+
+// fitText 截断超出宽度的文字
+func fitText(face font.Face, text string, maxWidth int) string {
+	if textWidth(face, text) <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if truncated := string(runes) + "..."; textWidth(face, truncated) <= maxWidth {
+			return truncated
+		}
+	}
+	return ""
+}
+
+// formatImageRemaining 格式化距离指定时间的剩余时长，图片中使用英文以兼容内置字体
+func formatImageRemaining(unix int64) string {
+	remaining := time.Until(time.Unix(unix, 0))
+	if remaining < time.Minute {
+		return "<1m"
+	}
+	hours := int(remaining.Hours())
+	minutes := int(remaining.Minutes()) % 60
+	if hours >= 24 {
+		return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
@@ -1,47 +1,112 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/emper0r/val-store/server/internal/models"
 	"github.com/emper0r/val-store/server/internal/repositories"
 )
 
+// ErrNightMarketNotActive 当前没有开放的夜市
+var ErrNightMarketNotActive = errors.New("当前没有开放的夜市")
+
 // ShopService 处理商店相关的业务逻辑
 type ShopService struct {
-	valorantAPI  *repositories.ValorantAPI
-	skinDatabase *repositories.SkinDatabase
-	sessionCache map[string]*models.UserSession // 缓存用户会话数据（UserID -> UserSession）
+	valorantAPI      *repositories.ValorantAPI
+	skinDatabase     *repositories.SkinDatabase
+	sessionStore     repositories.SessionStore // 用户会话存储（UserID -> UserSession）
+	priceService     *PriceService
+	inventoryService *InventoryService
+	cache            *shopCache // 按用户和区域缓存商店数据，直到商店刷新
+	historyStore     *repositories.ShopHistoryStore
+	notifier         EventNotifier // 商店刷新和夜市开放的通知，可以为空
 }
 
 // NewShopService 创建新的商店服务
-func NewShopService(valorantAPI *repositories.ValorantAPI, skinDatabase *repositories.SkinDatabase) *ShopService {
+func NewShopService(valorantAPI *repositories.ValorantAPI, skinDatabase *repositories.SkinDatabase, sessionStore repositories.SessionStore, priceService *PriceService, inventoryService *InventoryService, historyStore *repositories.ShopHistoryStore) *ShopService {
 	return &ShopService{
-		valorantAPI:  valorantAPI,
-		skinDatabase: skinDatabase,
-		sessionCache: make(map[string]*models.UserSession),
+		valorantAPI:      valorantAPI,
+		skinDatabase:     skinDatabase,
+		sessionStore:     sessionStore,
+		priceService:     priceService,
+		inventoryService: inventoryService,
+		cache:            newShopCache(),
+		historyStore:     historyStore,
 	}
 }
 
-// GetShop 获取用户的商店数据
-func (s *ShopService) GetShop(userID, accessToken, entitlementToken string) (*models.ShopResponse, error) {
-	// 从会话缓存中获取用户区域
-	session, exists := s.sessionCache[userID]
-	if exists && session.Region != "" {
-		// 设置用户特定的区域
-		fmt.Printf("从会话缓存中获取用户区域: %s\n", session.Region)
-		s.valorantAPI.SetRegion(session.Region)
-	} else {
-		fmt.Printf("未找到用户区域设置，使用默认区域\n")
+// SetNotifier 设置商店事件的通知接收者
+func (s *ShopService) SetNotifier(notifier EventNotifier) {
+	s.notifier = notifier
+}
+
+// GetShop 获取用户的商店数据，区域和令牌取自用户会话
+// 商店数据缓存到最早的商店刷新时间，refresh为true时跳过缓存重新获取
+// 同一用户的并发请求只会向Riot发起一次请求
+func (s *ShopService) GetShop(ctx context.Context, session *models.UserSession, refresh bool) (*models.ShopResponse, error) {
+	key := session.UserID + "|" + repositories.NormalizeRegion(session.Region)
+	requestedAt := time.Now()
+
+	if !refresh {
+		if cached, found := s.cache.get(key); found {
+			return cached, nil
+		}
+	}
+
+	// 等待其他请求获取商店期间客户端断开时立即返回
+	release, err := s.cache.acquire(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// 等待锁期间其他请求可能已经获取了最新的商店数据
+	if cached, fetchedAt, found := s.cache.getWithTime(key); found && (!refresh || fetchedAt.After(requestedAt)) {
+		return cached, nil
+	}
+
+	shopResponse, err := s.fetchShop(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.put(key, shopResponse, time.Unix(shopResponse.NextRotationAt, 0))
+
+	// 历史快照保存失败不影响商店返回
+	if err := s.recordSnapshot(session.UserID, shopResponse); err != nil {
+		fmt.Printf("保存商店快照失败: %v\n", err)
 	}
 
+	return shopResponse, nil
+}
+
+// fetchShop 从Riot获取商店数据并转换为客户端响应
+func (s *ShopService) fetchShop(ctx context.Context, session *models.UserSession) (*models.ShopResponse, error) {
+	auth := repositories.SessionAuth(session)
+
 	// 调用 Valorant API 获取原始商店数据
-	storeData, err := s.valorantAPI.GetStoreOffers(userID, accessToken, entitlementToken)
+	storeData, err := s.valorantAPI.GetStoreOffers(ctx, auth, session.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("获取商店数据失败: %w", err)
 	}
 
+	// 价格表获取失败时仍然返回商店，只是缺少价格
+	if err := s.priceService.EnsureFresh(ctx, auth); err != nil {
+		fmt.Printf("更新商店价格失败: %v\n", err)
+	}
+
+	// 获取用户已拥有的皮肤，失败时所有物品都标记为未拥有
+	owned := newOwnedItems(ctx, s.inventoryService, s.skinDatabase, session)
+	ownedLevels, err := owned.ids(models.ItemTypeSkinLevel)
+	if err != nil {
+		fmt.Printf("获取用户已拥有的皮肤失败: %v\n", err)
+		ownedLevels = map[string]bool{}
+	}
+
 	// 创建商店响应
 	shopResponse := &models.ShopResponse{
 		DailyOffers: make([]models.ShopItem, 0, len(storeData.SkinsPanelLayout.SingleItemOffers)),
@@ -66,123 +131,259 @@ func (s *ShopService) GetShop(userID, accessToken, entitlementToken string) (*mo
 			}
 		}
 
+		// 使用商店价格表中的价格（每日商店的报价ID即皮肤等级ID）
+		s.priceService.ApplyPrice(&skinInfo, skinID)
+
 		// 添加到每日商店
 		shopResponse.DailyOffers = append(shopResponse.DailyOffers, models.ShopItem{
 			Skin:       skinInfo,
+			ItemType:   models.ShopItemSkin,
 			FinalPrice: skinInfo.Price, // 使用标准价格
+			Owned:      ownedLevels[strings.ToLower(skinID)],
 		})
 	}
 
-	// 处理精选套装
-	if len(storeData.FeaturedBundle.Bundles) > 0 {
-		bundle := storeData.FeaturedBundle.Bundles[0]
-
-		// 设置精选套装信息
-		shopResponse.FeaturedBundle.Name = bundle.DataAssetID
-		shopResponse.FeaturedBundle.Price = 0           // 将在循环中累加
-		shopResponse.FeaturedBundle.DiscountedPrice = 0 // 将在循环中累加
-		shopResponse.FeaturedBundle.ExpiresAt = time.Now().Unix() + bundle.DurationRemainingInSeconds
-		shopResponse.FeaturedBundle.Items = make([]models.ShopItem, 0, len(bundle.Items))
-
-		// 处理套装内的物品
-		for _, item := range bundle.Items {
-			if item.Item.ItemTypeID == "e7c63390-eda7-46e0-bb7a-a6abdacd2433" { // 检查是否为皮肤
-				skinID := item.Item.ItemID
-				skinInfo, found := s.skinDatabase.GetSkinByID(skinID)
-
-				if !found {
-					// 如果皮肤未找到，使用占位符
-					skinInfo = models.Skin{
-						UUID:       skinID,
-						Name:       "未知皮肤",
-						IconURL:    "",
-						TierUUID:   "",
-						TierName:   "未知",
-						Price:      item.BasePrice,
-						WeaponID:   "",
-						WeaponName: "未知武器",
-					}
-				}
+	// 处理所有精选套装
+	shopResponse.FeaturedBundles = make([]models.ShopBundle, 0, len(storeData.FeaturedBundle.Bundles))
+	for _, bundle := range storeData.FeaturedBundle.Bundles {
+		shopResponse.FeaturedBundles = append(shopResponse.FeaturedBundles, s.buildBundle(bundle, owned))
+	}
+	if len(shopResponse.FeaturedBundles) > 0 {
+		shopResponse.FeaturedBundle = shopResponse.FeaturedBundles[0]
+	}
 
-				// 添加套装物品
-				shopItem := models.ShopItem{
-					Skin:            skinInfo,
-					DiscountPercent: item.DiscountPercent,
-					FinalPrice:      item.DiscountedPrice,
-				}
+	// 处理特惠商店（夜市）如果存在
+	if len(storeData.BonusStore.BonusStoreOffers) > 0 {
+		shopResponse.BonusOffers = s.buildNightMarket(storeData.BonusStore, ownedLevels)
+		shopResponse.BonusExpiresAt = time.Now().Unix() + storeData.BonusStore.BonusStoreRemainingDurationInSeconds
+	}
+
+	// 处理配件商店
+	shopResponse.AccessoryOffers = s.buildAccessoryOffers(storeData.AccessoryStore)
+	if len(shopResponse.AccessoryOffers) > 0 {
+		shopResponse.AccessoryExpiresAt = time.Now().Unix() + storeData.AccessoryStore.AccessoryStoreRemainingDurationInSeconds
+	}
+
+	// 最早的商店刷新时间
+	shopResponse.NextRotationAt = shopResponse.ExpiresAt
+	for _, expiresAt := range []int64{shopResponse.BonusExpiresAt, shopResponse.AccessoryExpiresAt} {
+		if expiresAt > 0 && expiresAt < shopResponse.NextRotationAt {
+			shopResponse.NextRotationAt = expiresAt
+		}
+	}
+	for _, bundle := range shopResponse.FeaturedBundles {
+		if bundle.ExpiresAt < shopResponse.NextRotationAt {
+			shopResponse.NextRotationAt = bundle.ExpiresAt
+		}
+	}
+
+	return shopResponse, nil
+}
+
+// GetNightMarket 获取用户的夜市，夜市未开放时返回ErrNightMarketNotActive
+func (s *ShopService) GetNightMarket(ctx context.Context, session *models.UserSession, refresh bool) (*models.NightMarketResponse, error) {
+	shop, err := s.GetShop(ctx, session, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shop.BonusOffers) == 0 {
+		return nil, ErrNightMarketNotActive
+	}
+
+	return &models.NightMarketResponse{
+		Offers:    shop.BonusOffers,
+		ExpiresAt: shop.BonusExpiresAt,
+	}, nil
+}
+
+// buildNightMarket 将夜市物品转换为客户端响应，保留所有货币的原价和折扣价
+func (s *ShopService) buildNightMarket(store models.BonusStore, ownedLevels map[string]bool) []models.NightMarketItem {
+	items := make([]models.NightMarketItem, 0, len(store.BonusStoreOffers))
 
-				shopResponse.FeaturedBundle.Items = append(shopResponse.FeaturedBundle.Items, shopItem)
-				shopResponse.FeaturedBundle.Price += item.BasePrice
-				shopResponse.FeaturedBundle.DiscountedPrice += item.DiscountedPrice
+	for _, offer := range store.BonusStoreOffers {
+		// 夜市报价中只有一个物品，报价ID即皮肤等级ID
+		skinID := offer.Offer.OfferID
+		if len(offer.Offer.Rewards) > 0 {
+			skinID = offer.Offer.Rewards[0].ItemID
+		}
+
+		skinInfo, found := s.skinDatabase.GetSkinByID(skinID)
+		if !found {
+			// 如果皮肤未找到，使用占位符
+			skinInfo = models.Skin{
+				UUID:       skinID,
+				Name:       "未知皮肤",
+				TierName:   "未知",
+				WeaponName: "未知武器",
 			}
 		}
+		s.priceService.ApplyPrice(&skinInfo, skinID)
+
+		items = append(items, models.NightMarketItem{
+			ShopItem: models.ShopItem{
+				ItemType:        models.ShopItemSkin,
+				Skin:            skinInfo,
+				BasePrice:       offer.Offer.Cost[models.CurrencyValorantPoints],
+				DiscountPercent: offer.DiscountPercent,
+				FinalPrice:      offer.DiscountCosts[models.CurrencyValorantPoints],
+				Owned:           ownedLevels[strings.ToLower(skinID)],
+			},
+			BonusOfferID:  offer.BonusOfferID,
+			Costs:         offer.Offer.Cost,
+			DiscountCosts: offer.DiscountCosts,
+			Seen:          offer.IsSeen,
+		})
 	}
 
-	// 处理特惠商店（夜市）如果存在
-	if len(storeData.BonusStore.BonusStoreOffers) > 0 {
-		shopResponse.BonusOffers = make([]models.ShopItem, 0, len(storeData.BonusStore.BonusStoreOffers))
+	return items
+}
 
-		for _, offer := range storeData.BonusStore.BonusStoreOffers {
-			skinID := offer.Offer.ItemID
-			skinInfo, found := s.skinDatabase.GetSkinByID(skinID)
+// GetAccessoryStore 获取用户的每周配件商店
+func (s *ShopService) GetAccessoryStore(ctx context.Context, session *models.UserSession, refresh bool) (*models.AccessoryStoreResponse, error) {
+	shop, err := s.GetShop(ctx, session, refresh)
+	if err != nil {
+		return nil, err
+	}
 
+	return &models.AccessoryStoreResponse{
+		Offers:    shop.AccessoryOffers,
+		ExpiresAt: shop.AccessoryExpiresAt,
+	}, nil
+}
+
+// cosmeticItemTypes 物品类型ID到饰品类型的映射
+var cosmeticItemTypes = map[string]string{
+	models.ItemTypeBuddy:       models.CosmeticBuddy,
+	models.ItemTypeSpray:       models.CosmeticSpray,
+	models.ItemTypePlayerCard:  models.CosmeticPlayerCard,
+	models.ItemTypePlayerTitle: models.CosmeticPlayerTitle,
+}
+
+// buildBundle 将精选套装转换为客户端响应，套装信息来自皮肤数据库，套装内每个物品单独计价
+func (s *ShopService) buildBundle(bundle models.Bundle, owned *ownedItems) models.ShopBundle {
+	shopBundle := models.ShopBundle{
+		ID:            bundle.ID,
+		UUID:          bundle.DataAssetID,
+		Name:          bundle.DataAssetID,
+		Items:         make([]models.ShopItem, 0, len(bundle.Items)),
+		WholesaleOnly: bundle.WholesaleOnly,
+		ExpiresAt:     time.Now().Unix() + bundle.DurationRemainingInSeconds,
+	}
+
+	if info, found := s.skinDatabase.GetBundleByID(bundle.DataAssetID); found {
+		shopBundle.Name = info.Name
+		shopBundle.Description = info.Description
+		shopBundle.IconURL = info.IconURL
+		shopBundle.PromoImageURL = info.PromoImageURL
+	}
+
+	for _, item := range bundle.Items {
+		itemID := item.Item.ItemID
+		itemTypeID := strings.ToLower(item.Item.ItemTypeID)
+
+		shopItem := models.ShopItem{
+			Quantity:        item.Item.Amount,
+			BasePrice:       item.BasePrice,
+			DiscountPercent: item.DiscountPercent,
+			FinalPrice:      item.DiscountedPrice,
+		}
+
+		if itemTypeID == models.ItemTypeSkinLevel {
+			skinInfo, found := s.skinDatabase.GetSkinByID(itemID)
 			if !found {
 				// 如果皮肤未找到，使用占位符
 				skinInfo = models.Skin{
-					UUID:       skinID,
+					UUID:       itemID,
 					Name:       "未知皮肤",
-					IconURL:    "",
-					TierUUID:   "",
 					TierName:   "未知",
-					Price:      0,
-					WeaponID:   "",
+					Price:      item.BasePrice,
 					WeaponName: "未知武器",
 				}
 			}
-
-			// 获取折扣价格
-			var finalPrice int
-			for _, price := range offer.DiscountCosts {
-				finalPrice = price
-				break
+			shopItem.ItemType = models.ShopItemSkin
+			shopItem.Skin = skinInfo
+		} else {
+			cosmetic, found := s.skinDatabase.GetCosmeticByID(itemID)
+			if !found {
+				// 如果物品未找到，使用占位符
+				cosmetic = models.Cosmetic{
+					UUID: itemID,
+					Type: cosmeticItemTypes[itemTypeID],
+					Name: "未知物品",
+				}
 			}
+			shopItem.ItemType = cosmetic.Type
+			shopItem.Item = &cosmetic
+		}
 
-			// 添加特惠物品
-			shopResponse.BonusOffers = append(shopResponse.BonusOffers, models.ShopItem{
-				Skin:            skinInfo,
-				DiscountPercent: offer.DiscountPercent,
-				FinalPrice:      finalPrice,
-			})
+		// 获取用户已拥有的同类物品，失败时标记为未拥有
+		if _, known := cosmeticItemTypes[itemTypeID]; known || itemTypeID == models.ItemTypeSkinLevel {
+			if ownedIDs, err := owned.ids(itemTypeID); err == nil {
+				shopItem.Owned = ownedIDs[strings.ToLower(itemID)]
+			} else {
+				fmt.Printf("获取用户已拥有的物品失败: %v\n", err)
+			}
 		}
+
+		shopBundle.Items = append(shopBundle.Items, shopItem)
+		shopBundle.Price += item.BasePrice
+		shopBundle.DiscountedPrice += item.DiscountedPrice
 	}
 
-	return shopResponse, nil
+	shopBundle.Savings = shopBundle.Price - shopBundle.DiscountedPrice
+	return shopBundle
 }
 
-// CacheUserSession 缓存用户会话
-func (s *ShopService) CacheUserSession(userID string, session *models.UserSession) {
-	s.sessionCache[userID] = session
+// buildAccessoryOffers 将配件商店的物品转换为客户端响应，物品信息来自皮肤数据库
+func (s *ShopService) buildAccessoryOffers(store models.AccessoryStore) []models.AccessoryItem {
+	expiresAt := time.Now().Unix() + store.AccessoryStoreRemainingDurationInSeconds
+	items := make([]models.AccessoryItem, 0, len(store.AccessoryStoreOffers))
+
+	for _, offer := range store.AccessoryStoreOffers {
+		for _, reward := range offer.Offer.Rewards {
+			cosmetic, found := s.skinDatabase.GetCosmeticByID(reward.ItemID)
+			if !found {
+				// 如果物品未找到，使用占位符
+				cosmetic = models.Cosmetic{
+					UUID: reward.ItemID,
+					Type: cosmeticItemTypes[strings.ToLower(reward.ItemTypeID)],
+					Name: "未知物品",
+				}
+			}
+
+			items = append(items, models.AccessoryItem{
+				OfferID:    offer.Offer.OfferID,
+				Item:       cosmetic,
+				Quantity:   reward.Quantity,
+				Price:      offer.Offer.Cost[models.CurrencyKingdomCredits],
+				Costs:      offer.Offer.Cost,
+				ContractID: offer.ContractID,
+				ExpiresAt:  expiresAt,
+			})
+		}
+	}
+
+	return items
 }
 
-// GetCachedSession 获取缓存的用户会话
+// GetCachedSession 获取存储的用户会话
 func (s *ShopService) GetCachedSession(userID string) (*models.UserSession, bool) {
-	session, exists := s.sessionCache[userID]
-	return session, exists
+	return s.sessionStore.Get(userID)
 }
 
 // UpdateUserRegion 更新用户会话中的区域设置
 func (s *ShopService) UpdateUserRegion(userID, region string) error {
-	session, exists := s.sessionCache[userID]
+	session, exists := s.sessionStore.Get(userID)
 	if !exists {
 		return fmt.Errorf("用户会话不存在，请先登录")
 	}
 
 	// 更新会话中的区域设置
 	session.Region = region
-	s.sessionCache[userID] = session
-
-	// 同时更新API客户端的区域设置
-	s.valorantAPI.SetRegion(region)
+	if err := s.sessionStore.Put(userID, session); err != nil {
+		return fmt.Errorf("保存用户会话失败: %w", err)
+	}
 
 	fmt.Printf("已更新用户 %s 的区域设置为 %s\n", userID, region)
 	return nil
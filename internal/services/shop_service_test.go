@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/mockriot"
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// newTestShopService 创建使用临时皮肤数据库、不保存历史快照的商店服务
+func newTestShopService(t *testing.T, api *repositories.ValorantAPI, sessionStore repositories.SessionStore) *ShopService {
+	t.Helper()
+
+	skinDatabase, err := repositories.NewSkinDatabase(filepath.Join(t.TempDir(), "skins.json"))
+	if err != nil {
+		t.Fatalf("创建皮肤数据库失败: %v", err)
+	}
+	priceService := NewPriceService(api, skinDatabase, time.Hour)
+	inventoryService := NewInventoryService(api, skinDatabase)
+	return NewShopService(api, skinDatabase, sessionStore, priceService, inventoryService, nil)
+}
+
+// loginTestUser 登录player0并返回其会话
+func loginTestUser(t *testing.T, authService *AuthService, sessionStore repositories.SessionStore) *models.UserSession {
+	t.Helper()
+
+	tokens, _, err := authService.Login(t.Context(), "player0", "pass0")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	session, exists := sessionStore.Get(tokens.User.UserID)
+	if !exists {
+		t.Fatal("登录后会话不存在")
+	}
+	return session
+}
+
+func TestGetShopCoalescesConcurrentRequests(t *testing.T) {
+	mock, err := mockriot.New(testFixture(1))
+	if err != nil {
+		t.Fatalf("创建模拟服务失败: %v", err)
+	}
+	var storefrontCalls atomic.Int32
+	api := newMockRiotAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/store/v3/storefront/") {
+			storefrontCalls.Add(1)
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	authService, sessionStore := newTestAuthService(t, api)
+	shopService := newTestShopService(t, api, sessionStore)
+	session := loginTestUser(t, authService, sessionStore)
+
+	// 延迟商店响应，使并发请求都在等待第一个请求
+	if err := mock.AddFault(mockriot.Fault{Endpoint: mockriot.EndpointStorefront, DelayMS: 100}); err != nil {
+		t.Fatalf("注入延迟失败: %v", err)
+	}
+
+	const requests = 8
+	shops := make([]*models.ShopResponse, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shop, err := shopService.GetShop(t.Context(), session, false)
+			if err != nil {
+				t.Errorf("获取商店失败: %v", err)
+				return
+			}
+			shops[i] = shop
+		}()
+	}
+	wg.Wait()
+
+	if calls := storefrontCalls.Load(); calls != 1 {
+		t.Fatalf("并发请求应当只向Riot请求一次商店，实际%d次", calls)
+	}
+	for i, shop := range shops {
+		if shop != shops[0] {
+			t.Fatalf("第%d个请求没有使用合并后的商店数据", i)
+		}
+	}
+
+	// 缓存命中时不请求Riot，refresh为true时重新获取
+	if _, err := shopService.GetShop(t.Context(), session, false); err != nil {
+		t.Fatalf("获取缓存的商店失败: %v", err)
+	}
+	if calls := storefrontCalls.Load(); calls != 1 {
+		t.Fatalf("缓存未过期时不应请求Riot，实际请求%d次", calls)
+	}
+	if _, err := shopService.GetShop(t.Context(), session, true); err != nil {
+		t.Fatalf("刷新商店失败: %v", err)
+	}
+	if calls := storefrontCalls.Load(); calls != 2 {
+		t.Fatalf("refresh为true时应当重新请求Riot，实际请求%d次", calls)
+	}
+}
+
+func TestGetShopStopsWaitingWhenContextDone(t *testing.T) {
+	_, api := newMockRiot(t, testFixture(1))
+	authService, sessionStore := newTestAuthService(t, api)
+	shopService := newTestShopService(t, api, sessionStore)
+	session := loginTestUser(t, authService, sessionStore)
+
+	// 模拟另一个请求正在获取同一用户的商店
+	key := session.UserID + "|" + repositories.NormalizeRegion(session.Region)
+	release, err := shopService.cache.acquire(t.Context(), key)
+	if err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := shopService.GetShop(ctx, session, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("等待期间ctx超时应当返回context.DeadlineExceeded，得到 %v", err)
+	}
+}
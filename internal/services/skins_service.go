@@ -1,44 +1,65 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/emper0r/val-store/server/internal/models"
 	"github.com/emper0r/val-store/server/internal/repositories"
 )
 
+// ErrSkinNotFound 皮肤数据库中没有该皮肤
+var ErrSkinNotFound = errors.New("皮肤未找到")
+
+// skinsRetryInterval 皮肤数据库更新失败后，等待该时间再重试
+const skinsRetryInterval = 10 * time.Minute
+
 // SkinsService 处理皮肤相关的业务逻辑
 type SkinsService struct {
 	valorantAPI  *repositories.ValorantAPI
 	skinDatabase *repositories.SkinDatabase
+	catalogAPI   *repositories.CatalogAPI
+	dumpFile     string // 离线导入文件，为空时从网络获取
+
+	updateMutex sync.Mutex // 避免同时执行多次导入
+
+	stateMutex sync.Mutex
+	refreshing bool      // 是否有后台更新正在执行
+	failedAt   time.Time // 最近一次更新失败的时间，成功后清空
+	lastErr    error     // 最近一次更新失败的错误
 }
 
 // NewSkinsService 创建新的皮肤服务
-func NewSkinsService(valorantAPI *repositories.ValorantAPI, skinDatabase *repositories.SkinDatabase) *SkinsService {
+func NewSkinsService(valorantAPI *repositories.ValorantAPI, skinDatabase *repositories.SkinDatabase, catalogAPI *repositories.CatalogAPI, dumpFile string) *SkinsService {
 	return &SkinsService{
 		valorantAPI:  valorantAPI,
 		skinDatabase: skinDatabase,
+		catalogAPI:   catalogAPI,
+		dumpFile:     dumpFile,
 	}
 }
 
-// GetAllSkins 获取所有皮肤列表
-func (s *SkinsService) GetAllSkins() ([]models.Skin, error) {
+// GetAllSkins 获取所有皮肤列表，数据库为空时使用ctx同步导入
+func (s *SkinsService) GetAllSkins(ctx context.Context) ([]models.Skin, error) {
 	// 从数据库获取所有皮肤
 	skins := s.skinDatabase.GetAllSkins()
 
-	// 如果数据库为空或需要更新，尝试更新
-	if len(skins) == 0 || s.skinDatabase.NeedsUpdate() {
-		if err := s.UpdateSkinsDatabase(); err != nil {
-			// 如果数据库为空且更新失败，返回错误
-			if len(skins) == 0 {
-				return nil, err
-			}
-			// 否则继续使用旧数据
+	if len(skins) == 0 {
+		// 数据库为空时只能同步导入，最近更新失败过则直接返回上次的错误
+		if err := s.recentFailure(); err != nil {
+			return nil, err
+		}
+		if err := s.UpdateSkinsDatabase(ctx); err != nil {
+			return nil, err
 		}
-
-		// 重新获取更新后的数据
 		skins = s.skinDatabase.GetAllSkins()
+	} else if s.skinDatabase.NeedsUpdate() {
+		// 已有数据时在后台更新，本次请求继续使用现有数据
+		s.refreshInBackground()
 	}
 
 	// 如果仍然为空，返回错误
@@ -61,40 +82,100 @@ func (s *SkinsService) GetAllSkins() ([]models.Skin, error) {
 func (s *SkinsService) GetSkinByID(skinID string) (models.Skin, error) {
 	skin, found := s.skinDatabase.GetSkinByID(skinID)
 	if !found {
-		return models.Skin{}, errors.New("皮肤未找到")
+		return models.Skin{}, ErrSkinNotFound
 	}
 	return skin, nil
 }
 
-// UpdateSkinsDatabase 更新皮肤数据库
-// 注：这个方法应该被服务器启动时调用，或者通过管理端点触发
-func (s *SkinsService) UpdateSkinsDatabase() error {
-	// 这里需要与Valorant API交互获取最新的皮肤列表
-	// 由于这需要直接调用Valorant的内容API，可能需要特殊处理
-	// 在实际实现中，这可能是一个复杂的过程
-
-	// 这里是一个简化的实现
-	// 实际项目中，您需要按照Valorant API的文档实现具体逻辑
-	// 例如解析Valorant的内容服务返回的数据
-
-	// 作为示例，我们返回一个未实现错误
-	// 在真实实现中，您需要替换为实际的API调用和数据处理逻辑
-
-	return errors.New("更新皮肤数据库功能尚未实现")
-
-	// 完整实现示例
-	/*
-		contentData, err := s.valorantAPI.GetContentInfo()
-		if err != nil {
-			return err
+// recentFailure 最近一次更新在重试间隔内失败时返回该错误
+func (s *SkinsService) recentFailure() error {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+
+	if !s.failedAt.IsZero() && time.Since(s.failedAt) < skinsRetryInterval {
+		return s.lastErr
+	}
+	return nil
+}
+
+// refreshInBackground 在后台更新皮肤数据库，已有更新在执行或最近更新失败时跳过
+func (s *SkinsService) refreshInBackground() {
+	if s.recentFailure() != nil {
+		return
+	}
+
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	if s.refreshing {
+		return
+	}
+	s.refreshing = true
+
+	// 后台更新不随触发它的请求取消
+	go func() {
+		if err := s.UpdateSkinsDatabase(context.Background()); err != nil {
+			fmt.Printf("后台更新皮肤数据库失败，继续使用现有数据: %v\n", err)
 		}
 
-		// 解析contentData，提取皮肤信息
-		var skins []models.Skin
+		s.stateMutex.Lock()
+		s.refreshing = false
+		s.stateMutex.Unlock()
+	}()
+}
+
+// UpdateSkinsDatabase 更新皮肤数据库
+// 配置了离线数据文件时从文件导入，否则从valorant-api.com获取
+// 失败时记录失败时间，在重试间隔内GetAllSkins不会再次触发更新；ctx取消不算失败
+func (s *SkinsService) UpdateSkinsDatabase(ctx context.Context) error {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+
+	err := s.importCatalog(ctx)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	s.stateMutex.Lock()
+	if err != nil {
+		s.failedAt, s.lastErr = time.Now(), err
+	} else {
+		s.failedAt, s.lastErr = time.Time{}, nil
+	}
+	s.stateMutex.Unlock()
+
+	return err
+}
 
-		// 处理逻辑...
+// importCatalog 导入内容数据并写入皮肤数据库，调用方需持有updateMutex
+func (s *SkinsService) importCatalog(ctx context.Context) error {
+	var (
+		dump *repositories.CatalogDump
+		err  error
+	)
+	if s.dumpFile != "" {
+		fmt.Printf("从离线文件 %s 导入皮肤数据库\n", s.dumpFile)
+		dump, err = repositories.LoadCatalogDump(s.dumpFile)
+	} else {
+		dump, err = s.catalogAPI.FetchCatalog(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	skins := repositories.BuildSkins(dump)
+	if len(skins) == 0 {
+		return errors.New("导入的皮肤数据为空")
+	}
+
+	catalog := models.SkinsDatabase{
+		Skins:     skins,
+		Cosmetics: repositories.BuildCosmetics(dump),
+		Bundles:   repositories.BuildBundles(dump),
+	}
+	if err := s.skinDatabase.UpdateSkinDatabase(catalog); err != nil {
+		return fmt.Errorf("更新皮肤数据库失败: %w", err)
+	}
 
-		// 更新数据库
-		return s.skinDatabase.UpdateSkinDatabase(skins)
-	*/
+	fmt.Printf("皮肤数据库已更新，共 %d 个皮肤，%d 个饰品，%d 个套装\n", len(skins), len(catalog.Cosmetics), len(catalog.Bundles))
+	return nil
 }
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+func TestGetAllSkinsBacksOffAfterFailedImport(t *testing.T) {
+	dir := t.TempDir()
+	skinDatabase, err := repositories.NewSkinDatabase(filepath.Join(dir, "skins.json"))
+	if err != nil {
+		t.Fatalf("创建皮肤数据库失败: %v", err)
+	}
+	skinsService := NewSkinsService(nil, skinDatabase, nil, filepath.Join(dir, "missing.json"))
+
+	_, first := skinsService.GetAllSkins(t.Context())
+	if first == nil {
+		t.Fatal("离线文件不存在时应当返回错误")
+	}
+
+	// 重试间隔内不再导入，直接返回上次的错误
+	if _, err := skinsService.GetAllSkins(t.Context()); err != first {
+		t.Fatalf("重试间隔内应当返回上次的错误，得到 %v", err)
+	}
+}
+
+func TestUpdateSkinsDatabaseCancelledIsNotFailure(t *testing.T) {
+	// 内容接口一直不响应，直到请求被取消
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	skinDatabase, err := repositories.NewSkinDatabase(filepath.Join(t.TempDir(), "skins.json"))
+	if err != nil {
+		t.Fatalf("创建皮肤数据库失败: %v", err)
+	}
+	skinsService := NewSkinsService(nil, skinDatabase, repositories.NewCatalogAPI(server.URL, ""), "")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := skinsService.GetAllSkins(ctx); err == nil {
+		t.Fatal("请求取消时应当返回错误")
+	}
+
+	// 取消的导入不记录为失败，下次请求仍然会导入
+	if err := skinsService.recentFailure(); err != nil {
+		t.Fatalf("取消的导入不应触发重试间隔: %v", err)
+	}
+}
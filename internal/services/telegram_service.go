@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+const (
+	// telegramLinkCodeTimeout 绑定代码的有效期
+	telegramLinkCodeTimeout = 10 * time.Minute
+	// telegramLinkCodeLength 绑定代码的长度
+	telegramLinkCodeLength = 8
+	// telegramLinkCodeAlphabet 绑定代码使用的字符，去掉了容易混淆的0、O、1、I
+	telegramLinkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// telegramHelp /start和/help的回复
+const telegramHelp = `val-store 机器人
+/login 代码 - 绑定val-store账号（在val-store中获取一次性代码）
+/logout - 解除绑定
+/shop - 每日商店
+/nightmarket - 夜市
+/wallet - 钱包余额
+
+绑定后每天商店刷新时会自动推送每日商店。`
+
+// telegramLinkCode 等待在Telegram中使用的绑定代码
+type telegramLinkCode struct {
+	userID    string
+	expiresAt time.Time
+}
+
+// TelegramService 处理Telegram机器人的消息，并推送每日商店
+type TelegramService struct {
+	botToken       string // 为空时不启用Telegram机器人
+	webhookSecret  string
+	apiBaseURL     string
+	linkStore      *repositories.LinkStore
+	sessionService *SessionService
+	shopService    *ShopService
+	userService    *UserService
+	client         *http.Client
+
+	codesMutex sync.Mutex
+	linkCodes  map[string]*telegramLinkCode // 绑定代码 -> 用户
+}
+
+// NewTelegramService 创建新的Telegram服务，botToken为空时不启用机器人
+func NewTelegramService(botToken, webhookSecret, apiBaseURL string, linkStore *repositories.LinkStore, sessionService *SessionService, shopService *ShopService, userService *UserService) *TelegramService {
+	return &TelegramService{
+		botToken:       botToken,
+		webhookSecret:  webhookSecret,
+		apiBaseURL:     strings.TrimRight(apiBaseURL, "/"),
+		linkStore:      linkStore,
+		sessionService: sessionService,
+		shopService:    shopService,
+		userService:    userService,
+		client:         &http.Client{Timeout: 10 * time.Second},
+		linkCodes:      make(map[string]*telegramLinkCode),
+	}
+}
+
+// Enabled 是否配置了Telegram机器人
+func (s *TelegramService) Enabled() bool {
+	return s.botToken != "" && s.webhookSecret != ""
+}
+
+// VerifySecret 验证Webhook地址中的密钥
+func (s *TelegramService) VerifySecret(secret string) bool {
+	return s.Enabled() && subtle.ConstantTimeCompare([]byte(secret), []byte(s.webhookSecret)) == 1
+}
+
+// CreateLinkCode 为用户生成一次性绑定代码，用户在Telegram中发送 /login 代码 完成绑定
+// 每个用户同时只有一个有效的代码
+func (s *TelegramService) CreateLinkCode(userID string) (*models.TelegramLinkCodeResponse, error) {
+	code, err := generateLinkCode()
+	if err != nil {
+		return nil, fmt.Errorf("生成绑定代码失败: %w", err)
+	}
+	expiresAt := time.Now().Add(telegramLinkCodeTimeout)
+
+	s.codesMutex.Lock()
+	defer s.codesMutex.Unlock()
+
+	s.cleanupLinkCodes()
+	for existing, linkCode := range s.linkCodes {
+		if linkCode.userID == userID {
+			delete(s.linkCodes, existing)
+		}
+	}
+	s.linkCodes[code] = &telegramLinkCode{userID: userID, expiresAt: expiresAt}
+
+	return &models.TelegramLinkCodeResponse{
+		Code:      code,
+		ExpiresAt: expiresAt.Unix(),
+	}, nil
+}
+
+// consumeLinkCode 使用绑定代码，返回代码对应的用户ID，代码只能使用一次
+func (s *TelegramService) consumeLinkCode(code string) (string, bool) {
+	s.codesMutex.Lock()
+	defer s.codesMutex.Unlock()
+
+	s.cleanupLinkCodes()
+	linkCode, exists := s.linkCodes[strings.ToUpper(code)]
+	if !exists {
+		return "", false
+	}
+	delete(s.linkCodes, strings.ToUpper(code))
+	return linkCode.userID, true
+}
+
+// cleanupLinkCodes 清理过期的绑定代码，调用方需持有codesMutex
+func (s *TelegramService) cleanupLinkCodes() {
+	now := time.Now()
+	for code, linkCode := range s.linkCodes {
+		if now.After(linkCode.expiresAt) {
+			delete(s.linkCodes, code)
+		}
+	}
+}
+
+// generateLinkCode 生成随机的绑定代码
+func generateLinkCode() (string, error) {
+	buf := make([]byte, telegramLinkCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = telegramLinkCodeAlphabet[int(b)%len(telegramLinkCodeAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// GetLinkedAccounts 获取用户绑定的Telegram用户ID
+func (s *TelegramService) GetLinkedAccounts(userID string) []string {
+	return s.linkStore.ListByUser(repositories.LinkPlatformTelegram, userID)
+}
+
+// UnlinkAccount 解除用户绑定的所有Telegram账号
+func (s *TelegramService) UnlinkAccount(userID string) (int, error) {
+	return s.linkStore.UnlinkUser(repositories.LinkPlatformTelegram, userID)
+}
+
+// ShopSubscribers 返回绑定了Telegram的用户，后台任务需要在商店刷新后获取他们的商店
+func (s *TelegramService) ShopSubscribers() []string {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.linkStore.ListUsers(repositories.LinkPlatformTelegram)
+}
+
+// HandleUpdate 处理Telegram推送的消息，回复通过Bot API发送
+// 在后台调用时ctx不应随Telegram的请求结束而取消
+func (s *TelegramService) HandleUpdate(ctx context.Context, update *models.TelegramUpdate) {
+	message := update.Message
+	if message == nil || message.From == nil || !strings.HasPrefix(message.Text, "/") {
+		return
+	}
+
+	// 群组中的命令带有@机器人用户名
+	fields := strings.Fields(message.Text)
+	command, _, _ := strings.Cut(fields[0], "@")
+	args := fields[1:]
+	telegramUserID := strconv.FormatInt(message.From.ID, 10)
+
+	var reply string
+	switch command {
+	case "/start", "/help":
+		reply = html.EscapeString(telegramHelp)
+	case "/login":
+		reply = s.login(telegramUserID, args)
+	case "/logout":
+		reply = s.logout(telegramUserID)
+	case "/shop", "/nightmarket", "/wallet":
+		userID, linked := s.linkStore.GetUserID(repositories.LinkPlatformTelegram, telegramUserID)
+		if !linked {
+			reply = "还没有绑定val-store账号，请先发送 /login 代码"
+			break
+		}
+		reply = s.commandReply(ctx, userID, command)
+	default:
+		reply = "未知命令，发送 /help 查看可用命令"
+	}
+
+	if err := s.SendMessage(message.Chat.ID, reply); err != nil {
+		fmt.Printf("回复Telegram消息失败: %v\n", err)
+	}
+}
+
+// login 使用一次性代码绑定val-store账号
+func (s *TelegramService) login(telegramUserID string, args []string) string {
+	if len(args) == 0 {
+		return "请发送 /login 代码，代码可以在val-store中获取"
+	}
+
+	userID, valid := s.consumeLinkCode(args[0])
+	if !valid {
+		return "绑定代码无效或已过期，请重新获取"
+	}
+
+	if err := s.linkStore.Link(repositories.LinkPlatformTelegram, telegramUserID, userID); err != nil {
+		if errors.Is(err, repositories.ErrLinkConflict) {
+			return "该Telegram账号已绑定其他val-store账号，请先发送 /logout"
+		}
+		fmt.Printf("绑定Telegram账号失败: %v\n", err)
+		return "绑定失败，请稍后重试"
+	}
+	return "绑定成功，每天商店刷新时会自动推送每日商店"
+}
+
+// logout 解除Telegram账号的绑定
+func (s *TelegramService) logout(telegramUserID string) string {
+	removed, err := s.linkStore.Unlink(repositories.LinkPlatformTelegram, telegramUserID)
+	if err != nil {
+		fmt.Printf("解除Telegram绑定失败: %v\n", err)
+		return "解除绑定失败，请稍后重试"
+	}
+	if !removed {
+		return "还没有绑定val-store账号"
+	}
+	return "已解除绑定"
+}
+
+// commandReply 使用用户的Riot会话执行需要请求Riot的命令
+func (s *TelegramService) commandReply(ctx context.Context, userID, command string) string {
+	var reply string
+	err := s.sessionService.WithSession(ctx, userID, func(session *models.UserSession) error {
+		switch command {
+		case "/shop":
+			shop, err := s.shopService.GetShop(ctx, session, false)
+			if err != nil {
+				return err
+			}
+			reply = formatTelegramShop(shop.DailyOffers, shop.ExpiresAt)
+		case "/nightmarket":
+			nightMarket, err := s.shopService.GetNightMarket(ctx, session, false)
+			if err != nil {
+				return err
+			}
+			reply = formatTelegramNightMarket(nightMarket)
+		case "/wallet":
+			wallet, err := s.userService.GetUserWallet(ctx, session)
+			if err != nil {
+				return err
+			}
+			reply = fmt.Sprintf("<b>钱包</b>\nVP: %d\n辐能点: %d\n王国信用点: %d",
+				wallet.ValorantPoints, wallet.RadianitePoints, wallet.KingdomCredits)
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		return reply
+	case errors.Is(err, ErrSessionNotFound), errors.Is(err, ErrSessionReauthFailed):
+		return "Riot会话已过期，请重新登录val-store"
+	case errors.Is(err, ErrNightMarketNotActive):
+		return "当前没有开放的夜市"
+	case errors.Is(err, repositories.ErrRateLimited):
+		return "Riot请求过于频繁，请稍后重试"
+	default:
+		fmt.Printf("处理Telegram命令 %s 失败: %v\n", command, err)
+		return "获取数据失败，请稍后重试"
+	}
+}
+
+// Notify 将商店刷新、夜市开放和会话失效推送到用户绑定的Telegram账号
+func (s *TelegramService) Notify(userID, event string, data interface{}) {
+	if !s.Enabled() {
+		return
+	}
+
+	var text string
+	switch event {
+	case models.WebhookEventShopRotated:
+		snapshot, ok := data.(models.ShopSnapshot)
+		if !ok {
+			return
+		}
+		text = formatTelegramShop(snapshot.DailyOffers, snapshot.ExpiresAt)
+	case models.WebhookEventNightMarketOpened:
+		nightMarket, ok := data.(models.NightMarketResponse)
+		if !ok {
+			return
+		}
+		text = formatTelegramNightMarket(&nightMarket)
+	case models.WebhookEventSessionExpired:
+		text = "Riot会话已失效，请重新登录val-store，否则无法推送每日商店"
+	default:
+		return
+	}
+
+	for _, telegramUserID := range s.GetLinkedAccounts(userID) {
+		chatID, err := strconv.ParseInt(telegramUserID, 10, 64)
+		if err != nil {
+			continue
+		}
+		go func() {
+			if err := s.SendMessage(chatID, text); err != nil {
+				fmt.Printf("推送Telegram消息失败: %v\n", err)
+			}
+		}()
+	}
+}
+
+// SendMessage 通过Bot API发送HTML格式的消息
+func (s *TelegramService) SendMessage(chatID int64, text string) error {
+	body, err := json.Marshal(models.TelegramSendMessageRequest{
+		ChatID:                chatID,
+		Text:                  text,
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", s.apiBaseURL, s.botToken)
+	resp, err := s.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// 错误信息中包含带令牌的URL，不直接返回
+		return errors.New("请求Telegram Bot API失败")
+	}
+	defer resp.Body.Close()
+
+	var result models.TelegramAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析Telegram响应失败，状态码: %d", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("Telegram返回错误: %s", result.Description)
+	}
+	return nil
+}
+
+// formatTelegramShop 将每日商店格式化为HTML消息，包含皮肤名称、等级和价格
+func formatTelegramShop(offers []models.ShopItem, expiresAt int64) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "<b>每日商店</b>（%s后刷新）\n", formatRemaining(expiresAt))
+	for _, item := range offers {
+		builder.WriteString("\n" + formatTelegramSkin(item, fmt.Sprintf("%d VP", item.FinalPrice)))
+	}
+	return builder.String()
+}
+
+// formatTelegramNightMarket 将夜市格式化为HTML消息
+func formatTelegramNightMarket(nightMarket *models.NightMarketResponse) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "<b>夜市</b>（%s后结束）\n", formatRemaining(nightMarket.ExpiresAt))
+	for _, item := range nightMarket.Offers {
+		price := fmt.Sprintf("<s>%d</s> %d VP（-%d%%）", item.BasePrice, item.FinalPrice, item.DiscountPercent)
+		builder.WriteString("\n" + formatTelegramSkin(item.ShopItem, price))
+	}
+	return builder.String()
+}
+
+// formatTelegramSkin 格式化单个皮肤，price为已格式化的价格
+func formatTelegramSkin(item models.ShopItem, price string) string {
+	line := "• <b>" + html.EscapeString(item.Skin.Name) + "</b>"
+	if item.Skin.TierName != "" {
+		line += " · " + html.EscapeString(item.Skin.TierName)
+	}
+	line += " · " + price
+	if item.Owned {
+		line += " · 已拥有"
+	}
+	return line
+}
+
+// formatRemaining 格式化距离指定时间的剩余时长
+func formatRemaining(unix int64) string {
+	remaining := time.Until(time.Unix(unix, 0))
+	if remaining < time.Minute {
+		return "不到1分钟"
+	}
+	hours := int(remaining.Hours())
+	minutes := int(remaining.Minutes()) % 60
+	if hours >= 24 {
+		return fmt.Sprintf("%d天%d小时", hours/24, hours%24)
+	}
+	return fmt.Sprintf("%d小时%d分钟", hours, minutes)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emper0r/val-store/server/internal/models"
+)
+
+// testTelegramToken 测试使用的机器人令牌
+const testTelegramToken = "123456:test-token"
+
+// newTestTelegramService 创建指向handler的Telegram服务，只用于发送消息
+func newTestTelegramService(t *testing.T, handler http.HandlerFunc) *TelegramService {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewTelegramService(testTelegramToken, "test-webhook-secret", server.URL+"/", nil, nil, nil, nil)
+}
+
+func TestTelegramSendMessage(t *testing.T) {
+	var received models.TelegramSendMessageRequest
+	telegramService := newTestTelegramService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/bot"+testTelegramToken+"/sendMessage" {
+			t.Errorf("请求地址不正确: %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		w.Write([]byte(`{"ok": true, "result": {}}`))
+	})
+
+	if err := telegramService.SendMessage(42, "<b>每日商店</b>"); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	if received.ChatID != 42 || received.Text != "<b>每日商店</b>" || received.ParseMode != "HTML" || !received.DisableWebPagePreview {
+		t.Fatalf("发送的消息不正确: %+v", received)
+	}
+}
+
+func TestTelegramSendMessageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "API错误", status: http.StatusBadRequest, body: `{"ok": false, "description": "Bad Request: chat not found"}`, wantErr: "chat not found"},
+		{name: "无效响应", status: http.StatusBadGateway, body: `<html>Bad Gateway</html>`, wantErr: "502"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telegramService := newTestTelegramService(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			err := telegramService.SendMessage(42, "hello")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("应当返回包含 %q 的错误，得到 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTelegramSendMessageHidesTokenOnNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	telegramService := NewTelegramService(testTelegramToken, "test-webhook-secret", server.URL, nil, nil, nil, nil)
+
+	err := telegramService.SendMessage(42, "hello")
+	if err == nil {
+		t.Fatal("无法连接Bot API时应当返回错误")
+	}
+	if strings.Contains(err.Error(), testTelegramToken) {
+		t.Fatalf("错误信息不应包含机器人令牌: %v", err)
+	}
+}
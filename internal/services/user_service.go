@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/emper0r/val-store/server/internal/models"
@@ -19,10 +20,10 @@ func NewUserService(valorantAPI *repositories.ValorantAPI) *UserService {
 	}
 }
 
-// GetUserWallet 获取用户钱包/余额信息
-func (s *UserService) GetUserWallet(userID, accessToken, entitlementToken string) (*models.WalletResponse, error) {
+// GetUserWallet 获取用户钱包/余额信息，区域和令牌取自用户会话
+func (s *UserService) GetUserWallet(ctx context.Context, session *models.UserSession) (*models.WalletResponse, error) {
 	// 调用 Valorant API 获取用户钱包数据
-	walletData, err := s.valorantAPI.GetWallet(userID, accessToken, entitlementToken)
+	walletData, err := s.valorantAPI.GetWallet(ctx, repositories.SessionAuth(session), session.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户钱包数据失败: %w", err)
 	}
@@ -34,21 +35,14 @@ func (s *UserService) GetUserWallet(userID, accessToken, entitlementToken string
 		KingdomCredits:  0,
 	}
 
-	// 映射货币ID到对应的货币类型
-	const (
-		ValPointsCurrencyID       = "85ad13f7-3d1b-5128-9eb2-7cd8ee0b5741" // VP
-		RadianitePointsCurrencyID = "e59aa87c-4cbf-517a-5983-6e81511be9b7" // RP
-		KingdomCreditsCurrencyID  = "85ca954a-41f2-ce94-9b45-8ca3dd39a00d" // KC
-	)
-
 	// 提取各种货币的余额
 	for currencyID, amount := range walletData.Balances {
 		switch currencyID {
-		case ValPointsCurrencyID:
+		case models.CurrencyValorantPoints:
 			walletResponse.ValorantPoints = amount
-		case RadianitePointsCurrencyID:
+		case models.CurrencyRadianitePoints:
 			walletResponse.RadianitePoints = amount
-		case KingdomCreditsCurrencyID:
+		case models.CurrencyKingdomCredits:
 			walletResponse.KingdomCredits = amount
 		}
 	}
@@ -65,7 +59,7 @@ func (s *UserService) GetUserInfo(userID, username string) map[string]interface{
 	}
 }
 
-// SetUserRegion 设置用户的游戏区域
+// SetUserRegion 校验用户设置的游戏区域，区域保存在用户会话中
 func (s *UserService) SetUserRegion(region string) error {
 	// 验证区域是否有效
 	validRegions := []string{
@@ -89,10 +83,6 @@ func (s *UserService) SetUserRegion(region string) error {
 		return fmt.Errorf("无效的区域代码: %s", region)
 	}
 
-	// 设置Valorant API客户端的区域
-	s.valorantAPI.SetRegion(region)
-	fmt.Printf("用户手动设置区域为: %s\n", region)
-
 	return nil
 }
 
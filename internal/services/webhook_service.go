@@ -0,0 +1,386 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+const (
+	// maxWebhooksPerUser 每个用户最多注册的Webhook数量
+	maxWebhooksPerUser = 10
+	// webhookTimeout 单次推送的超时时间
+	webhookTimeout = 10 * time.Second
+	// webhookMaxRedirects 推送最多跟随的重定向次数
+	webhookMaxRedirects = 3
+
+	// Webhook推送请求头
+	WebhookHeaderEvent     = "X-ValStore-Event"
+	WebhookHeaderDelivery  = "X-ValStore-Delivery"
+	WebhookHeaderTimestamp = "X-ValStore-Timestamp"
+	WebhookHeaderSignature = "X-ValStore-Signature"
+)
+
+// ErrWebhookInvalid Webhook参数无效
+var ErrWebhookInvalid = errors.New("无效的Webhook")
+
+// errWebhookAddressBlocked Webhook地址指向本机、链路本地或内网地址
+var errWebhookAddressBlocked = errors.New("不允许推送到本机或内网地址")
+
+// sharedAddressSpace 运营商级NAT使用的地址段（RFC 6598），同样视为内网
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// webhookEvents 用户可以订阅的事件
+var webhookEvents = []string{
+	models.WebhookEventShopRotated,
+	models.WebhookEventWishlistMatched,
+	models.WebhookEventNightMarketOpened,
+	models.WebhookEventSessionExpired,
+}
+
+// defaultWebhookRetryDelays 推送失败后的重试间隔，依次递增
+var defaultWebhookRetryDelays = []time.Duration{
+	10 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+}
+
+// WebhookService 管理用户的Webhook并推送事件
+type WebhookService struct {
+	webhookStore *repositories.WebhookStore
+	client       *http.Client
+	retryDelays  []time.Duration
+	allowPrivate bool // 允许推送到本机和内网地址，只用于测试
+}
+
+// NewWebhookService 创建新的Webhook服务
+func NewWebhookService(webhookStore *repositories.WebhookStore) *WebhookService {
+	s := &WebhookService{
+		webhookStore: webhookStore,
+		retryDelays:  defaultWebhookRetryDelays,
+	}
+
+	// 域名解析的结果可能在注册之后改变，连接时再检查一次实际连接的地址
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			return s.checkAddress(ip)
+		},
+	}
+	s.client = &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= webhookMaxRedirects {
+				return fmt.Errorf("重定向次数超过%d次", webhookMaxRedirects)
+			}
+			return s.checkURL(req.Context(), req.URL)
+		},
+	}
+	return s
+}
+
+// checkAddress 检查推送的目标地址，拒绝本机、链路本地、内网和组播地址
+func (s *WebhookService) checkAddress(ip netip.Addr) error {
+	if s.allowPrivate {
+		return nil
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", errWebhookAddressBlocked, ip)
+	}
+	return nil
+}
+
+// checkURL 检查推送地址的协议，并解析主机名检查所有地址
+func (s *WebhookService) checkURL(ctx context.Context, target *url.URL) error {
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.New("URL必须是http或https地址")
+	}
+
+	host := target.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return s.checkAddress(ip)
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("无法解析主机 %s", host)
+	}
+	for _, ip := range ips {
+		if err := s.checkAddress(ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateWebhook 注册Webhook，返回的Webhook中包含签名密钥，之后不会再返回
+// URL不能指向本机或内网地址，避免用户通过推送记录探测服务器所在的内网
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID string, req models.WebhookRequest) (*models.Webhook, error) {
+	parsed, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: URL必须是http或https地址", ErrWebhookInvalid)
+	}
+	if err := s.checkURL(ctx, parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookInvalid, err)
+	}
+
+	if len(req.Events) == 0 {
+		return nil, fmt.Errorf("%w: 至少需要订阅一个事件", ErrWebhookInvalid)
+	}
+	events := make([]string, 0, len(req.Events))
+	for _, event := range req.Events {
+		if !slices.Contains(webhookEvents, event) {
+			return nil, fmt.Errorf("%w: 不支持的事件: %s", ErrWebhookInvalid, event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	if len(s.webhookStore.ListByUser(userID)) >= maxWebhooksPerUser {
+		return nil, fmt.Errorf("%w: 每个用户最多注册%d个Webhook", ErrWebhookInvalid, maxWebhooksPerUser)
+	}
+
+	id, err := generateRandomID(8)
+	if err != nil {
+		return nil, fmt.Errorf("生成Webhook ID失败: %w", err)
+	}
+	secret, err := generateRandomID(32)
+	if err != nil {
+		return nil, fmt.Errorf("生成Webhook密钥失败: %w", err)
+	}
+
+	webhook := &models.Webhook{
+		ID:        id,
+		UserID:    userID,
+		URL:       parsed.String(),
+		Events:    events,
+		Secret:    "whsec_" + secret,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := s.webhookStore.Put(webhook); err != nil {
+		return nil, fmt.Errorf("保存Webhook失败: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks 列出用户的所有Webhook，不包含签名密钥
+func (s *WebhookService) ListWebhooks(userID string) []*models.Webhook {
+	webhooks := s.webhookStore.ListByUser(userID)
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks
+}
+
+// DeleteWebhook 删除用户的Webhook
+func (s *WebhookService) DeleteWebhook(userID, id string) error {
+	if _, err := s.getOwned(userID, id); err != nil {
+		return err
+	}
+	return s.webhookStore.Delete(id)
+}
+
+// GetDeliveries 获取Webhook的推送记录，按时间从新到旧排列
+func (s *WebhookService) GetDeliveries(userID, id string) ([]models.WebhookDelivery, error) {
+	if _, err := s.getOwned(userID, id); err != nil {
+		return nil, err
+	}
+	return s.webhookStore.ListDeliveries(id), nil
+}
+
+// Ping 向Webhook发送测试推送，不论是否订阅了事件，只尝试一次
+func (s *WebhookService) Ping(ctx context.Context, userID, id string) (*models.WebhookDelivery, error) {
+	webhook, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := newWebhookEvent(userID, models.WebhookEventPing, map[string]string{"webhook_id": id})
+	if err != nil {
+		return nil, err
+	}
+	return s.deliver(ctx, webhook, event, nil), nil
+}
+
+// getOwned 获取属于用户的Webhook，其他用户的Webhook视为不存在
+func (s *WebhookService) getOwned(userID, id string) (*models.Webhook, error) {
+	webhook, err := s.webhookStore.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.UserID != userID {
+		return nil, repositories.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// ShopSubscribers 返回订阅了商店相关事件的用户，后台任务需要定期获取他们的商店
+func (s *WebhookService) ShopSubscribers() []string {
+	var users []string
+	for _, event := range []string{models.WebhookEventShopRotated, models.WebhookEventNightMarketOpened} {
+		for _, webhook := range s.webhookStore.ListByEvent(event) {
+			if !slices.Contains(users, webhook.UserID) {
+				users = append(users, webhook.UserID)
+			}
+		}
+	}
+	return users
+}
+
+// Notify 向用户订阅了该事件的所有Webhook推送事件，在后台推送并按需重试
+func (s *WebhookService) Notify(userID, event string, data interface{}) {
+	for _, webhook := range s.webhookStore.ListByEvent(event) {
+		if webhook.UserID != userID {
+			continue
+		}
+
+		payload, err := newWebhookEvent(userID, event, data)
+		if err != nil {
+			fmt.Printf("创建Webhook事件失败: %v\n", err)
+			continue
+		}
+		go s.deliver(context.Background(), webhook, payload, s.retryDelays)
+	}
+}
+
+// newWebhookEvent 创建推送内容，每次推送使用新的ID
+func newWebhookEvent(userID, event string, data interface{}) (models.WebhookEvent, error) {
+	id, err := generateRandomID(12)
+	if err != nil {
+		return models.WebhookEvent{}, fmt.Errorf("生成推送ID失败: %w", err)
+	}
+	return models.WebhookEvent{
+		ID:        id,
+		Event:     event,
+		UserID:    userID,
+		CreatedAt: time.Now().Unix(),
+		Data:      data,
+	}, nil
+}
+
+// deliver 推送事件，失败时按retryDelays中的间隔重试，每次尝试后更新推送记录
+// ctx取消时停止重试
+func (s *WebhookService) deliver(ctx context.Context, webhook *models.Webhook, event models.WebhookEvent, retryDelays []time.Duration) *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		ID:        event.ID,
+		WebhookID: webhook.ID,
+		Event:     event.Event,
+		CreatedAt: event.CreatedAt,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = fmt.Sprintf("序列化推送内容失败: %v", err)
+		s.saveDelivery(delivery)
+		return delivery
+	}
+
+	for attempt := 0; ; attempt++ {
+		delivery.Attempts++
+		statusCode, err := s.send(ctx, webhook, event, body)
+		delivery.StatusCode = statusCode
+		if err == nil {
+			delivery.Success = true
+			delivery.Error = ""
+			delivery.DeliveredAt = time.Now().Unix()
+			s.saveDelivery(delivery)
+			return delivery
+		}
+
+		delivery.Error = err.Error()
+		s.saveDelivery(delivery)
+
+		// 客户端错误（429除外）重试也不会成功
+		retryable := statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+		if !retryable || attempt >= len(retryDelays) {
+			fmt.Printf("推送Webhook %s 失败（已尝试%d次）: %v\n", webhook.ID, delivery.Attempts, err)
+			return delivery
+		}
+
+		timer := time.NewTimer(retryDelays[attempt])
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return delivery
+		}
+	}
+}
+
+// send 发送一次推送，返回HTTP状态码
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, event models.WebhookEvent, body []byte) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "val-store-webhook")
+	req.Header.Set(WebhookHeaderEvent, event.Event)
+	req.Header.Set(WebhookHeaderDelivery, event.ID)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("接收方返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// saveDelivery 保存推送记录，失败时只记录日志
+func (s *WebhookService) saveDelivery(delivery *models.WebhookDelivery) {
+	if err := s.webhookStore.SaveDelivery(delivery); err != nil {
+		fmt.Printf("保存Webhook推送记录失败: %v\n", err)
+	}
+}
+
+// SignWebhookPayload 计算推送签名：HMAC-SHA256(secret, timestamp + "." + body)的十六进制
+// 接收方应使用相同方式计算并与X-ValStore-Signature中sha256=之后的部分比较
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+// webhookReceiver 记录收到的推送，前failures次返回failureStatus
+type webhookReceiver struct {
+	t             *testing.T
+	secret        string
+	failures      int
+	failureStatus int
+
+	mutex    sync.Mutex
+	requests int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("读取推送内容失败: %v", err)
+	}
+
+	// 按文档中的方式验证签名
+	signature, found := strings.CutPrefix(req.Header.Get(WebhookHeaderSignature), "sha256=")
+	expected := SignWebhookPayload(r.secret, req.Header.Get(WebhookHeaderTimestamp), body)
+	if !found || !hmac.Equal([]byte(signature), []byte(expected)) {
+		r.t.Errorf("推送签名不正确: %q", req.Header.Get(WebhookHeaderSignature))
+	}
+
+	var event models.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		r.t.Errorf("解析推送内容失败: %v", err)
+	}
+	if req.Header.Get(WebhookHeaderEvent) != event.Event || req.Header.Get(WebhookHeaderDelivery) != event.ID {
+		r.t.Errorf("推送请求头与内容不一致: %v", req.Header)
+	}
+
+	r.mutex.Lock()
+	r.requests++
+	fail := r.requests <= r.failures
+	r.mutex.Unlock()
+
+	if fail {
+		w.WriteHeader(r.failureStatus)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newTestWebhook 启动接收方并注册指向它的Webhook
+func newTestWebhook(t *testing.T, failures, failureStatus int) (*WebhookService, *models.Webhook, *webhookReceiver) {
+	t.Helper()
+
+	webhookStore, err := repositories.NewWebhookStore(filepath.Join(t.TempDir(), "webhooks.json"))
+	if err != nil {
+		t.Fatalf("创建Webhook存储失败: %v", err)
+	}
+	webhookService := NewWebhookService(webhookStore)
+	webhookService.retryDelays = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+	webhookService.allowPrivate = true // 接收方监听在127.0.0.1
+
+	receiver := &webhookReceiver{t: t, failures: failures, failureStatus: failureStatus}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	webhook, err := webhookService.CreateWebhook(t.Context(), "user", models.WebhookRequest{
+		URL:    server.URL,
+		Events: []string{models.WebhookEventShopRotated},
+	})
+	if err != nil {
+		t.Fatalf("注册Webhook失败: %v", err)
+	}
+	receiver.secret = webhook.Secret
+	return webhookService, webhook, receiver
+}
+
+// deliverTestEvent 同步推送一次商店刷新事件
+func deliverTestEvent(t *testing.T, webhookService *WebhookService, webhook *models.Webhook) *models.WebhookDelivery {
+	t.Helper()
+
+	event, err := newWebhookEvent(webhook.UserID, models.WebhookEventShopRotated, map[string]string{"shop": "daily"})
+	if err != nil {
+		t.Fatalf("创建推送事件失败: %v", err)
+	}
+	return webhookService.deliver(t.Context(), webhook, event, webhookService.retryDelays)
+}
+
+func TestWebhookDeliveryRetriesServerErrors(t *testing.T) {
+	webhookService, webhook, receiver := newTestWebhook(t, 2, http.StatusServiceUnavailable)
+
+	delivery := deliverTestEvent(t, webhookService, webhook)
+	if !delivery.Success || delivery.Attempts != 3 || delivery.StatusCode != http.StatusNoContent {
+		t.Fatalf("两次5xx后第三次应当推送成功，得到 %+v", delivery)
+	}
+	if receiver.requests != 3 {
+		t.Fatalf("接收方应当收到3次推送，实际%d次", receiver.requests)
+	}
+
+	// 推送记录保存最终结果
+	deliveries, err := webhookService.GetDeliveries(webhook.UserID, webhook.ID)
+	if err != nil || len(deliveries) != 1 || !deliveries[0].Success {
+		t.Fatalf("推送记录不正确: %+v, %v", deliveries, err)
+	}
+}
+
+func TestWebhookDeliveryGivesUpAfterRetries(t *testing.T) {
+	webhookService, webhook, receiver := newTestWebhook(t, 10, http.StatusBadGateway)
+
+	delivery := deliverTestEvent(t, webhookService, webhook)
+	attempts := len(webhookService.retryDelays) + 1
+	if delivery.Success || delivery.Attempts != attempts || delivery.StatusCode != http.StatusBadGateway {
+		t.Fatalf("用完重试次数后应当失败，得到 %+v", delivery)
+	}
+	if receiver.requests != attempts {
+		t.Fatalf("接收方应当收到%d次推送，实际%d次", attempts, receiver.requests)
+	}
+}
+
+func TestWebhookDeliveryDoesNotRetryClientErrors(t *testing.T) {
+	webhookService, webhook, receiver := newTestWebhook(t, 10, http.StatusGone)
+
+	delivery := deliverTestEvent(t, webhookService, webhook)
+	if delivery.Success || delivery.Attempts != 1 {
+		t.Fatalf("4xx不应重试，得到 %+v", delivery)
+	}
+	if receiver.requests != 1 {
+		t.Fatalf("接收方应当只收到1次推送，实际%d次", receiver.requests)
+	}
+}
+
+func TestCreateWebhookRejectsInternalAddresses(t *testing.T) {
+	webhookStore, err := repositories.NewWebhookStore(filepath.Join(t.TempDir(), "webhooks.json"))
+	if err != nil {
+		t.Fatalf("创建Webhook存储失败: %v", err)
+	}
+	webhookService := NewWebhookService(webhookStore)
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/hook",
+		"https://192.168.1.10/hook",
+		"http://100.64.0.1/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+		"ftp://example.com/hook",
+	} {
+		_, err := webhookService.CreateWebhook(t.Context(), "user", models.WebhookRequest{
+			URL:    target,
+			Events: []string{models.WebhookEventShopRotated},
+		})
+		if !errors.Is(err, ErrWebhookInvalid) {
+			t.Errorf("注册 %s 应当返回ErrWebhookInvalid，得到 %v", target, err)
+		}
+	}
+}
+
+func TestWebhookDeliveryRejectsInternalAddressAtDial(t *testing.T) {
+	webhookService, webhook, receiver := newTestWebhook(t, 0, 0)
+
+	// 注册之后域名改为解析到内网地址时，连接前再次检查
+	webhookService.allowPrivate = false
+	delivery := deliverTestEvent(t, webhookService, webhook)
+	if delivery.Success || delivery.StatusCode != 0 {
+		t.Fatalf("推送到内网地址应当失败，得到 %+v", delivery)
+	}
+	if receiver.requests != 0 {
+		t.Fatalf("接收方不应收到推送，实际%d次", receiver.requests)
+	}
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emper0r/val-store/server/internal/models"
+	"github.com/emper0r/val-store/server/internal/repositories"
+)
+
+const (
+	// maxWishlistMatches 每个用户最多保留的匹配记录数
+	maxWishlistMatches = 200
+	// wishlistCheckDelay 商店刷新后等待一段时间再检查，避免Riot还未完成轮换
+	wishlistCheckDelay = 2 * time.Minute
+	// wishlistRetryInterval 没有获取到任何商店时的重试间隔
+	wishlistRetryInterval = time.Hour
+)
+
+var (
+	// ErrWishlistSkinInvalid 皮肤不在皮肤数据库中
+	ErrWishlistSkinInvalid = errors.New("皮肤不存在")
+	// ErrWishlistSkinNotFound 愿望单中没有该皮肤
+	ErrWishlistSkinNotFound = errors.New("愿望单中没有该皮肤")
+)
+
+// WishlistService 管理用户的皮肤愿望单，并在后台检查商店中是否出现愿望单中的皮肤
+type WishlistService struct {
+	wishlistStore  *repositories.WishlistStore
+	skinDatabase   *repositories.SkinDatabase
+	shopService    *ShopService
+	sessionService *SessionService
+	notifier       EventNotifier   // 愿望单匹配的通知，可以为空
+	shopWatchers   func() []string // 返回其他需要后台获取商店的用户，可以为空
+}
+
+// NewWishlistService 创建新的愿望单服务
+func NewWishlistService(wishlistStore *repositories.WishlistStore, skinDatabase *repositories.SkinDatabase, shopService *ShopService, sessionService *SessionService) *WishlistService {
+	return &WishlistService{
+		wishlistStore:  wishlistStore,
+		skinDatabase:   skinDatabase,
+		shopService:    shopService,
+		sessionService: sessionService,
+	}
+}
+
+// SetNotifier 设置愿望单匹配的通知接收者
+func (s *WishlistService) SetNotifier(notifier EventNotifier) {
+	s.notifier = notifier
+}
+
+// SetShopWatchers 设置其他需要后台获取商店的用户（如订阅了商店事件的Webhook）
+func (s *WishlistService) SetShopWatchers(watchers func() []string) {
+	s.shopWatchers = watchers
+}
+
+// GetWishlist 获取用户的愿望单
+func (s *WishlistService) GetWishlist(userID string) *models.WishlistResponse {
+	wishlist, found := s.wishlistStore.Get(userID)
+	if !found {
+		return &models.WishlistResponse{Skins: []models.Skin{}, Notify: true}
+	}
+	return s.buildResponse(wishlist)
+}
+
+// AddSkin 添加皮肤到愿望单，皮肤ID可以是皮肤、等级或颜色变体的ID
+func (s *WishlistService) AddSkin(userID string, req models.WishlistRequest) (*models.WishlistResponse, error) {
+	skin, found := s.skinDatabase.GetSkinByID(req.SkinID)
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrWishlistSkinInvalid, req.SkinID)
+	}
+	skinID := strings.ToLower(skin.UUID)
+
+	wishlist, err := s.wishlistStore.Update(userID, func(wishlist *models.Wishlist) error {
+		// 新愿望单默认参与后台检查
+		if len(wishlist.SkinIDs) == 0 && wishlist.UpdatedAt == 0 {
+			wishlist.Notify = true
+		}
+		if req.Notify != nil {
+			wishlist.Notify = *req.Notify
+		}
+		if !slices.Contains(wishlist.SkinIDs, skinID) {
+			wishlist.SkinIDs = append(wishlist.SkinIDs, skinID)
+		}
+		wishlist.UpdatedAt = time.Now().Unix()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存愿望单失败: %w", err)
+	}
+
+	return s.buildResponse(wishlist), nil
+}
+
+// RemoveSkin 从愿望单中删除皮肤
+func (s *WishlistService) RemoveSkin(userID, skinID string) (*models.WishlistResponse, error) {
+	// 允许使用等级或颜色变体的ID删除
+	skinID = strings.ToLower(skinID)
+	if skin, found := s.skinDatabase.GetSkinByID(skinID); found {
+		skinID = strings.ToLower(skin.UUID)
+	}
+
+	wishlist, err := s.wishlistStore.Update(userID, func(wishlist *models.Wishlist) error {
+		index := slices.Index(wishlist.SkinIDs, skinID)
+		if index < 0 {
+			return ErrWishlistSkinNotFound
+		}
+		wishlist.SkinIDs = slices.Delete(wishlist.SkinIDs, index, index+1)
+		wishlist.UpdatedAt = time.Now().Unix()
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrWishlistSkinNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("保存愿望单失败: %w", err)
+	}
+
+	return s.buildResponse(wishlist), nil
+}
+
+// GetMatches 获取愿望单的匹配记录，按时间从新到旧排列
+func (s *WishlistService) GetMatches(userID string) []models.WishlistMatch {
+	wishlist, found := s.wishlistStore.Get(userID)
+	if !found {
+		return []models.WishlistMatch{}
+	}
+
+	matches := wishlist.Matches
+	slices.Reverse(matches)
+	if matches == nil {
+		matches = []models.WishlistMatch{}
+	}
+	return matches
+}
+
+// buildResponse 将愿望单转换为客户端响应，附带皮肤详细信息
+func (s *WishlistService) buildResponse(wishlist *models.Wishlist) *models.WishlistResponse {
+	response := &models.WishlistResponse{
+		Skins:  make([]models.Skin, 0, len(wishlist.SkinIDs)),
+		Notify: wishlist.Notify,
+	}
+	for _, skinID := range wishlist.SkinIDs {
+		skin, found := s.skinDatabase.GetSkinByID(skinID)
+		if !found {
+			// 皮肤数据库更新后可能缺少该皮肤，仍然返回ID
+			skin = models.Skin{UUID: skinID, Name: "未知皮肤"}
+		}
+		response.Skins = append(response.Skins, skin)
+	}
+	return response
+}
+
+// RunScheduler 在每次每日商店刷新后检查用户的商店，阻塞运行直到ctx取消
+func (s *WishlistService) RunScheduler(ctx context.Context) {
+	for {
+		next := s.CheckAll(ctx)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// CheckAll 获取所有参与后台检查的用户的商店，返回下一次检查的时间
+// 包括开启了愿望单检查的用户和shopWatchers返回的用户
+func (s *WishlistService) CheckAll(ctx context.Context) time.Time {
+	users := make([]string, 0)
+	for _, wishlist := range s.wishlistStore.List() {
+		if wishlist.Notify && len(wishlist.SkinIDs) > 0 {
+			users = append(users, wishlist.UserID)
+		}
+	}
+	if s.shopWatchers != nil {
+		for _, userID := range s.shopWatchers() {
+			if !slices.Contains(users, userID) {
+				users = append(users, userID)
+			}
+		}
+	}
+
+	var nextRotation int64
+	checked := 0
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			break
+		}
+		expiresAt, err := s.CheckUser(ctx, userID)
+		if err != nil {
+			fmt.Printf("检查用户 %s 的商店失败: %v\n", userID, err)
+			continue
+		}
+		checked++
+		if nextRotation == 0 || expiresAt < nextRotation {
+			nextRotation = expiresAt
+		}
+	}
+
+	if checked > 0 {
+		fmt.Printf("已检查 %d 个用户的商店\n", checked)
+	}
+
+	// 没有成功获取任何商店时稍后重试
+	if nextRotation <= time.Now().Unix() {
+		return time.Now().Add(wishlistRetryInterval)
+	}
+	return time.Unix(nextRotation, 0).Add(wishlistCheckDelay)
+}
+
+// CheckUser 使用用户保存的会话获取商店并记录愿望单匹配，返回每日商店的刷新时间
+func (s *WishlistService) CheckUser(ctx context.Context, userID string) (int64, error) {
+	var shop *models.ShopResponse
+	err := s.sessionService.WithSession(ctx, userID, func(session *models.UserSession) error {
+		var err error
+		shop, err = s.shopService.GetShop(ctx, session, false)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	wishlist, found := s.wishlistStore.Get(userID)
+	if !found || !wishlist.Notify || len(wishlist.SkinIDs) == 0 {
+		return shop.ExpiresAt, nil
+	}
+
+	matches := findWishlistMatches(wishlist.SkinIDs, shop)
+	if len(matches) == 0 {
+		return shop.ExpiresAt, nil
+	}
+
+	var added []models.WishlistMatch
+	_, err = s.wishlistStore.Update(userID, func(wishlist *models.Wishlist) error {
+		added = added[:0]
+		for _, match := range matches {
+			if !hasWishlistMatch(wishlist.Matches, match) {
+				wishlist.Matches = append(wishlist.Matches, match)
+				added = append(added, match)
+			}
+		}
+		if len(wishlist.Matches) > maxWishlistMatches {
+			wishlist.Matches = wishlist.Matches[len(wishlist.Matches)-maxWishlistMatches:]
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("保存愿望单匹配失败: %w", err)
+	}
+
+	if len(added) > 0 && s.notifier != nil {
+		s.notifier.Notify(userID, models.WebhookEventWishlistMatched, map[string]interface{}{
+			"matches": added,
+		})
+	}
+
+	return shop.ExpiresAt, nil
+}
+
+// findWishlistMatches 查找商店中出现的愿望单皮肤（每日商店、精选套装和夜市）
+func findWishlistMatches(skinIDs []string, shop *models.ShopResponse) []models.WishlistMatch {
+	wanted := make(map[string]bool, len(skinIDs))
+	for _, skinID := range skinIDs {
+		wanted[strings.ToLower(skinID)] = true
+	}
+
+	date := rotationDate(shop.ExpiresAt)
+	now := time.Now().Unix()
+	var matches []models.WishlistMatch
+
+	add := func(item models.ShopItem, source, bundleName string, expiresAt int64) {
+		if item.Item != nil || !wanted[strings.ToLower(item.Skin.UUID)] {
+			return
+		}
+		matches = append(matches, models.WishlistMatch{
+			SkinID:     strings.ToLower(item.Skin.UUID),
+			SkinName:   item.Skin.Name,
+			Source:     source,
+			BundleName: bundleName,
+			Price:      item.FinalPrice,
+			Date:       date,
+			ExpiresAt:  expiresAt,
+			MatchedAt:  now,
+		})
+	}
+
+	for _, item := range shop.DailyOffers {
+		add(item, models.WishlistSourceDaily, "", shop.ExpiresAt)
+	}
+	for _, bundle := range shop.FeaturedBundles {
+		for _, item := range bundle.Items {
+			add(item, models.WishlistSourceBundle, bundle.Name, bundle.ExpiresAt)
+		}
+	}
+	for _, item := range shop.BonusOffers {
+		add(item.ShopItem, models.WishlistSourceNightMarket, "", shop.BonusExpiresAt)
+	}
+
+	return matches
+}
+
+// hasWishlistMatch 判断是否已经记录过该匹配
+// 每日商店按日期去重，套装和夜市会持续多天，按截止时间去重
+func hasWishlistMatch(matches []models.WishlistMatch, match models.WishlistMatch) bool {
+	for _, existing := range matches {
+		if existing.SkinID != match.SkinID || existing.Source != match.Source {
+			continue
+		}
+		if match.Source == models.WishlistSourceDaily {
+			if existing.Date == match.Date {
+				return true
+			}
+			continue
+		}
+		// 截止时间由剩余秒数推算，允许一小时的误差
+		if diff := existing.ExpiresAt - match.ExpiresAt; diff > -3600 && diff < 3600 {
+			return true
+		}
+	}
+	return false
+}